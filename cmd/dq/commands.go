@@ -0,0 +1,287 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/influx6/data/query/adaptors"
+	dqsql "github.com/influx6/data/query/adaptors/sql"
+	"github.com/influx6/data/query/parser"
+)
+
+// newFlagSet returns a flag set of the subcommand writing its errors and usage to stderr
+func newFlagSet(name, args string, stderr io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: dq %s %s\n\n", name, args)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// writeJSON writes the value as indented json
+func writeJSON(w io.Writer, val interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(val)
+}
+
+// runCommand executes the queries of each file and prints their merged records as json
+func runCommand(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	var src sources
+
+	fs := newFlagSet("run", "[flags] [file.dq ...]", stderr)
+	src.flags(fs, true)
+	fs.IntVar(&src.parallel, "parallel", 1, "number of root records, and batched or federated child records, run at once")
+
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	ins, err := readInputs(fs.Args(), stdin)
+
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	db, err := src.open()
+
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	if db != nil {
+		defer db.Close()
+	}
+
+	en, err := src.engine(db)
+
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+
+	res := make(adaptors.Result)
+
+	for _, in := range ins {
+		tree, err := en.Query(context.Background(), in.text)
+
+		if err != nil {
+			fmt.Fprintf(stderr, "%s: %s\n", in.name, err)
+			return 1
+		}
+
+		for key, val := range tree {
+			res[key] = val
+		}
+	}
+
+	if err := writeJSON(stdout, adaptors.Plain(res)); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	return 0
+}
+
+// checkCommand parses the queries of each file and compiles them against their adaptors without executing them,
+// the sql adaptor is used when no source is given
+func checkCommand(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	var src sources
+
+	fs := newFlagSet("check", "[flags] [file.dq ...]", stderr)
+	src.flags(fs, true)
+
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	ins, err := readInputs(fs.Args(), stdin)
+
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	db, err := src.open()
+
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	if db != nil {
+		defer db.Close()
+	}
+
+	en, err := src.engine(db)
+
+	if err == errNoSource {
+		reg := adaptors.NewRegistry()
+		reg.Register("sql", src.adaptor(nil))
+		en, err = adaptors.NewEngine(reg, nil), nil
+	}
+
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+
+	code := 0
+
+	for _, in := range ins {
+		graphs, err := adaptors.Parse(parser.DefaultInspectionFactory, in.text)
+
+		if err != nil {
+			fmt.Fprintf(stderr, "%s: %s\n", in.name, err)
+			code = 1
+			continue
+		}
+
+		failed := false
+
+		for _, gs := range graphs {
+			if err := en.Check(gs); err != nil {
+				fmt.Fprintf(stderr, "%s: %s\n", in.name, err)
+				failed = true
+			}
+		}
+
+		if failed {
+			code = 1
+			continue
+		}
+
+		fmt.Fprintf(stdout, "%s: %d queries ok\n", in.name, len(graphs))
+	}
+
+	return code
+}
+
+// fmtCommand prints the queries of each file in their canonical format or rewrites the files with -w
+func fmtCommand(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := newFlagSet("fmt", "[-w] [-c] [file.dq ...]", stderr)
+	write := fs.Bool("w", false, "write the formatted queries back to their files")
+	compact := fs.Bool("c", false, "print each query on a single line")
+
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	ins, err := readInputs(fs.Args(), stdin)
+
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	printer := &parser.Printer{Indent: parser.DefaultIndent}
+
+	if *compact {
+		printer.Indent = ""
+	}
+
+	code := 0
+
+	for _, in := range ins {
+		out, err := parser.FormatQuery(printer, parser.DefaultInspectionFactory, in.text)
+
+		if err != nil {
+			fmt.Fprintf(stderr, "%s: %s\n", in.name, err)
+			code = 1
+			continue
+		}
+
+		if *write && in.name != "<stdin>" {
+			if err := writeFile(in.name, out+"\n"); err != nil {
+				fmt.Fprintln(stderr, err)
+				code = 1
+			}
+			continue
+		}
+
+		fmt.Fprintln(stdout, out)
+	}
+
+	return code
+}
+
+// explainCommand prints the sql generated for the queries of each file, with the database's plan when a -dsn is given
+func explainCommand(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	var src sources
+
+	fs := newFlagSet("explain", "[flags] [file.dq ...]", stderr)
+	src.flags(fs, false)
+	asJSON := fs.Bool("json", false, "print the full explanation of each query as json")
+
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	ins, err := readInputs(fs.Args(), stdin)
+
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	db, err := src.open()
+
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	if db != nil {
+		defer db.Close()
+	}
+
+	var all []*dqsql.Explanation
+
+	for _, in := range ins {
+		exs, err := dqsql.ExplainQuery(context.Background(), db, in.text, src.queries(), dqsql.RelQueries, nil, parser.DefaultInspectionFactory)
+
+		if err != nil {
+			fmt.Fprintf(stderr, "%s: %s\n", in.name, err)
+			return 1
+		}
+
+		all = append(all, exs...)
+	}
+
+	if *asJSON {
+		if err := writeJSON(stdout, all); err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+		return 0
+	}
+
+	for index, ex := range all {
+		if index > 0 {
+			fmt.Fprintln(stdout)
+		}
+
+		fmt.Fprintf(stdout, "-- %s\n%s\n", ex.Query, ex.SQL)
+
+		if len(ex.Args) > 0 {
+			fmt.Fprintf(stdout, "-- args: %v\n", ex.Args)
+		}
+
+		for _, tb := range ex.Tables {
+			fmt.Fprintf(stdout, "-- %s (%s) %s: columns %d-%d %v\n", tb.Name, tb.Alias, tb.Kind, tb.Begin, tb.End, tb.Columns)
+		}
+
+		for _, row := range ex.Plan {
+			fmt.Fprintf(stdout, "-- plan: %v\n", row)
+		}
+	}
+
+	return 0
+}
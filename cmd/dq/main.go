@@ -0,0 +1,70 @@
+// Command dq runs, checks, formats and explains data queries from the command line.
+//
+// Usage:
+//
+//	dq run     [-driver mysql|postgres|sqlite3] [-dsn dsn] [-data file] [-format json|csv|ndjson] [-parallel n] [file.dq ...]
+//	dq check   [-driver mysql|postgres|sqlite3] [-dsn dsn] [-data file] [file.dq ...]
+//	dq fmt     [-w] [-c] [file.dq ...]
+//	dq explain [-driver mysql|postgres|sqlite3] [-dsn dsn] [-json] [file.dq ...]
+//
+// Queries are read from the giving files or from stdin when none are given. Records are routed to the
+// database of -dsn as 'sql' and to the file of -data as 'data', the first of them being the default.
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// command defines a subcommand taking its arguments and output streams, returning the exit code
+type command func(args []string, stdin io.Reader, stdout, stderr io.Writer) int
+
+var commands = map[string]command{
+	"run":     runCommand,
+	"check":   checkCommand,
+	"fmt":     fmtCommand,
+	"explain": explainCommand,
+}
+
+const usage = `dq runs, checks, formats and explains data queries
+
+Usage:
+
+	dq <command> [flags] [file.dq ...]
+
+Commands:
+
+	run      execute queries against a database or a json/csv/ndjson file and print json
+	check    parse and lint queries
+	fmt      print queries in their canonical format
+	explain  print the sql generated for queries
+
+Use "dq <command> -h" for the flags of a command.
+`
+
+func main() {
+	os.Exit(dispatch(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// dispatch runs the command named by the first argument
+func dispatch(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+
+	cmd, ok := commands[args[0]]
+
+	if !ok {
+		if args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+			fmt.Fprint(stdout, usage)
+			return 0
+		}
+
+		fmt.Fprintf(stderr, "dq: unknown command %q\n\n%s", args[0], usage)
+		return 2
+	}
+
+	return cmd(args[1:], stdin, stdout, stderr)
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/influx6/flux"
+)
+
+func execute(args []string, stdin string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := dispatch(args, strings.NewReader(stdin), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestRun(t *testing.T) {
+	code, out, errs := execute([]string{"run", "-data", "./../../query/fixtures/users.json"}, `users(){ name, age(gt: 30), photos(with: [user_id id]){ url } }`)
+
+	if code != 0 {
+		flux.FatalFailed(t, "Expected run to succeed but got %d: %s", code, errs)
+	}
+
+	var res map[string][]map[string]interface{}
+
+	if err := json.Unmarshal([]byte(out), &res); err != nil {
+		flux.FatalFailed(t, "Expected json output: %+s", err)
+	}
+
+	if users := res["users"]; len(users) != 2 || users[0]["name"] != "josh" {
+		flux.FatalFailed(t, "Expected users 'josh' and 'sara' older than 30: %s", out)
+	}
+
+	if code, _, _ := execute([]string{"run"}, `users(){ name }`); code != 2 {
+		flux.FatalFailed(t, "Expected run without a source to be a usage error but got %d", code)
+	}
+
+	flux.LogPassed(t, "Ran query against a json file and printed its records as json")
+}
+
+func TestCheck(t *testing.T) {
+	if code, out, errs := execute([]string{"check", "./../../query/fixtures/models.dq"}, ""); code != 0 || !strings.Contains(out, "1 queries ok") {
+		flux.FatalFailed(t, "Expected models.dq to check: %s %s", out, errs)
+	}
+
+	code, _, errs := execute([]string{"check"}, `users(){ name, photos{ url } }`)
+
+	if code != 1 || !strings.Contains(errs, "with") {
+		flux.FatalFailed(t, "Expected a child record without 'with' to fail the sql check: %s", errs)
+	}
+
+	flux.LogPassed(t, "Checked queries against the sql adaptor")
+}
+
+func TestFmt(t *testing.T) {
+	code, out, errs := execute([]string{"fmt"}, `users(id: 1){ name,age(lt: 40, gt: 20) }`)
+
+	if code != 0 {
+		flux.FatalFailed(t, "Expected fmt to succeed: %s", errs)
+	}
+
+	if expected := "users(id: 1){\n  age(gt: 20, lt: 40),\n  name\n}\n"; out != expected {
+		flux.FatalFailed(t, "Expected formatted query %q but got %q", expected, out)
+	}
+
+	if _, out, _ := execute([]string{"fmt", "-c"}, `users(){ name }`); out != "users(){ name }\n" {
+		flux.FatalFailed(t, "Expected single line query but got %q", out)
+	}
+
+	flux.LogPassed(t, "Formatted queries in their canonical form")
+}
+
+func TestExplain(t *testing.T) {
+	code, out, errs := execute([]string{"explain"}, `users(){ name, photos(with: [user_id id]){ url } }`)
+
+	if code != 0 || !strings.Contains(out, "FROM USERS") || !strings.Contains(out, "-- photos") {
+		flux.FatalFailed(t, "Expected generated sql with table ranges: %s %s", out, errs)
+	}
+
+	code, out, errs = execute([]string{"explain", "-driver", "postgres"}, `users(){ name(ilike: 'a%') }`)
+
+	if code != 0 || !strings.Contains(out, ".name ILIKE $1") {
+		flux.FatalFailed(t, "Expected postgres sql for the postgres driver: %s %s", out, errs)
+	}
+
+	flux.LogPassed(t, "Explained the sql of a query")
+}
+
+func TestCheckFiles(t *testing.T) {
+	code, out, errs := execute([]string{"check", "./../../query/fixtures/dataset.dq"}, "")
+
+	if code != 0 || !strings.Contains(out, "dataset.dq: ") {
+		flux.FatalFailed(t, "Expected each query of the chunkfile to check: %s %s", out, errs)
+	}
+
+	if code, _, _ := execute([]string{"check", "./missing.dq"}, ""); code != 1 {
+		flux.FatalFailed(t, "Expected a missing file to fail")
+	}
+
+	flux.LogPassed(t, "Read the queries of chunkfiles")
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/influx6/data/query/adaptors"
+	dqcsv "github.com/influx6/data/query/adaptors/csv"
+	dqjson "github.com/influx6/data/query/adaptors/json"
+	dqndjson "github.com/influx6/data/query/adaptors/ndjson"
+	dqsql "github.com/influx6/data/query/adaptors/sql"
+	"github.com/influx6/data/query/parser"
+
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// errNoSource is returned when running queries without a database or data file
+var errNoSource = errors.New("dq: run needs a -dsn or -data source")
+
+// sources defines the database and data file queries are routed to
+type sources struct {
+	driver, dsn  string
+	data, format string
+	parallel     int
+}
+
+// flags adds the flags of the sources to the flag set
+func (s *sources) flags(fs *flag.FlagSet, data bool) {
+	fs.StringVar(&s.driver, "driver", "mysql", "database/sql driver of the -dsn: mysql, postgres or sqlite3")
+	fs.StringVar(&s.dsn, "dsn", "", "data source name of the database routed to as 'sql'")
+
+	if data {
+		fs.StringVar(&s.data, "data", "", "json, csv or ndjson file or directory routed to as 'data'")
+		fs.StringVar(&s.format, "format", "", "format of the -data source: json, csv or ndjson (default from its extension)")
+	}
+}
+
+// open returns the database of the -dsn, it is nil when no dsn was given
+func (s *sources) open() (*sql.DB, error) {
+	if s.dsn == "" {
+		return nil, nil
+	}
+
+	return sql.Open(s.driver, s.dsn)
+}
+
+// queries returns the sql query templates of the dialect of the -driver
+func (s *sources) queries() *parser.OPFactory {
+	switch s.driver {
+	case "postgres":
+		return dqsql.PostgresQueries
+	case "sqlite3":
+		return dqsql.SQLiteQueries
+	}
+
+	return dqsql.TemplatesQueries
+}
+
+// adaptor returns the sql adaptor of the database in the dialect of the -driver
+func (s *sources) adaptor(db *sql.DB) *dqsql.Adaptor {
+	return dqsql.NewAdaptor(db, nil, s.queries(), dqsql.RelQueries)
+}
+
+// source returns the adaptors.Source of the -data file
+func (s *sources) source() (adaptors.Source, error) {
+	format := s.format
+
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(s.data)), ".")
+	}
+
+	switch format {
+	case "json":
+		return dqjson.Open(s.data)
+	case "csv":
+		return dqcsv.Open(s.data)
+	case "ndjson", "jsonl":
+		return dqndjson.Open(s.data)
+	}
+
+	return nil, fmt.Errorf("dq: unknown format %q of %s, use -format json, csv or ndjson", format, s.data)
+}
+
+// engine returns an engine routing to the database as 'sql' and to the -data source as 'data'
+func (s *sources) engine(db *sql.DB) (*adaptors.Engine, error) {
+	reg := adaptors.NewRegistry()
+
+	if db != nil {
+		reg.Register("sql", s.adaptor(db).Parallel(s.parallel))
+	}
+
+	if s.data != "" {
+		src, err := s.source()
+
+		if err != nil {
+			return nil, err
+		}
+
+		reg.Register("data", adaptors.NewSourceAdaptor(src, nil))
+	}
+
+	if len(reg.Names()) == 0 {
+		return nil, errNoSource
+	}
+
+	en := adaptors.NewEngine(reg, nil)
+	en.Parallelism = s.parallel
+
+	return en, nil
+}
+
+// input defines the query text of a file
+type input struct {
+	name, text string
+}
+
+// readInputs returns the query text of each file, reading stdin when no files are given or a file is '-'
+func readInputs(files []string, stdin io.Reader) ([]input, error) {
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+
+	var ins []input
+
+	for _, file := range files {
+		if file == "-" {
+			data, err := ioutil.ReadAll(stdin)
+
+			if err != nil {
+				return nil, err
+			}
+
+			ins = append(ins, input{name: "<stdin>", text: string(data)})
+			continue
+		}
+
+		var chunks []string
+
+		if err := adaptors.ScanFile(file, func(query string) {
+			chunks = append(chunks, query)
+		}); err != nil {
+			return nil, err
+		}
+
+		ins = append(ins, input{name: file, text: strings.Join(chunks, "\n")})
+	}
+
+	return ins, nil
+}
+
+// writeFile replaces the content of the file keeping its permissions
+func writeFile(file, text string) error {
+	info, err := os.Stat(file)
+
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(file, []byte(text), info.Mode())
+}
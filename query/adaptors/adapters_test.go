@@ -1,8 +1,10 @@
 package adaptors
 
 import (
+	"context"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/influx6/data/query/parser"
 	"github.com/influx6/ds"
@@ -142,3 +144,625 @@ func TestChunkScannerWithLargePack(t *testing.T) {
 
 	fs.Close()
 }
+
+func authorize(po *Policy, pr *Principal, query string) (ds.Graphs, error) {
+	var ws sync.WaitGroup
+	ws.Add(1)
+
+	var graph ds.Graphs
+	var res error
+
+	fs := PolicyParser(parser.DefaultInspectionFactory, po, pr)
+
+	fs.React(func(rs flux.Reactor, err error, data interface{}) {
+		defer ws.Done()
+		res = err
+		graph, _ = data.(ds.Graphs)
+	}, true)
+
+	fs.Send(query)
+
+	ws.Wait()
+	fs.Close()
+
+	return graph, res
+}
+
+func TestPolicy(t *testing.T) {
+	po := NewPolicy(false)
+	po.AllowTable("users")
+	po.AllowTable("photos", "url", "user_id")
+	po.DenyColumns("users", "password")
+	po.AddRowFilter("users", "tenant_id", ValueOf("tenant"))
+
+	pr := NewPrincipal("alex").Set("tenant", 20)
+
+	gs, err := authorize(po, pr, `users(){ name, photos(with: [user_id id]){ url } }`)
+
+	if err != nil {
+		flux.FatalFailed(t, "Expected query to be authorized: %+s", err)
+	}
+
+	users := gs.Get("users").(*parser.ParseNode)
+	rules, err := users.Rules.Get("tenant_id")
+
+	if err != nil {
+		flux.FatalFailed(t, "Expected row filter 'tenant_id' to be injected: %+s", err)
+	}
+
+	if pm, ok := rules[0].Get("value").(parser.Param); !ok || pm.Value != 20 {
+		flux.FatalFailed(t, "Expected row filter to bind principal value: %+v", rules[0])
+	}
+
+	flux.LogPassed(t, "Authorized query with injected row filter")
+
+	if _, err := authorize(po, pr, `users(){ password }`); err == nil {
+		flux.FatalFailed(t, "Expected denied column to fail authorization")
+	}
+
+	if _, err := authorize(po, pr, `photos(){ id }`); err == nil {
+		flux.FatalFailed(t, "Expected column outside allow list to fail authorization")
+	}
+
+	if _, err := authorize(po, pr, `admins(){ name }`); err == nil {
+		flux.FatalFailed(t, "Expected table outside allow list to fail authorization")
+	}
+
+	if _, err := authorize(po, pr, `users(){ name, photos(with: [user_id password]){ url } }`); err == nil {
+		flux.FatalFailed(t, "Expected denied parent column in 'with' to fail authorization")
+	}
+
+	if _, err := authorize(po, pr, `users(){ name, photos(with: [id id]){ url } }`); err == nil {
+		flux.FatalFailed(t, "Expected child column outside allow list in 'with' to fail authorization")
+	}
+
+	po.DenyColumns("users", "exists")
+
+	if _, err := authorize(po, pr, `users(){ exists }`); err == nil {
+		flux.FatalFailed(t, "Expected denied column named as a control key to fail authorization")
+	}
+
+	flux.LogPassed(t, "Unauthorized queries failed properly")
+}
+
+func check(l *Limits, query string) error {
+	var ws sync.WaitGroup
+	ws.Add(1)
+
+	var res error
+
+	fs := LimitParser(parser.DefaultInspectionFactory, l)
+
+	fs.React(func(rs flux.Reactor, err error, data interface{}) {
+		res = err
+		ws.Done()
+	}, true)
+
+	fs.Send(query)
+
+	ws.Wait()
+	fs.Close()
+
+	return res
+}
+
+func TestLimits(t *testing.T) {
+	query := `users(){ name, age(in: [1 2 3]), photos(with: [user_id id]){ url, tags(with: [photo_id id]){ name } } }`
+
+	if err := check(&Limits{MaxDepth: 3, MaxRecords: 3, MaxIn: 3}, query); err != nil {
+		flux.FatalFailed(t, "Expected query within limits to pass: %+s", err)
+	}
+
+	flux.LogPassed(t, "Query within limits passed")
+
+	err := check(&Limits{MaxDepth: 2}, query)
+
+	le, ok := err.(*LimitError)
+
+	if !ok || le.Node != "tags" || le.Limit != "depth" {
+		flux.FatalFailed(t, "Expected depth limit error naming 'tags' but got: %+s", err)
+	}
+
+	flux.LogPassed(t, "Depth limit failed properly: %+s", err)
+
+	if err := check(&Limits{MaxIn: 2}, query); err == nil {
+		flux.FatalFailed(t, "Expected 'in' list limit to fail")
+	}
+
+	if err := check(&Limits{MaxCost: 9}, query); err != nil {
+		flux.FatalFailed(t, "Expected records within the cost limit to pass: %+s", err)
+	}
+
+	err = check(&Limits{MaxCost: 5}, query)
+
+	if le, ok := err.(*LimitError); !ok || le.Node != "photos" || le.Limit != "cost" || le.Value != 6 {
+		flux.FatalFailed(t, "Expected cost limit error naming 'photos' but got: %+s", err)
+	}
+
+	flux.LogPassed(t, "In and cost limits failed properly")
+}
+
+// collections provides a Source over named lists of records for tests
+type collections map[string][]Record
+
+func (c collections) Each(name string, fx func(Record) error) error {
+	for _, rec := range c[name] {
+		if err := fx(rec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestEngine(t *testing.T) {
+	users := collections{
+		"users":  {{"id": 1, "name": "alex"}, {"id": 2, "name": "josh"}},
+		"photos": {{"user_id": 1, "url": "./a.jpg"}},
+	}
+
+	archive := collections{
+		"users": {{"id": 9, "name": "old"}},
+	}
+
+	limited := NewMatchFactory()
+	limited.Add("is", func(val interface{}, c parser.Collector) (bool, error) {
+		return Equals(val, c.Get("value")), nil
+	})
+
+	reg := NewRegistry()
+	reg.Register("live", NewSourceAdaptor(users, nil))
+	reg.Register("archive", NewSourceAdaptor(archive, limited))
+
+	en := NewEngine(reg, nil)
+
+	res, err := en.Query(context.Background(), `users(id: 1){ name, photos(with: [user_id id]){ url } }`)
+
+	if err != nil {
+		flux.FatalFailed(t, "Failed to query default adaptor: %+s", err)
+	}
+
+	if recs := res["users"].([]Record); len(recs) != 1 || len(recs[0]["photos"].([]Record)) != 1 {
+		flux.FatalFailed(t, "Expected user 1 with 1 photo from the default adaptor: %+v", res)
+	}
+
+	res, err = en.Query(context.Background(), `users@archive(){ name }`)
+
+	if err != nil {
+		flux.FatalFailed(t, "Failed to query routed adaptor: %+s", err)
+	}
+
+	if recs := res["users"].([]Record); len(recs) != 1 || recs[0]["name"] != "old" {
+		flux.FatalFailed(t, "Expected the archived user from users@archive: %+v", res)
+	}
+
+	flux.LogPassed(t, "Routed queries to their named adaptors")
+
+	if _, err := en.Query(context.Background(), `users@archive(){ name, id(gt: 1) }`); err == nil {
+		flux.FatalFailed(t, "Expected unsupported 'gt' condition on archive to fail")
+	}
+
+	if _, err := en.Query(context.Background(), `users@missing(){ name }`); err == nil {
+		flux.FatalFailed(t, "Expected unregistered adaptor to fail")
+	}
+
+	if _, err := en.Query(context.Background(), `users(){ name, photos@archive{ url } }`); err == nil {
+		flux.FatalFailed(t, "Expected child routed to another adaptor without 'with' keys to fail")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := en.Query(ctx, `users(){ name }`); err != context.Canceled {
+		flux.FatalFailed(t, "Expected cancelled context to end the query: %+s", err)
+	}
+
+	flux.LogPassed(t, "Rejected unsupported, unregistered, mixed and cancelled queries")
+}
+
+// countingAdaptor counts the plans executed by its SourceAdaptor
+type countingAdaptor struct {
+	*SourceAdaptor
+	calls int
+}
+
+func (c *countingAdaptor) Execute(ctx context.Context, p Plan) (Result, error) {
+	c.calls++
+	return c.SourceAdaptor.Execute(ctx, p)
+}
+
+func TestFederation(t *testing.T) {
+	users := collections{
+		"users": {{"id": 1, "name": "alex"}, {"id": 2, "name": "josh"}, {"id": 3, "name": "john"}},
+	}
+
+	logs := collections{
+		"events": {
+			{"user_id": 1, "type": "login"},
+			{"user_id": 2, "type": "login"},
+			{"user_id": 1, "type": "logout"},
+			{"user_id": 7, "type": "login"},
+		},
+	}
+
+	events := &countingAdaptor{SourceAdaptor: NewSourceAdaptor(logs, nil)}
+
+	reg := NewRegistry()
+	reg.Register("sql", NewSourceAdaptor(users, nil))
+	reg.Register("logs", events)
+
+	en := NewEngine(reg, nil)
+
+	res, err := en.Query(context.Background(), `users(){ name, events@logs(with: [user_id id]){ type } }`)
+
+	if err != nil {
+		flux.FatalFailed(t, "Failed to federate query: %+s", err)
+	}
+
+	recs := res["users"].([]Record)
+
+	if len(recs) != 3 {
+		flux.FatalFailed(t, "Expected 3 users but got %d", len(recs))
+	}
+
+	counts := map[string]int{"alex": 2, "josh": 1, "john": 0}
+
+	for _, rec := range recs {
+		if _, ok := rec["id"]; ok {
+			flux.FatalFailed(t, "Expected stitching key 'id' to be removed: %+v", rec)
+		}
+
+		evs := asRecords(rec["events"])
+
+		if len(evs) != counts[rec["name"].(string)] {
+			flux.FatalFailed(t, "Expected %d events for %q but got %+v", counts[rec["name"].(string)], rec["name"], evs)
+		}
+
+		for _, ev := range evs {
+			if _, ok := ev["user_id"]; ok || ev["type"] == nil {
+				flux.FatalFailed(t, "Expected only the selected 'type' of each event: %+v", ev)
+			}
+		}
+	}
+
+	if events.calls != 1 {
+		flux.FatalFailed(t, "Expected events to be looked up in 1 batch but took %d", events.calls)
+	}
+
+	flux.LogPassed(t, "Stitched events from another adaptor into their users in one batch")
+
+	events.calls = 0
+	en.BatchSize = 2
+
+	if _, err := en.Query(context.Background(), `users(){ name, events@logs(with: [user_id id]){ type } }`); err != nil {
+		flux.FatalFailed(t, "Failed to federate query: %+s", err)
+	}
+
+	if events.calls != 2 {
+		flux.FatalFailed(t, "Expected 3 user keys to take 2 batches of 2 but took %d", events.calls)
+	}
+
+	flux.LogPassed(t, "Split federated lookups into batches of BatchSize keys")
+}
+
+func TestPersistedQueries(t *testing.T) {
+	store := NewMemoryQueryStore(nil)
+
+	pq, err := store.Add("adults", `users(){ name, age(gt: 30) }`)
+
+	if err != nil {
+		flux.FatalFailed(t, "Failed to persist query: %+s", err)
+	}
+
+	if got, err := store.Get(pq.Hash); err != nil || got != pq {
+		flux.FatalFailed(t, "Expected query addressed by its hash: %+s", err)
+	}
+
+	graphs, err := Parse(parser.DefaultInspectionFactory, `users(){
+		age(gt: 30),
+		name
+	}`)
+
+	if err != nil {
+		flux.FatalFailed(t, "Failed to parse query: %+s", err)
+	}
+
+	if got, err := Persisted(store, graphs); err != nil || got.ID != "adults" {
+		flux.FatalFailed(t, "Expected reformatted query to match the persisted one: %+s", err)
+	}
+
+	if _, err := store.Add("adults", `users(){ name }`); err == nil {
+		flux.FatalFailed(t, "Expected a different query under the same id to fail")
+	}
+
+	files, err := NewFileQueryStore("./../fixtures/persisted", nil)
+
+	if err != nil {
+		flux.FatalFailed(t, "Failed to load persisted query files: %+s", err)
+	}
+
+	if ids := files.IDs(); len(ids) != 2 || ids[0] != "users/admins" || ids[1] != "users/users" {
+		flux.FatalFailed(t, "Expected ids of each record of users.dq but got %v", ids)
+	}
+
+	fpq, _ := files.Get("users/users")
+
+	if fpq.Hash != pq.Hash {
+		flux.FatalFailed(t, "Expected the file query to share the hash of the same query added in code")
+	}
+
+	first, _ := fpq.Graphs()
+	second, _ := fpq.Graphs()
+
+	if err := BindVariables(first[0], nil); err != nil || first[0] == second[0] {
+		flux.FatalFailed(t, "Expected independent copies of the persisted graphs")
+	}
+
+	flux.LogPassed(t, "Persisted queries in memory and from files, addressed by id and hash")
+}
+
+// lockedCollections provides a Source whose records can be replaced while it is queried
+type lockedCollections struct {
+	records collections
+	rw      sync.RWMutex
+}
+
+func (l *lockedCollections) Each(name string, fx func(Record) error) error {
+	l.rw.RLock()
+	defer l.rw.RUnlock()
+	return l.records.Each(name, fx)
+}
+
+func (l *lockedCollections) set(name string, recs ...Record) {
+	l.rw.Lock()
+	l.records[name] = recs
+	l.rw.Unlock()
+}
+
+func TestWatch(t *testing.T) {
+	src := &lockedCollections{records: collections{}}
+	src.set("users", Record{"id": 1, "name": "alex"}, Record{"id": 2, "name": "josh"})
+
+	reg := NewRegistry()
+	reg.Register("live", NewSourceAdaptor(src, nil))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	diffs, err := NewEngine(reg, nil).Watch(ctx, `users(){ id, name }`, 5*time.Millisecond)
+
+	if err != nil {
+		flux.FatalFailed(t, "Failed to watch query: %+s", err)
+	}
+
+	first := <-diffs
+
+	if first.Record != "users" || len(first.Changes) != 2 || first.Changes[0].Kind != Added {
+		flux.FatalFailed(t, "Expected first diff to add both users: %+v", first)
+	}
+
+	src.set("users", Record{"id": 1, "name": "alexander"}, Record{"id": 3, "name": "john"})
+
+	next := <-diffs
+
+	kinds := []ChangeKind{Changed, Removed, Added}
+
+	if len(next.Changes) != 3 {
+		flux.FatalFailed(t, "Expected 3 changes but got %+v", next.Changes)
+	}
+
+	for index, change := range next.Changes {
+		if change.Kind != kinds[index] {
+			flux.FatalFailed(t, "Expected change %d to be %s but got %+v", index, kinds[index], change)
+		}
+	}
+
+	if next.Changes[0].Previous["name"] != "alex" || next.Changes[0].Record["name"] != "alexander" {
+		flux.FatalFailed(t, "Expected changed user with its previous record: %+v", next.Changes[0])
+	}
+
+	cancel()
+
+	for range diffs {
+	}
+
+	flux.LogPassed(t, "Watched query and emitted added, changed and removed users by primary key")
+
+	if _, err := NewEngine(reg, nil).Watch(context.Background(), `users(){ id, name }`, 0); err != ErrInvalidInterval {
+		flux.FatalFailed(t, "Expected zero interval to fail with ErrInvalidInterval but got: %+s", err)
+	}
+
+	flux.LogPassed(t, "Zero interval failed properly")
+}
+
+// barrierAdaptor blocks each execution until every adaptor sharing its barrier is executing, failing when they are run one at a time
+type barrierAdaptor struct {
+	*SourceAdaptor
+	barrier *sync.WaitGroup
+}
+
+func (b *barrierAdaptor) Execute(ctx context.Context, p Plan) (Result, error) {
+	b.barrier.Done()
+
+	done := make(chan struct{})
+
+	go func() {
+		b.barrier.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		return nil, context.DeadlineExceeded
+	}
+
+	return b.SourceAdaptor.Execute(ctx, p)
+}
+
+func TestParallel(t *testing.T) {
+	var mu sync.Mutex
+	var running, peak int
+
+	out := make([]int, 6)
+
+	if err := Parallel(context.Background(), 2, len(out), func(ctx context.Context, index int) error {
+		mu.Lock()
+		running++
+		if running > peak {
+			peak = running
+		}
+		mu.Unlock()
+
+		time.Sleep(5 * time.Millisecond)
+		out[index] = index * 10
+
+		mu.Lock()
+		running--
+		mu.Unlock()
+		return nil
+	}); err != nil {
+		flux.FatalFailed(t, "Failed to run tasks: %+s", err)
+	}
+
+	if peak > 2 || out[5] != 50 {
+		flux.FatalFailed(t, "Expected at most 2 tasks at once with ordered results: %d %+v", peak, out)
+	}
+
+	flux.LogPassed(t, "Ran tasks within the parallelism limit keeping results in order")
+
+	err := Parallel(context.Background(), 3, 3, func(ctx context.Context, index int) error {
+		if index == 0 {
+			return ErrInvalidPlan
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(2 * time.Second):
+			return nil
+		}
+	})
+
+	if err != ErrInvalidPlan {
+		flux.FatalFailed(t, "Expected the first error to be returned but got %+s", err)
+	}
+
+	flux.LogPassed(t, "Cancelled the remaining tasks on the first error")
+
+	users := collections{"users": {{"id": 1, "name": "alex"}}}
+	barrier := new(sync.WaitGroup)
+	barrier.Add(2)
+
+	reg := NewRegistry()
+	reg.Register("sql", NewSourceAdaptor(users, nil))
+	reg.Register("logs", &barrierAdaptor{SourceAdaptor: NewSourceAdaptor(collections{"events": {{"user_id": 1, "type": "login"}}}, nil), barrier: barrier})
+	reg.Register("media", &barrierAdaptor{SourceAdaptor: NewSourceAdaptor(collections{"photos": {{"user_id": 1, "url": "a.jpg"}}}, nil), barrier: barrier})
+
+	en := NewEngine(reg, nil)
+	en.Parallelism = 2
+
+	res, err := en.Query(context.Background(), `users(){
+		name,
+		events@logs(with: [user_id id]){ type },
+		photos@media(with: [user_id id]){ url }
+	}`)
+
+	if err != nil {
+		flux.FatalFailed(t, "Expected sibling records to be loaded at once: %+s", err)
+	}
+
+	recs := res["users"].([]Record)
+
+	if len(recs) != 1 || len(asRecords(recs[0]["events"])) != 1 || len(asRecords(recs[0]["photos"])) != 1 {
+		flux.FatalFailed(t, "Expected events and photos stitched to alex: %+v", recs)
+	}
+
+	flux.LogPassed(t, "Loaded sibling federated records concurrently")
+}
+
+func TestDateMatchers(t *testing.T) {
+	now := time.Date(2016, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	mf := NewMatchFactory()
+	mf.Now = func() time.Time { return now }
+	AddDefaultMatchers(mf)
+
+	src := collections{
+		"users": {
+			{"name": "alex", "joined": now.Add(-2 * 24 * time.Hour)},
+			{"name": "josh", "joined": "2016-02-01T09:30:00Z"},
+			{"name": "kate", "joined": now.Add(3 * time.Hour)},
+			{"name": "sara"},
+		},
+	}
+
+	cases := map[string][]string{
+		`users(){ name, joined(within: 7d) }`:                            {"alex"},
+		`users(){ name, joined(within: +1d) }`:                           {"kate"},
+		`users(){ name, joined(after: 2016-02-01T09:30:00Z) }`:           {"alex", "kate"},
+		`users(){ name, joined(before: 1w) }`:                            {"josh"},
+		`users(){ name, joined(between: 2016-01-01..now) }`:              {"alex", "josh"},
+		`users(){ name, joined(between: 2016-02-01T09:30..2016-02-02) }`: {"josh"},
+	}
+
+	for query, names := range cases {
+		graphs, err := Parse(parser.DefaultInspectionFactory, query)
+
+		if err != nil {
+			flux.FatalFailed(t, "Failed to parse %q: %+s", query, err)
+		}
+
+		res, err := NewEvaluator(src, mf).Evaluate(graphs[0])
+
+		if err != nil {
+			flux.FatalFailed(t, "Failed to evaluate %q: %+s", query, err)
+		}
+
+		users := res["users"].([]Record)
+
+		if len(users) != len(names) {
+			flux.FatalFailed(t, "Expected %+v to match %q but got %+v", names, query, users)
+		}
+
+		for index, name := range names {
+			if users[index]["name"] != name {
+				flux.FatalFailed(t, "Expected %+v to match %q but got %+v", names, query, users)
+			}
+		}
+	}
+
+	flux.LogPassed(t, "Matched date conditions against an injected clock")
+}
+
+func TestNullKeys(t *testing.T) {
+	src := collections{
+		"users":  {{"id": 1, "name": "alex"}, {"id": nil, "name": "ghost"}, {"name": "missing"}},
+		"photos": {{"user_id": 1, "url": "./a.jpg"}, {"user_id": nil, "url": "./orphan.jpg"}, {"url": "./lost.jpg"}},
+	}
+
+	graphs, err := Parse(parser.DefaultInspectionFactory, `users(){ name, photos(with: [user_id id]){ url } }`)
+
+	if err != nil {
+		flux.FatalFailed(t, "Failed to parse query: %+s", err)
+	}
+
+	res, err := NewEvaluator(src, nil).Evaluate(graphs[0])
+
+	if err != nil {
+		flux.FatalFailed(t, "Failed to evaluate query: %+s", err)
+	}
+
+	for _, user := range res["users"].([]Record) {
+		photos, _ := user["photos"].([]Record)
+
+		if user["name"] == "alex" && len(photos) != 1 {
+			flux.FatalFailed(t, "Expected alex to have a single photo: %+v", user)
+		}
+
+		if user["name"] != "alex" && len(photos) != 0 {
+			flux.FatalFailed(t, "Expected records with a null key to join nothing: %+v", user)
+		}
+	}
+
+	flux.LogPassed(t, "Skipped null keys when joining records")
+}
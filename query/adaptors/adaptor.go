@@ -0,0 +1,407 @@
+package adaptors
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/influx6/data/query/parser"
+	"github.com/influx6/ds"
+	"github.com/influx6/flux"
+)
+
+// Plan defines the backend specific form of a compiled query graph eg a sql statement or an aggregation pipeline
+type Plan interface{}
+
+// Result defines the record tree produced by executing a plan, keyed by the name of its root record
+type Result map[string]interface{}
+
+// ConditionSet defines any factory that can report if it handles a condition type eg *parser.OPFactory or *MatchFactory
+type ConditionSet interface {
+	Has(tag string) bool
+}
+
+// Capabilities describes the parts of the query language an adaptor supports
+type Capabilities struct {
+	//Joins is true if child records can be linked to their parent through 'with' keys
+	Joins bool
+	//Embedded is true if child records can be read from their parent's embedded objects and lists
+	Embedded bool
+	//Conditions reports the supported condition types, all types are supported when nil
+	Conditions ConditionSet
+	//Computed is true if records can select computed fields eg 'fullName: concat(first, " ", last)'
+	Computed bool
+	//Functions reports the functions computed fields may call, all registered functions are supported when nil
+	Functions ConditionSet
+}
+
+// Supports returns true if the condition type is supported
+func (c Capabilities) Supports(tag string) bool {
+	return c.Conditions == nil || c.Conditions.Has(tag)
+}
+
+// SupportsFunction returns true if computed fields may call the function
+func (c Capabilities) SupportsFunction(name string) bool {
+	return c.Computed && (c.Functions == nil || c.Functions.Has(name))
+}
+
+// Adaptor defines the contract every backend provides to compile query graphs into plans and execute them
+type Adaptor interface {
+	Compile(gs ds.Graphs) (Plan, error)
+	Execute(ctx context.Context, p Plan) (Result, error)
+	Capabilities() Capabilities
+}
+
+//AdaptorNotFoundMessage provides error for records routed to unregistered adaptors
+const AdaptorNotFoundMessage = "Adaptor '%s' Not Found!"
+
+//UnsupportedConditionMessage provides error for conditions an adaptor cannot evaluate
+const UnsupportedConditionMessage = "Adaptor '%s' does not support the '%s' condition"
+
+//UnsupportedJoinMessage provides error for 'with' joins on adaptors without join support
+const UnsupportedJoinMessage = "Adaptor '%s' does not support joining records with 'with'"
+
+//UnsupportedComputedMessage provides error for computed fields on adaptors without support for them
+const UnsupportedComputedMessage = "Adaptor '%s' does not support computed fields"
+
+//UnsupportedFunctionMessage provides error for computed fields calling functions an adaptor does not implement
+const UnsupportedFunctionMessage = "Adaptor '%s' does not support the '%s' function"
+
+// ErrNoAdaptors is returned when routing a query with an empty registry
+var ErrNoAdaptors = errors.New("No adaptors registered")
+
+// ErrInvalidPlan is returned when an adaptor is handed a plan of another adaptor
+var ErrInvalidPlan = errors.New("Plan type not supported by adaptor")
+
+// Registry provides a named set of adaptors, the first registered adaptor is the default
+type Registry struct {
+	adaptors map[string]Adaptor
+	def      string
+	rw       sync.RWMutex
+}
+
+// NewRegistry returns a new Registry instance
+func NewRegistry() *Registry {
+	return &Registry{adaptors: make(map[string]Adaptor)}
+}
+
+// Register adds the adaptor under the name, replacing any existing one
+func (r *Registry) Register(name string, a Adaptor) {
+	r.rw.Lock()
+	r.adaptors[name] = a
+
+	if r.def == "" {
+		r.def = name
+	}
+	r.rw.Unlock()
+}
+
+// SetDefault sets the adaptor used by records without a '@source'
+func (r *Registry) SetDefault(name string) error {
+	r.rw.Lock()
+	defer r.rw.Unlock()
+
+	if _, ok := r.adaptors[name]; !ok {
+		return fmt.Errorf(AdaptorNotFoundMessage, name)
+	}
+
+	r.def = name
+	return nil
+}
+
+// Default returns the name of the default adaptor
+func (r *Registry) Default() string {
+	r.rw.RLock()
+	defer r.rw.RUnlock()
+	return r.def
+}
+
+// Get returns the named adaptor, an empty name returns the default adaptor
+func (r *Registry) Get(name string) (Adaptor, error) {
+	r.rw.RLock()
+	defer r.rw.RUnlock()
+
+	if name == "" {
+		if r.def == "" {
+			return nil, ErrNoAdaptors
+		}
+		name = r.def
+	}
+
+	a, ok := r.adaptors[name]
+
+	if !ok {
+		return nil, fmt.Errorf(AdaptorNotFoundMessage, name)
+	}
+
+	return a, nil
+}
+
+// Names returns the sorted names of the registered adaptors
+func (r *Registry) Names() []string {
+	r.rw.RLock()
+	defer r.rw.RUnlock()
+
+	var names []string
+
+	for name := range r.adaptors {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// Parse scans the query string into its separate root records and parses each into its graph
+func Parse(inspect *parser.InspectionFactory, query string) ([]ds.Graphs, error) {
+	var chunks []string
+
+	scan := parser.NewScanner(bytes.NewBufferString(query))
+
+	if err := parser.ScanChunks(scan, func(chunk string) {
+		chunks = append(chunks, chunk)
+	}); err != nil {
+		return nil, err
+	}
+
+	ps := parser.NewParser(inspect)
+
+	var graphs []ds.Graphs
+
+	for _, chunk := range chunks {
+		gs, err := ps.Scan(bytes.NewBufferString(chunk))
+
+		if err != nil {
+			return nil, err
+		}
+
+		graphs = append(graphs, gs)
+	}
+
+	return graphs, nil
+}
+
+// Engine routes each query graph to the registered adaptor named by its root record eg users@sql, using the default adaptor otherwise.
+// Child records routed to another adaptor eg events@logs are executed on their own adaptor and stitched into their parents through their 'with' keys
+type Engine struct {
+	//BatchSize is the number of parent keys looked up by each query of a federated child record, DefaultBatchSize is used when zero
+	BatchSize int
+	//PrimaryKey is the field matching the records of a watched query across executions, DefaultPrimaryKey is used when empty
+	PrimaryKey string
+	//Parallelism is the number of root records of a compound query, or federated child records of the same level, executed at once, one or less executes them in order
+	Parallelism int
+	registry    *Registry
+	inspect     *parser.InspectionFactory
+}
+
+// NewEngine returns a new Engine over the registry, parser.DefaultInspectionFactory is used when the inspections are nil
+func NewEngine(reg *Registry, inspect *parser.InspectionFactory) *Engine {
+	if inspect == nil {
+		inspect = parser.DefaultInspectionFactory
+	}
+
+	return &Engine{registry: reg, inspect: inspect}
+}
+
+// Registry returns the registry of the engine
+func (e *Engine) Registry() *Registry {
+	return e.registry
+}
+
+// Route returns the name and adaptor of the graph's root record, checking each record against the capabilities of the adaptor it is routed to
+func (e *Engine) Route(gs ds.Graphs) (string, Adaptor, error) {
+	fd, err := e.split(gs)
+
+	if err != nil {
+		return "", nil, err
+	}
+
+	return fd.segments[0].name, fd.segments[0].adaptor, nil
+}
+
+// checkCapabilities returns an error for the first part of the node the capabilities do not cover, the 'with' rule of a federated record is stitched by the engine and left unchecked
+func checkCapabilities(name string, caps Capabilities, node *parser.ParseNode, federated bool) error {
+	if _, ok := Relation(node); ok && !caps.Joins && !federated {
+		return node.Report("with", fmt.Sprintf(UnsupportedJoinMessage, name))
+	}
+
+	var err error
+
+	check := func(field string, c parser.Collector, stop func()) {
+		if IsControlKey(field) {
+			return
+		}
+
+		tag, _ := c.Get("type").(string)
+
+		if !caps.Supports(tag) {
+			err = node.Report(field, fmt.Sprintf(UnsupportedConditionMessage, name, tag))
+			stop()
+		}
+	}
+
+	node.Rules.EachCondition(check)
+
+	if err == nil {
+		node.Records.EachCondition(check)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	for _, cf := range node.Computed {
+		if !caps.Computed {
+			return node.Report(cf.Name, fmt.Sprintf(UnsupportedComputedMessage, name))
+		}
+
+		for _, fn := range parser.ExprFunctions(cf.Expr) {
+			if !caps.SupportsFunction(fn) {
+				return node.Report(cf.Name, fmt.Sprintf(UnsupportedFunctionMessage, name, fn))
+			}
+		}
+	}
+
+	return nil
+}
+
+// Execute routes, compiles and executes a single query graph, federating it across adaptors when its records are routed to more than one
+func (e *Engine) Execute(ctx context.Context, gs ds.Graphs) (Result, error) {
+	fd, err := e.split(gs)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(fd.segments) > 1 {
+		return e.federate(ctx, fd)
+	}
+
+	ad := fd.segments[0].adaptor
+
+	plan, err := ad.Compile(gs)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return ad.Execute(ctx, plan)
+}
+
+// Check routes the graph and compiles a copy of the records of each adaptor it touches without executing them, returning the first error found
+func (e *Engine) Check(gs ds.Graphs) error {
+	fd, err := e.split(gs)
+
+	if err != nil {
+		return err
+	}
+
+	for _, seg := range fd.segments {
+		if _, err := seg.adaptor.Compile(fd.graph(seg, nil)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Query parses the query string and executes each of its root records, Parallelism at a time, merging their trees into one result in the order of the query
+func (e *Engine) Query(ctx context.Context, query string) (Result, error) {
+	graphs, err := Parse(e.inspect, query)
+
+	if err != nil {
+		return nil, err
+	}
+
+	trees := make([]Result, len(graphs))
+
+	if err := Parallel(ctx, e.Parallelism, len(graphs), func(ctx context.Context, index int) error {
+		tree, err := e.Execute(ctx, graphs[index])
+		trees[index] = tree
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	res := make(Result)
+
+	for _, tree := range trees {
+		for key, val := range tree {
+			res[key] = val
+		}
+	}
+
+	return res, nil
+}
+
+// EngineAdaptor returns a reactor that executes each ds.Graphs through the engine, replying with its Result
+func EngineAdaptor(e *Engine) flux.Reactor {
+	return QueryAdaptor(func(r flux.Reactor, gs ds.Graphs) {
+		res, err := e.Execute(context.Background(), gs)
+
+		if err != nil {
+			r.ReplyError(err)
+			return
+		}
+
+		r.Reply(res)
+	})
+}
+
+// BuildEngineQuero generates a full query parser that routes its queries through the engine
+func BuildEngineQuero(e *Engine) flux.Reactor {
+	co := ChunkParser(e.inspect)
+	co.Bind(EngineAdaptor(e), true)
+	return co
+}
+
+// SourceAdaptor provides the Adaptor of any Source by evaluating its plans in memory, the plan of a graph is the graph itself
+type SourceAdaptor struct {
+	source Source
+	match  *MatchFactory
+}
+
+// NewSourceAdaptor returns a new SourceAdaptor for the source using the matchers, DefaultMatchers is used when the factory is nil
+func NewSourceAdaptor(src Source, mf *MatchFactory) *SourceAdaptor {
+	if mf == nil {
+		mf = DefaultMatchers
+	}
+
+	return &SourceAdaptor{source: src, match: mf}
+}
+
+// Compile returns the graph as the plan
+func (s *SourceAdaptor) Compile(gs ds.Graphs) (Plan, error) {
+	return gs, nil
+}
+
+// Execute evaluates the graph of the plan against the source
+func (s *SourceAdaptor) Execute(ctx context.Context, p Plan) (Result, error) {
+	gs, ok := p.(ds.Graphs)
+
+	if !ok {
+		return nil, ErrInvalidPlan
+	}
+
+	res, err := NewEvaluator(s.source, s.match).EvaluateContext(ctx, gs)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return Result(res), nil
+}
+
+// Capabilities returns the capabilities of in-memory evaluation
+func (s *SourceAdaptor) Capabilities() Capabilities {
+	return Capabilities{
+		Joins:      true,
+		Embedded:   true,
+		Conditions: s.match,
+		Computed:   true,
+		Functions:  DefaultScalars,
+	}
+}
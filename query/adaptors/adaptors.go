@@ -49,14 +49,7 @@ func ChunkFileScanAdaptor() flux.Reactor {
 			return
 		}
 
-		var fs *os.File
-
-		if fs, err = os.Open(data); err != nil {
-			v.ReplyError(err)
-			return
-		}
-
-		if err = parser.ScanChunks(parser.NewScanner(fs), func(query string) {
+		if err = ScanFile(data, func(query string) {
 			v.Reply(query)
 		}); err != nil {
 			v.ReplyError(err)
@@ -64,6 +57,19 @@ func ChunkFileScanAdaptor() flux.Reactor {
 	})
 }
 
+// ScanFile calls the function with each query of the chunkfile, as replied by the ChunkFileScanAdaptor
+func ScanFile(file string, chunks func(string)) error {
+	fs, err := os.Open(file)
+
+	if err != nil {
+		return err
+	}
+
+	defer fs.Close()
+
+	return parser.ScanChunks(parser.NewScanner(fs), chunks)
+}
+
 // ChunkScanAdaptor provides a Stacks for parser.Parser and scans strings inputs for query
 func ChunkScanAdaptor() flux.Reactor {
 	return flux.Reactive(func(v flux.Reactor, err error, d interface{}) {
@@ -0,0 +1,459 @@
+package bolt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/influx6/data/query/adaptors"
+	"github.com/influx6/data/query/parser"
+	"github.com/influx6/flux"
+	bdb "go.etcd.io/bbolt"
+)
+
+// ErrNoID is returned when a stored record has no value for the store's id field
+var ErrNoID = errors.New("Record has no id value")
+
+// Store provides an adaptors.Source and adaptors.Seeker over a bolt database, where each bucket is a collection of json encoded records keyed by their id field and indexed fields are kept in their own buckets of value to ids
+type Store struct {
+	db      *bdb.DB
+	id      string
+	indexes map[string][]string
+	rw      sync.RWMutex
+}
+
+// NewStore returns a new Store over the database keying records by the id field eg 'id'
+func NewStore(db *bdb.DB, id string) *Store {
+	return &Store{
+		db:      db,
+		id:      id,
+		indexes: make(map[string][]string),
+	}
+}
+
+// Open opens the bolt database file and returns a Store keying records by 'id'
+func Open(file string) (*Store, error) {
+	db, err := bdb.Open(file, 0600, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return NewStore(db, "id"), nil
+}
+
+// DB returns the underline bolt database
+func (s *Store) DB() *bdb.DB {
+	return s.db
+}
+
+// Close closes the underline bolt database
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Key returns the bolt key of a value, non-negative whole numbers are encoded as 8 byte big-endian so their keys keep numeric order and others use their string form
+func Key(val interface{}) []byte {
+	if num, ok := numericKey(val); ok {
+		return num
+	}
+
+	return []byte(adaptors.ToString(val))
+}
+
+// numericKey returns the big-endian key of a non-negative whole number
+func numericKey(val interface{}) ([]byte, bool) {
+	num, ok := adaptors.ToFloat(val)
+
+	if !ok || num < 0 || num != math.Trunc(num) || num >= math.MaxUint64 {
+		return nil, false
+	}
+
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, uint64(num))
+	return key, true
+}
+
+// indexName returns the name of the bucket indexing the field of a bucket
+func indexName(bucket, field string) []byte {
+	return []byte(fmt.Sprintf("idx:%s:%s", bucket, field))
+}
+
+// Index adds secondary indexes for the fields of the bucket, building them from its existing records, indexes are kept up to date by Put and Delete
+func (s *Store) Index(bucket string, fields ...string) error {
+	s.rw.Lock()
+	for _, field := range fields {
+		if !hasField(s.indexes[bucket], field) {
+			s.indexes[bucket] = append(s.indexes[bucket], field)
+		}
+	}
+	s.rw.Unlock()
+
+	return s.db.Update(func(tx *bdb.Tx) error {
+		bu, err := tx.CreateBucketIfNotExists([]byte(bucket))
+
+		if err != nil {
+			return err
+		}
+
+		for _, field := range fields {
+			name := indexName(bucket, field)
+
+			if tx.Bucket(name) != nil {
+				if err := tx.DeleteBucket(name); err != nil {
+					return err
+				}
+			}
+
+			if _, err := tx.CreateBucket(name); err != nil {
+				return err
+			}
+		}
+
+		return bu.ForEach(func(k, v []byte) error {
+			rec, err := decode(v)
+
+			if err != nil {
+				return err
+			}
+
+			return s.reindex(tx, bucket, fields, k, nil, rec)
+		})
+	})
+}
+
+// hasField returns true if the field is within the list
+func hasField(fields []string, field string) bool {
+	for _, item := range fields {
+		if item == field {
+			return true
+		}
+	}
+	return false
+}
+
+// Put stores the records in the bucket under the key of their id field, replacing existing records and their index entries
+func (s *Store) Put(bucket string, recs ...adaptors.Record) error {
+	s.rw.RLock()
+	fields := s.indexes[bucket]
+	s.rw.RUnlock()
+
+	return s.db.Update(func(tx *bdb.Tx) error {
+		bu, err := tx.CreateBucketIfNotExists([]byte(bucket))
+
+		if err != nil {
+			return err
+		}
+
+		for _, rec := range recs {
+			id, ok := rec[s.id]
+
+			if !ok || id == nil {
+				return ErrNoID
+			}
+
+			key := Key(id)
+
+			var old adaptors.Record
+
+			if data := bu.Get(key); data != nil {
+				if old, err = decode(data); err != nil {
+					return err
+				}
+			}
+
+			data, err := json.Marshal(rec)
+
+			if err != nil {
+				return err
+			}
+
+			if err := bu.Put(key, data); err != nil {
+				return err
+			}
+
+			if err := s.reindex(tx, bucket, fields, key, old, rec); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// Delete removes the records with the ids from the bucket and its indexes
+func (s *Store) Delete(bucket string, ids ...interface{}) error {
+	s.rw.RLock()
+	fields := s.indexes[bucket]
+	s.rw.RUnlock()
+
+	return s.db.Update(func(tx *bdb.Tx) error {
+		bu := tx.Bucket([]byte(bucket))
+
+		if bu == nil {
+			return fmt.Errorf(adaptors.CollectionNotFoundMessage, bucket)
+		}
+
+		for _, id := range ids {
+			key := Key(id)
+			data := bu.Get(key)
+
+			if data == nil {
+				continue
+			}
+
+			old, err := decode(data)
+
+			if err != nil {
+				return err
+			}
+
+			if err := bu.Delete(key); err != nil {
+				return err
+			}
+
+			if err := s.reindex(tx, bucket, fields, key, old, nil); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// reindex moves the record's key from the index entries of its old field values to those of its new ones
+func (s *Store) reindex(tx *bdb.Tx, bucket string, fields []string, key []byte, old, rec adaptors.Record) error {
+	for _, field := range fields {
+		ix, err := tx.CreateBucketIfNotExists(indexName(bucket, field))
+
+		if err != nil {
+			return err
+		}
+
+		if old != nil {
+			if entry := ix.Bucket(Key(old[field])); entry != nil {
+				if err := entry.Delete(key); err != nil {
+					return err
+				}
+			}
+		}
+
+		if rec == nil {
+			continue
+		}
+
+		val, ok := rec[field]
+
+		if !ok || val == nil {
+			continue
+		}
+
+		entry, err := ix.CreateBucketIfNotExists(Key(val))
+
+		if err != nil {
+			return err
+		}
+
+		if err := entry.Put(key, []byte{}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// decode returns the record of a stored json value
+func decode(data []byte) (adaptors.Record, error) {
+	var rec adaptors.Record
+
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return nil, err
+	}
+
+	return rec, nil
+}
+
+// emit decodes the stored value and passes it to the function
+func emit(data []byte, fx func(adaptors.Record) error) error {
+	if data == nil {
+		return nil
+	}
+
+	rec, err := decode(data)
+
+	if err != nil {
+		return err
+	}
+
+	return fx(rec)
+}
+
+// collect returns a function that appends the records passed to it, records read within a transaction are only handed to the
+// caller once it closes, as a caller that reads the store again eg for a relation would otherwise wait on a concurrent update
+func collect(recs *[]adaptors.Record) func(adaptors.Record) error {
+	return func(rec adaptors.Record) error {
+		*recs = append(*recs, rec)
+		return nil
+	}
+}
+
+// dispatch passes each record to the function, stopping at the first error
+func dispatch(recs []adaptors.Record, fx func(adaptors.Record) error) error {
+	for _, rec := range recs {
+		if err := fx(rec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Each iterates the records of the named bucket in key order
+func (s *Store) Each(name string, fx func(adaptors.Record) error) error {
+	var recs []adaptors.Record
+
+	err := s.db.View(func(tx *bdb.Tx) error {
+		bu := tx.Bucket([]byte(name))
+
+		if bu == nil {
+			return fmt.Errorf(adaptors.CollectionNotFoundMessage, name)
+		}
+
+		return bu.ForEach(func(_, v []byte) error {
+			return emit(v, collect(&recs))
+		})
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return dispatch(recs, fx)
+}
+
+// Seek satisfies a condition on the id field through key lookups or cursor seeks and an 'is' condition on an indexed field through its index, returning false for any other condition
+func (s *Store) Seek(name, field string, c parser.Collector, fx func(adaptors.Record) error) (bool, error) {
+	var done bool
+	var recs []adaptors.Record
+
+	err := s.db.View(func(tx *bdb.Tx) error {
+		bu := tx.Bucket([]byte(name))
+
+		if bu == nil {
+			return fmt.Errorf(adaptors.CollectionNotFoundMessage, name)
+		}
+
+		if field == s.id {
+			var err error
+			done, err = seekKey(bu, c, collect(&recs))
+			return err
+		}
+
+		ix := tx.Bucket(indexName(name, field))
+
+		if ix == nil || !c.HasMatch("type", "is") {
+			return nil
+		}
+
+		done = true
+
+		entry := ix.Bucket(Key(c.Get("value")))
+
+		if entry == nil {
+			return nil
+		}
+
+		return entry.ForEach(func(k, _ []byte) error {
+			return emit(bu.Get(k), collect(&recs))
+		})
+	})
+
+	if err != nil {
+		return done, err
+	}
+
+	return done, dispatch(recs, fx)
+}
+
+// seekKey retrieves the records whose keys satisfy the condition, where ordered conditions are only seeked over numeric keys
+func seekKey(bu *bdb.Bucket, c parser.Collector, fx func(adaptors.Record) error) (bool, error) {
+	tag, _ := c.Get("type").(string)
+
+	switch tag {
+	case "is":
+		return true, emit(bu.Get(Key(c.Get("value"))), fx)
+	case "in":
+		items, ok := adaptors.InValues(c)
+
+		if !ok {
+			return false, parser.ErrInvalidCollector
+		}
+
+		for _, item := range items {
+			if err := emit(bu.Get(Key(adaptors.Unwrap(item))), fx); err != nil {
+				return true, err
+			}
+		}
+
+		return true, nil
+	}
+
+	var low, high []byte
+	var ok bool
+
+	switch tag {
+	case "gt", "gte":
+		if low, ok = numericKey(c.Get("value")); !ok {
+			return false, nil
+		}
+	case "lt", "lte":
+		if high, ok = numericKey(c.Get("value")); !ok {
+			return false, nil
+		}
+	case "range":
+		if low, ok = numericKey(c.Get("min")); !ok {
+			return false, nil
+		}
+
+		if high, ok = numericKey(c.Get("max")); !ok {
+			return false, nil
+		}
+	default:
+		return false, nil
+	}
+
+	cur := bu.Cursor()
+
+	var k, v []byte
+
+	if low == nil {
+		k, v = cur.First()
+	} else {
+		k, v = cur.Seek(low)
+	}
+
+	for ; k != nil && (high == nil || bytes.Compare(k, high) <= 0); k, v = cur.Next() {
+		if len(k) != 8 {
+			continue
+		}
+
+		if err := emit(v, fx); err != nil {
+			return true, err
+		}
+	}
+
+	return true, nil
+}
+
+// BuildQuero generates a full query parser that executes its queries against the bolt store
+func BuildQuero(st *Store, mf *adaptors.MatchFactory, ds *parser.InspectionFactory) flux.Reactor {
+	return adaptors.BuildSourceQuero(st, mf, ds)
+}
+
+// Quero returns a new instance of a complete query handler over the bolt store using the default inspections and matchers
+func Quero(st *Store) flux.Reactor {
+	return BuildQuero(st, adaptors.DefaultMatchers, parser.DefaultInspectionFactory)
+}
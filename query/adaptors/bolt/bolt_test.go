@@ -0,0 +1,174 @@
+package bolt
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influx6/data/query/adaptors"
+	"github.com/influx6/data/query/parser"
+	"github.com/influx6/flux"
+)
+
+func store(t *testing.T) (*Store, func()) {
+	dir, err := ioutil.TempDir("", "bolt")
+
+	if err != nil {
+		flux.FatalFailed(t, "Failed to create temp dir: %+s", err)
+	}
+
+	st, err := Open(filepath.Join(dir, "data.db"))
+
+	if err != nil {
+		flux.FatalFailed(t, "Failed to open bolt store: %+s", err)
+	}
+
+	cleanup := func() {
+		st.Close()
+		os.RemoveAll(dir)
+	}
+
+	data, err := ioutil.ReadFile("./../../fixtures/users.json")
+
+	if err != nil {
+		flux.FatalFailed(t, "File.Error occured: %+s", err)
+	}
+
+	var cols map[string][]adaptors.Record
+
+	if err := json.Unmarshal(data, &cols); err != nil {
+		flux.FatalFailed(t, "Failed to decode fixture: %+s", err)
+	}
+
+	if err := st.Index("photos", "user_id"); err != nil {
+		flux.FatalFailed(t, "Failed to index photos: %+s", err)
+	}
+
+	for name, recs := range cols {
+		if err := st.Put(name, recs...); err != nil {
+			flux.FatalFailed(t, "Failed to store %s: %+s", name, err)
+		}
+	}
+
+	return st, cleanup
+}
+
+func run(t *testing.T, st *Store, query string) []adaptors.Record {
+	var ws sync.WaitGroup
+	ws.Add(1)
+
+	var res map[string]interface{}
+
+	qo := Quero(st)
+
+	qo.React(func(r flux.Reactor, err error, d interface{}) {
+		defer ws.Done()
+
+		if err != nil {
+			flux.FatalFailed(t, "Failed to query bolt store: %+s", err)
+		}
+
+		res = d.(map[string]interface{})
+	}, true)
+
+	qo.Send(query)
+
+	ws.Wait()
+	qo.Close()
+
+	return res["users"].([]adaptors.Record)
+}
+
+func TestBoltQuery(t *testing.T) {
+	st, cleanup := store(t)
+	defer cleanup()
+
+	users := run(t, st, `users(id: 1){ name, photos(with: [user_id id]){ url } }`)
+
+	if len(users) != 1 || users[0]["name"] != "alex" {
+		flux.FatalFailed(t, "Expected user 'alex' by key lookup but got %+v", users)
+	}
+
+	if photos := users[0]["photos"].([]adaptors.Record); len(photos) != 2 {
+		flux.FatalFailed(t, "Expected 2 photos from the user_id index but got %d", len(photos))
+	}
+
+	users = run(t, st, `users(){ name, id(gt: 1), age(lt: 40) }`)
+
+	if len(users) != 1 || users[0]["name"] != "josh" {
+		flux.FatalFailed(t, "Expected only 'josh' from the key seek but got %+v", users)
+	}
+
+	flux.LogPassed(t, "Queried bolt buckets through keys, seeks and indexes")
+}
+
+func TestBoltSeek(t *testing.T) {
+	st, cleanup := store(t)
+	defer cleanup()
+
+	var ids []float64
+
+	collect := func(rec adaptors.Record) error {
+		ids = append(ids, rec["id"].(float64))
+		return nil
+	}
+
+	done, err := st.Seek("users", "id", parser.Collector{"type": "range", "min": "2", "max": "3"}, collect)
+
+	if err != nil || !done || len(ids) != 2 || ids[0] != 2 || ids[1] != 3 {
+		flux.FatalFailed(t, "Expected ordered ids 2 and 3 from the range seek but got %v (%v, %+s)", ids, done, err)
+	}
+
+	ids = nil
+
+	if err := st.Delete("photos", 2); err != nil {
+		flux.FatalFailed(t, "Failed to delete photo: %+s", err)
+	}
+
+	done, err = st.Seek("photos", "user_id", parser.Collector{"type": "is", "value": "1"}, collect)
+
+	if err != nil || !done || len(ids) != 1 || ids[0] != 3 {
+		flux.FatalFailed(t, "Expected the index to hold only photo 3 after delete but got %v", ids)
+	}
+
+	if done, _ = st.Seek("photos", "url", parser.Collector{"type": "is", "value": "x"}, collect); done {
+		flux.FatalFailed(t, "Expected seek on an unindexed field to fall back to a scan")
+	}
+
+	flux.LogPassed(t, "Seeked ordered keys and maintained secondary indexes")
+}
+
+func TestBoltWriteWithinEach(t *testing.T) {
+	st, cleanup := store(t)
+
+	//a record large enough to grow the database, which waits on every open read transaction
+	large := adaptors.Record{"id": 100, "name": strings.Repeat("x", 8<<20)}
+	done := make(chan error, 1)
+
+	go func() {
+		done <- st.Each("users", func(rec adaptors.Record) error {
+			if rec["id"].(float64) != 1 {
+				return nil
+			}
+			return st.Put("users", large)
+		})
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			flux.FatalFailed(t, "Failed to write within each: %+s", err)
+		}
+	case <-time.After(5 * time.Second):
+		//the store is left open as closing it waits on the deadlocked transactions
+		flux.FatalFailed(t, "Expected the record function to run outside the read transaction")
+	}
+
+	cleanup()
+	flux.LogPassed(t, "Wrote to the store from within the record function")
+}
@@ -0,0 +1,242 @@
+package csv
+
+import (
+	ecsv "encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/influx6/data/query/adaptors"
+	"github.com/influx6/data/query/parser"
+	"github.com/influx6/flux"
+)
+
+// DefaultSample is the number of rows read to infer the kinds of a table's columns
+const DefaultSample = 100
+
+// Source provides an adaptors.Source over csv files, where each table is made up of one or more files whose first row names the columns
+type Source struct {
+	Sample  int
+	Comma   rune
+	tables  map[string][]string
+	columns map[string][]adaptors.Column
+	rw      sync.RWMutex
+}
+
+// NewSource returns a new Source without any tables
+func NewSource() *Source {
+	return &Source{
+		Sample:  DefaultSample,
+		Comma:   ',',
+		tables:  make(map[string][]string),
+		columns: make(map[string][]adaptors.Column),
+	}
+}
+
+// Open returns a Source over the csv file or directory of csv files at the path, see adaptors.Tables
+func Open(path string) (*Source, error) {
+	tables, err := adaptors.Tables(path, ".csv")
+
+	if err != nil {
+		return nil, err
+	}
+
+	src := NewSource()
+
+	for name, files := range tables {
+		src.Add(name, files...)
+	}
+
+	return src, nil
+}
+
+// Add adds the files as the rows of the named table
+func (s *Source) Add(name string, files ...string) {
+	s.rw.Lock()
+	s.tables[name] = append(s.tables[name], files...)
+	delete(s.columns, name)
+	s.rw.Unlock()
+}
+
+// files returns the files of the named table
+func (s *Source) files(name string) ([]string, error) {
+	s.rw.RLock()
+	files, ok := s.tables[name]
+	s.rw.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf(adaptors.CollectionNotFoundMessage, name)
+	}
+
+	return files, nil
+}
+
+// Columns returns the columns of the named table with their kinds inferred from the first rows of its first file
+func (s *Source) Columns(name string) ([]adaptors.Column, error) {
+	s.rw.RLock()
+	cols, ok := s.columns[name]
+	s.rw.RUnlock()
+
+	if ok {
+		return cols, nil
+	}
+
+	files, err := s.files(name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(files) > 0 {
+		cols, err = s.infer(files[0])
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	s.rw.Lock()
+	s.columns[name] = cols
+	s.rw.Unlock()
+
+	return cols, nil
+}
+
+// infer reads the header and sample rows of the file to infer its columns
+func (s *Source) infer(file string) ([]adaptors.Column, error) {
+	var cols []adaptors.Column
+
+	err := s.read(file, func(header []string) {
+		for _, name := range header {
+			cols = append(cols, adaptors.Column{Name: name})
+		}
+	}, func(row []string, count int) error {
+		if count >= s.Sample {
+			return adaptors.ErrStopEach
+		}
+
+		for index := range cols {
+			if index < len(row) {
+				cols[index].Kind = adaptors.Widen(cols[index].Kind, adaptors.InferKind(row[index]))
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil && err != adaptors.ErrStopEach {
+		return nil, err
+	}
+
+	for index := range cols {
+		if cols[index].Kind == adaptors.KindUnknown {
+			cols[index].Kind = adaptors.KindText
+		}
+	}
+
+	return cols, nil
+}
+
+// read streams the rows of the file, passing its header to hfx and each following row with its count to fx
+func (s *Source) read(file string, hfx func([]string), fx func([]string, int) error) error {
+	fs, err := os.Open(file)
+
+	if err != nil {
+		return err
+	}
+
+	defer fs.Close()
+
+	reader := ecsv.NewReader(fs)
+	reader.Comma = s.Comma
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+
+	if err == io.EOF {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	for index, name := range header {
+		header[index] = strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))
+	}
+
+	hfx(header)
+
+	for count := 0; ; count++ {
+		row, err := reader.Read()
+
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if err := fx(row, count); err != nil {
+			return err
+		}
+	}
+}
+
+// Each streams the rows of the named table's files, converting each value to the inferred kind of its column
+func (s *Source) Each(name string, fx func(adaptors.Record) error) error {
+	files, err := s.files(name)
+
+	if err != nil {
+		return err
+	}
+
+	cols, err := s.Columns(name)
+
+	if err != nil {
+		return err
+	}
+
+	kinds := make(map[string]adaptors.Kind)
+
+	for _, col := range cols {
+		kinds[col.Name] = col.Kind
+	}
+
+	for _, file := range files {
+		var header []string
+
+		err := s.read(file, func(h []string) {
+			header = h
+		}, func(row []string, _ int) error {
+			rec := make(adaptors.Record)
+
+			for index, key := range header {
+				if index < len(row) {
+					rec[key] = adaptors.Convert(row[index], kinds[key])
+				}
+			}
+
+			return fx(rec)
+		})
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// BuildQuero generates a full query parser that executes its queries against the csv tables
+func BuildQuero(src *Source, mf *adaptors.MatchFactory, ds *parser.InspectionFactory) flux.Reactor {
+	return adaptors.BuildSourceQuero(src, mf, ds)
+}
+
+// Quero returns a new instance of a complete query handler over the csv tables using the default inspections and matchers
+func Quero(src *Source) flux.Reactor {
+	return BuildQuero(src, adaptors.DefaultMatchers, parser.DefaultInspectionFactory)
+}
@@ -0,0 +1,94 @@
+package csv
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/influx6/data/query/adaptors"
+	"github.com/influx6/flux"
+)
+
+func run(t *testing.T, src *Source, query string) []adaptors.Record {
+	var ws sync.WaitGroup
+	ws.Add(1)
+
+	var res map[string]interface{}
+
+	qo := Quero(src)
+
+	qo.React(func(r flux.Reactor, err error, d interface{}) {
+		defer ws.Done()
+
+		if err != nil {
+			flux.FatalFailed(t, "Failed to query csv tables: %+s", err)
+		}
+
+		res = d.(map[string]interface{})
+	}, true)
+
+	qo.Send(query)
+
+	ws.Wait()
+	qo.Close()
+
+	return res["users"].([]adaptors.Record)
+}
+
+func TestCSVColumns(t *testing.T) {
+	src, err := Open("./../../fixtures/csv")
+
+	if err != nil {
+		flux.FatalFailed(t, "Failed to open csv directory: %+s", err)
+	}
+
+	cols, err := src.Columns("users")
+
+	if err != nil {
+		flux.FatalFailed(t, "Failed to infer columns: %+s", err)
+	}
+
+	expected := map[string]adaptors.Kind{
+		"id":     adaptors.KindInt,
+		"name":   adaptors.KindText,
+		"age":    adaptors.KindInt,
+		"street": adaptors.KindText,
+		"active": adaptors.KindBool,
+		"score":  adaptors.KindFloat,
+	}
+
+	if len(cols) != len(expected) {
+		flux.FatalFailed(t, "Expected %d columns but got %d", len(expected), len(cols))
+	}
+
+	for _, col := range cols {
+		if expected[col.Name] != col.Kind {
+			flux.FatalFailed(t, "Expected column %q to be %s but got %s", col.Name, expected[col.Name], col.Kind)
+		}
+	}
+
+	flux.LogPassed(t, "Inferred csv column kinds from the header and sample rows")
+}
+
+func TestCSVQuery(t *testing.T) {
+	src, err := Open("./../../fixtures/csv")
+
+	if err != nil {
+		flux.FatalFailed(t, "Failed to open csv directory: %+s", err)
+	}
+
+	users := run(t, src, `users(active: true){ name, score, age(gt: 20, lt: 40), photos(with: [user_id id]){ url } }`)
+
+	if len(users) != 1 || users[0]["name"] != "alex" {
+		flux.FatalFailed(t, "Expected only active user 'alex' aged between 20 and 40 but got %+v", users)
+	}
+
+	if users[0]["score"] != 4.5 {
+		flux.FatalFailed(t, "Expected typed score 4.5 but got %#v", users[0]["score"])
+	}
+
+	if photos := users[0]["photos"].([]adaptors.Record); len(photos) != 2 {
+		flux.FatalFailed(t, "Expected 2 joined photos for 'alex' but got %d", len(photos))
+	}
+
+	flux.LogPassed(t, "Queried csv tables with conditions and joins across files")
+}
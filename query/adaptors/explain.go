@@ -0,0 +1,93 @@
+package adaptors
+
+import (
+	"sort"
+
+	"github.com/influx6/data/query/parser"
+	"github.com/influx6/ds"
+)
+
+// GraphNode describes a record of a parsed query graph in a form that marshals to json
+type GraphNode struct {
+	Name       string                        `json:"name"`
+	Source     string                        `json:"source,omitempty"`
+	Key        string                        `json:"key"`
+	Attrs      []string                      `json:"attrs,omitempty"`
+	Fields     []string                      `json:"fields,omitempty"`
+	Computed   map[string]string             `json:"computed,omitempty"`
+	Rules      map[string][]parser.Collector `json:"rules,omitempty"`
+	Conditions map[string][]parser.Collector `json:"conditions,omitempty"`
+	Line       int                           `json:"line"`
+	Pos        int                           `json:"pos"`
+	Children   []*GraphNode                  `json:"children,omitempty"`
+}
+
+// DescribeGraph returns the tree of GraphNodes of the graph, it must be called before the graph is compiled as compiling may consume its rules
+func DescribeGraph(gs ds.Graphs) (*GraphNode, error) {
+	tree, err := buildTree(gs)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return describeNode(tree, tree.root), nil
+}
+
+// describeNode returns the GraphNode of the node and its children
+func describeNode(tree *queryTree, node *parser.ParseNode) *GraphNode {
+	gn := &GraphNode{
+		Name:   node.Name(),
+		Source: node.Source,
+		Key:    node.Key,
+		Attrs:  node.Attr.All(),
+		Fields: node.Records.Keys(),
+		Rules:  describeConditions(node.Rules),
+		Line:   node.Line,
+		Pos:    node.Pos,
+	}
+
+	sort.Strings(gn.Attrs)
+	sort.Strings(gn.Fields)
+
+	gn.Conditions = describeConditions(node.Records)
+
+	for _, cf := range node.Computed {
+		if gn.Computed == nil {
+			gn.Computed = make(map[string]string)
+		}
+		gn.Computed[cf.Name] = cf.Expr.String()
+	}
+
+	for _, child := range tree.children[node.Key] {
+		gn.Children = append(gn.Children, describeNode(tree, child))
+	}
+
+	return gn
+}
+
+// describeConditions returns copies of the conditions of the collectors with bound parameters replaced by their values
+func describeConditions(co *parser.Collectors) map[string][]parser.Collector {
+	conds := make(map[string][]parser.Collector)
+
+	co.Each(func(list []parser.Collector, key string, _ func()) {
+		for _, item := range list {
+			cond := make(parser.Collector)
+
+			for name, val := range item {
+				if name == parser.ArgsKey {
+					continue
+				}
+
+				cond[name] = Unwrap(val)
+			}
+
+			conds[key] = append(conds[key], cond)
+		}
+	})
+
+	if len(conds) == 0 {
+		return nil
+	}
+
+	return conds
+}
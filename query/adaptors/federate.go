@@ -0,0 +1,446 @@
+package adaptors
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/influx6/data/query/parser"
+	"github.com/influx6/ds"
+)
+
+// DefaultBatchSize is the number of parent keys sent in each IN lookup of a federated child record
+const DefaultBatchSize = 500
+
+//FederatedRelationMessage provides error for child records routed to another adaptor without a 'with' rule to stitch them by
+const FederatedRelationMessage = "Record '%s' is routed to adaptor '%s' and needs a 'with: [childkey parentkey]' rule to join it to its parent"
+
+//FederatedExistsMessage provides error for existence rules on child records routed to another adaptor than their parent
+const FederatedExistsMessage = "Record '%s' is routed to adaptor '%s' and its existence rule can only filter a parent of the same adaptor"
+
+// segment defines the part of a query graph executed by a single adaptor, joined to a record of its parent segment through the 'with' keys of its root
+type segment struct {
+	name    string
+	adaptor Adaptor
+	root    *parser.ParseNode
+	parent  *parser.ParseNode
+	keys    []string
+	nodes   []*parser.ParseNode
+}
+
+// federation defines a query graph split into the segments of each adaptor it touches, with the root segment first and every segment after its parent's
+type federation struct {
+	tree     *queryTree
+	segments []*segment
+	owner    map[string]*segment
+	extras   map[string][]string
+}
+
+// split divides the graph into segments, where a child record routed to another adaptor than its parent starts a new segment
+func (e *Engine) split(gs ds.Graphs) (*federation, error) {
+	tree, err := buildTree(gs)
+
+	if err != nil {
+		return nil, err
+	}
+
+	fd := &federation{
+		tree:   tree,
+		owner:  make(map[string]*segment),
+		extras: make(map[string][]string),
+	}
+
+	name := tree.root.Source
+
+	if name == "" {
+		name = e.registry.Default()
+	}
+
+	ad, err := e.registry.Get(name)
+
+	if err != nil {
+		return nil, tree.root.Report("", err.Error())
+	}
+
+	root := &segment{name: name, adaptor: ad, root: tree.root}
+	fd.segments = append(fd.segments, root)
+
+	if err := fd.walk(e, root, tree.root); err != nil {
+		return nil, err
+	}
+
+	return fd, nil
+}
+
+// walk adds the node and its children to the segment, starting new segments for children routed elsewhere
+func (fd *federation) walk(e *Engine, seg *segment, node *parser.ParseNode) error {
+	if err := checkCapabilities(seg.name, seg.adaptor.Capabilities(), node, node == seg.root && seg.parent != nil); err != nil {
+		return err
+	}
+
+	seg.nodes = append(seg.nodes, node)
+	fd.owner[node.Key] = seg
+
+	for _, child := range fd.tree.children[node.Key] {
+		if child.Source == "" || child.Source == seg.name {
+			if err := fd.walk(e, seg, child); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if _, ok := Existence(child); ok {
+			return child.Report("", fmt.Sprintf(FederatedExistsMessage, child.Name(), child.Source))
+		}
+
+		keys, ok := Relation(child)
+
+		if !ok {
+			return child.Report("", fmt.Sprintf(FederatedRelationMessage, child.Name(), child.Source))
+		}
+
+		ad, err := e.registry.Get(child.Source)
+
+		if err != nil {
+			return child.Report("", err.Error())
+		}
+
+		//records selecting only some fields need the keys the engine stitches them by
+		if fields := node.Records.Keys(); (len(fields) > 0 || len(node.Computed) > 0) && !node.Records.Has(keys[1]) {
+			fd.extras[node.Key] = append(fd.extras[node.Key], keys[1])
+		}
+
+		if fields := child.Records.Keys(); (len(fields) > 0 || len(child.Computed) > 0) && !child.Records.Has(keys[0]) {
+			fd.extras[child.Key] = append(fd.extras[child.Key], keys[0])
+		}
+
+		cs := &segment{name: child.Source, adaptor: ad, root: child, parent: node, keys: keys}
+		fd.segments = append(fd.segments, cs)
+
+		if err := fd.walk(e, cs, child); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// graph returns a new graph of the segment's records, the root of a child segment loses its 'with' rule and gains an 'in' rule over the giving parent key values when they are not nil
+func (fd *federation) graph(seg *segment, values []interface{}) ds.Graphs {
+	gs := ds.NewGraph()
+	copies := make(map[string]*parser.ParseNode)
+
+	for _, node := range seg.nodes {
+		tp, pa, pk := parser.MODELSUBROOT, node.Parent, node.PKey
+
+		if node == seg.root {
+			tp, pa, pk = parser.MODELROOT, "", ""
+		}
+
+		cp := cloneNode(node, tp, pa, pk, gs)
+
+		for _, field := range fd.extras[node.Key] {
+			cp.Records.Set(field, nil)
+		}
+
+		if node == seg.root && seg.parent != nil {
+			cp.Rules.Remove("with")
+
+			if values != nil {
+				rules, _ := cp.Rules.Get(seg.keys[0])
+				cp.Rules.Set(seg.keys[0], append(rules, parser.Collector{"type": "in", "range": values}))
+			}
+		}
+
+		gs.AddNode(cp)
+
+		if parent, ok := copies[node.PKey]; ok && node != seg.root {
+			gs.BindNodes(parent, cp, 0)
+		}
+
+		copies[node.Key] = cp
+	}
+
+	return gs
+}
+
+// CloneGraph returns a copy of the graph whose records and conditions can be compiled, bound or changed without touching the original
+func CloneGraph(gs ds.Graphs) (ds.Graphs, error) {
+	tree, err := buildTree(gs)
+
+	if err != nil {
+		return nil, err
+	}
+
+	cl := ds.NewGraph()
+
+	var clone func(node *parser.ParseNode, parent *parser.ParseNode)
+
+	clone = func(node *parser.ParseNode, parent *parser.ParseNode) {
+		cp := cloneNode(node, node.NType, node.Parent, node.PKey, cl)
+		cl.AddNode(cp)
+
+		if parent != nil {
+			cl.BindNodes(parent, cp, 0)
+		}
+
+		for _, child := range tree.children[node.Key] {
+			clone(child, cp)
+		}
+	}
+
+	clone(tree.root, nil)
+
+	return cl, nil
+}
+
+// cloneNode returns a copy of the node within the graph with the giving type and parent, keeping its key so its children still refer to it
+func cloneNode(node *parser.ParseNode, tp parser.NodeType, pa, pk string, gs ds.Graphs) *parser.ParseNode {
+	name := node.Name()
+
+	if node.Source != "" {
+		name += "@" + node.Source
+	}
+
+	cp := parser.NewParseNode(tp, name, pa, pk, gs)
+	cp.Key = node.Key
+	cp.Attr = node.Attr
+	cp.Line, cp.Pos = node.Line, node.Pos
+	cp.Marks = node.Marks
+	cp.Rules = cloneCollectors(node.Rules)
+	cp.Records = cloneCollectors(node.Records)
+	cp.Computed = append([]parser.ComputedField(nil), node.Computed...)
+	return cp
+}
+
+// cloneCollectors returns a copy of the collectors whose conditions can be changed without touching the original
+func cloneCollectors(c *parser.Collectors) *parser.Collectors {
+	co := parser.NewCollectors()
+
+	c.Each(func(list []parser.Collector, key string, _ func()) {
+		var items []parser.Collector
+
+		for _, item := range list {
+			items = append(items, item.Clone())
+		}
+
+		co.Set(key, items)
+	})
+
+	return co
+}
+
+// federate executes each segment of the graph on its own adaptor level by level, the child segments of a level are looked up in batches of
+// their parents' keys, Parallelism of them at a time, and nested under their parents once the whole level is loaded
+func (e *Engine) federate(ctx context.Context, fd *federation) (Result, error) {
+	res, records, err := fd.execute(ctx, fd.segments[0], nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, level := range fd.levels() {
+		loads := make([]*load, len(level))
+
+		if err := Parallel(ctx, e.Parallelism, len(level), func(ctx context.Context, index int) error {
+			ld, err := e.fetch(ctx, fd, level[index], records[level[index].parent.Key])
+			loads[index] = ld
+			return err
+		}); err != nil {
+			return nil, err
+		}
+
+		for index, seg := range level {
+			loads[index].attach(seg, records)
+		}
+	}
+
+	for key, fields := range fd.extras {
+		for _, rec := range records[key] {
+			for _, field := range fields {
+				delete(rec, field)
+			}
+		}
+	}
+
+	return res, nil
+}
+
+// levels returns the child segments grouped by their depth below the root segment, keeping their order within each level
+func (fd *federation) levels() [][]*segment {
+	depth := map[*segment]int{fd.segments[0]: 0}
+
+	var levels [][]*segment
+
+	for _, seg := range fd.segments[1:] {
+		level := depth[fd.owner[seg.parent.Key]] + 1
+		depth[seg] = level
+
+		for len(levels) < level {
+			levels = append(levels, nil)
+		}
+
+		levels[level-1] = append(levels[level-1], seg)
+	}
+
+	return levels
+}
+
+// load defines the records of a child segment looked up for the keys of its parents, with the records of its root grouped by their join key
+type load struct {
+	found  map[string][]map[string]interface{}
+	groups map[string][]map[string]interface{}
+}
+
+// fetch executes the child segment for the keys of its parent records, BatchSize keys at a time
+func (e *Engine) fetch(ctx context.Context, fd *federation, seg *segment, parents []map[string]interface{}) (*load, error) {
+	seen := make(map[string]bool)
+
+	var values []interface{}
+
+	for _, rec := range parents {
+		val := rec[seg.keys[1]]
+
+		if val == nil {
+			continue
+		}
+
+		if key := KeyOf(val); !seen[key] {
+			seen[key] = true
+			values = append(values, parser.Param{Value: Unwrap(val)})
+		}
+	}
+
+	size := e.BatchSize
+
+	if size <= 0 {
+		size = DefaultBatchSize
+	}
+
+	ld := &load{
+		found:  make(map[string][]map[string]interface{}),
+		groups: make(map[string][]map[string]interface{}),
+	}
+
+	for begin := 0; begin < len(values); begin += size {
+		end := begin + size
+
+		if end > len(values) {
+			end = len(values)
+		}
+
+		_, found, err := fd.execute(ctx, seg, values[begin:end])
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, rec := range found[seg.root.Key] {
+			key := KeyOf(rec[seg.keys[0]])
+			ld.groups[key] = append(ld.groups[key], rec)
+		}
+
+		mergeRecords(ld.found, found)
+	}
+
+	return ld, nil
+}
+
+// attach adds the loaded records to the records of the query and nests each group of child records under its parent
+func (ld *load) attach(seg *segment, records map[string][]map[string]interface{}) {
+	mergeRecords(records, ld.found)
+
+	for _, rec := range records[seg.parent.Key] {
+		group := ld.groups[KeyOf(rec[seg.keys[1]])]
+
+		if group == nil || rec[seg.keys[1]] == nil {
+			group = []map[string]interface{}{}
+		}
+
+		rec[seg.root.Name()] = group
+	}
+}
+
+// mergeRecords appends the records of each node key of src to those of dst
+func mergeRecords(dst, src map[string][]map[string]interface{}) {
+	for key, recs := range src {
+		dst[key] = append(dst[key], recs...)
+	}
+}
+
+// execute compiles and runs the segment's graph on its adaptor, returning its result with the records of each of its nodes
+func (fd *federation) execute(ctx context.Context, seg *segment, values []interface{}) (Result, map[string][]map[string]interface{}, error) {
+	plan, err := seg.adaptor.Compile(fd.graph(seg, values))
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	res, err := seg.adaptor.Execute(ctx, plan)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	records := make(map[string][]map[string]interface{})
+
+	var collect func(node *parser.ParseNode, recs []map[string]interface{})
+
+	collect = func(node *parser.ParseNode, recs []map[string]interface{}) {
+		records[node.Key] = append(records[node.Key], recs...)
+
+		for _, child := range fd.tree.children[node.Key] {
+			if fd.owner[child.Key] != seg {
+				continue
+			}
+
+			for _, rec := range recs {
+				collect(child, asRecords(rec[child.Name()]))
+			}
+		}
+	}
+
+	collect(seg.root, asRecords(res[seg.root.Name()]))
+
+	return res, records, nil
+}
+
+var recordType = reflect.TypeOf(map[string]interface{}{})
+
+// asRecords returns the records held by a result value, accepting a single record or a list of records of any map type keyed by strings
+func asRecords(val interface{}) []map[string]interface{} {
+	if rec, ok := asRecord(val); ok {
+		return []map[string]interface{}{rec}
+	}
+
+	rv := reflect.ValueOf(val)
+
+	if rv.Kind() != reflect.Slice {
+		return nil
+	}
+
+	var recs []map[string]interface{}
+
+	for index := 0; index < rv.Len(); index++ {
+		if rec, ok := asRecord(rv.Index(index).Interface()); ok {
+			recs = append(recs, rec)
+		}
+	}
+
+	return recs
+}
+
+// asRecord returns the value as a map sharing its entries, if it is a map type keyed by strings
+func asRecord(val interface{}) (map[string]interface{}, bool) {
+	if val == nil {
+		return nil, false
+	}
+
+	rv := reflect.ValueOf(val)
+
+	if rv.Kind() != reflect.Map || !rv.Type().ConvertibleTo(recordType) {
+		return nil, false
+	}
+
+	return rv.Convert(recordType).Interface().(map[string]interface{}), true
+}
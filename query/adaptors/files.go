@@ -0,0 +1,204 @@
+package adaptors
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Kind defines the inferred type of a column in a file based table
+type Kind int
+
+const (
+	// KindUnknown is the kind of a column that only holds empty values
+	KindUnknown Kind = iota
+	// KindBool is the kind of a column holding true/false values
+	KindBool
+	// KindInt is the kind of a column holding whole numbers
+	KindInt
+	// KindFloat is the kind of a column holding decimal numbers
+	KindFloat
+	// KindText is the kind of any other column
+	KindText
+)
+
+// String returns the name of the kind
+func (k Kind) String() string {
+	switch k {
+	case KindBool:
+		return "bool"
+	case KindInt:
+		return "int"
+	case KindFloat:
+		return "float"
+	case KindText:
+		return "text"
+	}
+	return "unknown"
+}
+
+// Column defines a named and typed column of a file based table
+type Column struct {
+	Name string
+	Kind Kind
+}
+
+// InferKind returns the kind of a single raw value
+func InferKind(val string) Kind {
+	val = strings.TrimSpace(val)
+
+	if val == "" {
+		return KindUnknown
+	}
+
+	if _, err := strconv.ParseInt(val, 10, 64); err == nil {
+		return KindInt
+	}
+
+	if _, err := strconv.ParseFloat(val, 64); err == nil {
+		return KindFloat
+	}
+
+	if lower := strings.ToLower(val); lower == "true" || lower == "false" {
+		return KindBool
+	}
+
+	return KindText
+}
+
+// KindOfValue returns the kind of an already decoded value eg from json
+func KindOfValue(val interface{}) Kind {
+	switch mo := val.(type) {
+	case nil:
+		return KindUnknown
+	case bool:
+		return KindBool
+	case float64:
+		if mo == float64(int64(mo)) {
+			return KindInt
+		}
+		return KindFloat
+	case int, int64:
+		return KindInt
+	}
+	return KindText
+}
+
+// Widen returns the kind able to hold values of both kinds, where ints widen to floats and any other mix becomes text
+func Widen(a, b Kind) Kind {
+	switch {
+	case a == b || b == KindUnknown:
+		return a
+	case a == KindUnknown:
+		return b
+	case (a == KindInt && b == KindFloat) || (a == KindFloat && b == KindInt):
+		return KindFloat
+	}
+	return KindText
+}
+
+// Convert returns the raw value as the kind, empty values become nil and values that fail to convert are kept as text
+func Convert(val string, k Kind) interface{} {
+	trimmed := strings.TrimSpace(val)
+
+	if trimmed == "" {
+		return nil
+	}
+
+	switch k {
+	case KindInt:
+		if num, err := strconv.ParseInt(trimmed, 10, 64); err == nil {
+			return num
+		}
+	case KindFloat:
+		if num, err := strconv.ParseFloat(trimmed, 64); err == nil {
+			return num
+		}
+	case KindBool:
+		if lower := strings.ToLower(trimmed); lower == "true" || lower == "false" {
+			return lower == "true"
+		}
+	}
+
+	return val
+}
+
+// Tables returns the files of each table found at the path, a file is a table named by its base name without extension, while a directory holds a table per matching file and a table per sub-directory made up of the matching files within it
+func Tables(path string, exts ...string) (map[string][]string, error) {
+	info, err := os.Stat(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	tables := make(map[string][]string)
+
+	if !info.IsDir() {
+		tables[tableName(path)] = []string{path}
+		return tables, nil
+	}
+
+	entries, err := ioutil.ReadDir(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		full := filepath.Join(path, entry.Name())
+
+		if !entry.IsDir() {
+			if hasExt(full, exts) {
+				tables[tableName(full)] = append(tables[tableName(full)], full)
+			}
+			continue
+		}
+
+		parts, err := ioutil.ReadDir(full)
+
+		if err != nil {
+			return nil, err
+		}
+
+		var files []string
+
+		for _, part := range parts {
+			if !part.IsDir() && hasExt(part.Name(), exts) {
+				files = append(files, filepath.Join(full, part.Name()))
+			}
+		}
+
+		if len(files) > 0 {
+			sort.Strings(files)
+			tables[entry.Name()] = files
+		}
+	}
+
+	return tables, nil
+}
+
+// tableName returns the base name of the file without its extension
+func tableName(file string) string {
+	base := filepath.Base(file)
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
+
+// hasExt returns true if the file has one of the extensions, any file matches when none are given
+func hasExt(file string, exts []string) bool {
+	if len(exts) == 0 {
+		return true
+	}
+
+	ext := strings.ToLower(filepath.Ext(file))
+
+	for _, item := range exts {
+		if ext == item {
+			return true
+		}
+	}
+
+	return false
+}
@@ -1,3 +1,117 @@
 package json
 
-//json
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/influx6/data/query/adaptors"
+	"github.com/influx6/data/query/parser"
+	"github.com/influx6/flux"
+)
+
+// Document provides an adaptors.Source over decoded json data, where a json object's keys name its collections and a json array is the collection of every root record
+type Document struct {
+	data interface{}
+}
+
+// NewDocument returns a new Document over already decoded json data eg a map[string]interface{} or []interface{}
+func NewDocument(data interface{}) *Document {
+	return &Document{data: data}
+}
+
+// Load decodes the json data of the reader into a Document
+func Load(r io.Reader) (*Document, error) {
+	var data interface{}
+
+	if err := json.NewDecoder(r).Decode(&data); err != nil {
+		return nil, err
+	}
+
+	return NewDocument(data), nil
+}
+
+// Open decodes the json file into a Document
+func Open(file string) (*Document, error) {
+	fs, err := os.Open(file)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer fs.Close()
+
+	return Load(fs)
+}
+
+// Each iterates the records of the named collection
+func (d *Document) Each(name string, fx func(adaptors.Record) error) error {
+	switch mo := d.data.(type) {
+	case map[string]interface{}:
+		col, ok := mo[name]
+
+		if !ok {
+			return fmt.Errorf(adaptors.CollectionNotFoundMessage, name)
+		}
+
+		return each(col, fx)
+	case []interface{}, []map[string]interface{}, []adaptors.Record:
+		return each(mo, fx)
+	}
+
+	return fmt.Errorf(adaptors.CollectionNotFoundMessage, name)
+}
+
+// each iterates a json object or list of objects
+func each(col interface{}, fx func(adaptors.Record) error) error {
+	switch mo := col.(type) {
+	case map[string]interface{}:
+		return fx(adaptors.Record(mo))
+	case adaptors.Record:
+		return fx(mo)
+	case []adaptors.Record:
+		for _, item := range mo {
+			if err := fx(item); err != nil {
+				return err
+			}
+		}
+	case []map[string]interface{}:
+		for _, item := range mo {
+			if err := fx(adaptors.Record(item)); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for _, item := range mo {
+			rec, ok := item.(map[string]interface{})
+
+			if !ok {
+				continue
+			}
+
+			if err := fx(adaptors.Record(rec)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// BuildQuero generates a full query parser that executes its queries against the json document
+func BuildQuero(doc *Document, mf *adaptors.MatchFactory, ds *parser.InspectionFactory) flux.Reactor {
+	return adaptors.BuildSourceQuero(doc, mf, ds)
+}
+
+// Quero returns a new instance of a complete query handler over the json document using the default inspections and matchers
+func Quero(doc *Document) flux.Reactor {
+	return BuildQuero(doc, adaptors.DefaultMatchers, parser.DefaultInspectionFactory)
+}
+
+// QueroJSON attaches a json reactor that marshalls all response out as a json string
+func QueroJSON(doc *Document) flux.Reactor {
+	qo := Quero(doc)
+	qo.Bind(flux.JSONReactor(), true)
+	return qo
+}
@@ -0,0 +1,81 @@
+package json
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/influx6/data/query/adaptors"
+	"github.com/influx6/flux"
+)
+
+func run(t *testing.T, query string) map[string]interface{} {
+	doc, err := Open("./../../fixtures/users.json")
+
+	if err != nil {
+		flux.FatalFailed(t, "File.Error occured: %+s", err)
+	}
+
+	var ws sync.WaitGroup
+	ws.Add(1)
+
+	var res map[string]interface{}
+
+	qo := Quero(doc)
+
+	qo.React(func(r flux.Reactor, err error, d interface{}) {
+		defer ws.Done()
+
+		if err != nil {
+			flux.FatalFailed(t, "Failed to query json document: %+s", err)
+		}
+
+		res = d.(map[string]interface{})
+	}, true)
+
+	qo.Send(query)
+
+	ws.Wait()
+	qo.Close()
+
+	return res
+}
+
+func TestJSONQuery(t *testing.T) {
+	res := run(t, `users(){ name, age(gt: 20, lt: 40), photos(with: [user_id id]){ url } }`)
+
+	users := res["users"].([]adaptors.Record)
+
+	if len(users) != 2 {
+		flux.FatalFailed(t, "Expected 2 users aged between 20 and 40 but got %d", len(users))
+	}
+
+	if photos := users[0]["photos"].([]adaptors.Record); len(photos) != 2 {
+		flux.FatalFailed(t, "Expected 2 joined photos for 'alex' but got %d", len(photos))
+	}
+
+	if _, ok := users[0]["street"]; ok {
+		flux.FatalFailed(t, "Expected only selected fields but found 'street'")
+	}
+
+	flux.LogPassed(t, "Queried json collections with conditions and joins")
+}
+
+func TestJSONEmbedded(t *testing.T) {
+	res := run(t, `users(id: 1){ name, address{ city }, skills(){ name, level(gte: 6) } }`)
+
+	users := res["users"].([]adaptors.Record)
+
+	if len(users) != 1 {
+		flux.FatalFailed(t, "Expected user with id 1 but got %d users", len(users))
+	}
+
+	if addr, ok := users[0]["address"].(adaptors.Record); !ok || addr["city"] != "lagos" {
+		flux.FatalFailed(t, "Expected embedded address object: %+v", users[0]["address"])
+	}
+
+	if skills := users[0]["skills"].([]adaptors.Record); len(skills) != 1 {
+		flux.FatalFailed(t, "Expected 1 embedded skill with level >= 6 but got %d", len(skills))
+	}
+
+	flux.LogPassed(t, "Queried embedded json objects and lists")
+}
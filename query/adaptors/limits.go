@@ -0,0 +1,156 @@
+package adaptors
+
+import (
+	"fmt"
+
+	"github.com/influx6/data/query/parser"
+	"github.com/influx6/ds"
+	"github.com/influx6/flux"
+)
+
+// NodeCostFx defines a function type that estimates the cost of retrieving a record at the giving depth
+type NodeCostFx func(node *parser.ParseNode, depth int) int
+
+// DefaultNodeCost estimates a record as one unit plus one per field, computed field and condition, multiplied by its depth since every nested record multiplies the rows of the join
+func DefaultNodeCost(node *parser.ParseNode, depth int) int {
+	cost := 1 + len(node.Records.Keys()) + len(node.Computed)
+
+	node.Rules.EachCondition(func(_ string, _ parser.Collector, _ func()) {
+		cost++
+	})
+
+	node.Records.EachCondition(func(_ string, _ parser.Collector, _ func()) {
+		cost++
+	})
+
+	return cost * depth
+}
+
+// Limits defines the budget a parsed query must stay within, a zero value disables the giving limit. MaxCost bounds the cost of each
+// record on its own, as estimated by Cost
+type Limits struct {
+	MaxDepth   int
+	MaxRecords int
+	MaxFields  int
+	MaxIn      int
+	MaxCost    int
+	Cost       NodeCostFx
+}
+
+// LimitError provides a custom error for queries that exceed a limit
+type LimitError struct {
+	Node      string
+	Limit     string
+	Max       int
+	Value     int
+	Line, Pos int
+}
+
+// Error returns a string that match the error interface{}
+func (l *LimitError) Error() string {
+	return fmt.Sprintf("Limit(%s): Node: %s (Line: %d, Pos: %d) -> Message: %d exceeds the maximum of %d", l.Limit, l.Node, l.Line, l.Pos, l.Value, l.Max)
+}
+
+// exceeded returns a LimitError for the node if the value is above a non-zero maximum
+func exceeded(node *parser.ParseNode, limit string, max, value int) error {
+	if max <= 0 || value <= max {
+		return nil
+	}
+
+	return &LimitError{
+		Node:  node.Name(),
+		Limit: limit,
+		Max:   max,
+		Value: value,
+		Line:  node.Line,
+		Pos:   node.Pos,
+	}
+}
+
+// Check walks the graph and returns a LimitError naming the first record that takes the query over any of the limits
+func (l *Limits) Check(gs ds.Graphs) error {
+	mo, err := DFGraph(gs)
+
+	if err != nil {
+		return err
+	}
+
+	costfx := l.Cost
+
+	if costfx == nil {
+		costfx = DefaultNodeCost
+	}
+
+	depths := make(map[string]int)
+	var records, fields int
+
+	for mo.Next() == nil {
+		node := mo.Node().(*parser.ParseNode)
+
+		depth := depths[node.PKey] + 1
+		depths[node.Key] = depth
+
+		records++
+		fields += len(node.Records.Keys()) + len(node.Computed)
+
+		if err := exceeded(node, "depth", l.MaxDepth, depth); err != nil {
+			return err
+		}
+
+		if err := exceeded(node, "records", l.MaxRecords, records); err != nil {
+			return err
+		}
+
+		if err := exceeded(node, "fields", l.MaxFields, fields); err != nil {
+			return err
+		}
+
+		var inerr error
+
+		checkIn := func(_ string, c parser.Collector, stop func()) {
+			if !c.HasMatch("type", "in") {
+				return
+			}
+
+			items, _ := InValues(c)
+
+			if inerr = exceeded(node, "in", l.MaxIn, len(items)); inerr != nil {
+				stop()
+			}
+		}
+
+		node.Rules.EachCondition(checkIn)
+
+		if inerr == nil {
+			node.Records.EachCondition(checkIn)
+		}
+
+		if inerr != nil {
+			return inerr
+		}
+
+		if err := exceeded(node, "cost", l.MaxCost, costfx(node, depth)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// LimitAdaptor returns a reactor that rejects any ds.Graphs which exceeds the limits before passing it on
+func LimitAdaptor(l *Limits) flux.Reactor {
+	return QueryAdaptor(func(r flux.Reactor, gs ds.Graphs) {
+		if err := l.Check(gs); err != nil {
+			r.ReplyError(err)
+			return
+		}
+		r.Reply(gs)
+	})
+}
+
+// LimitParser returns a Reactor that combines the ChunkParser with the LimitAdaptor to reject over-budget stringed queries
+func LimitParser(ds *parser.InspectionFactory, l *Limits) flux.Reactor {
+	ms := ChunkParser(ds)
+	ms.Bind(LimitAdaptor(l), true)
+	return ms
+}
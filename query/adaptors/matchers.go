@@ -0,0 +1,455 @@
+package adaptors
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/influx6/data/query/parser"
+)
+
+//MatcherNotFoundMessage provides error for not found condition matchers
+const MatcherNotFoundMessage = "Error: Matcher for condition '%s' not Found!"
+
+// MatchFx defines a function type that evaluates a field's value against a condition collector in memory
+type MatchFx func(val interface{}, c parser.Collector) (bool, error)
+
+// MatchFactory provides a factory of in-memory evaluators for condition types, the in-memory counterpart of the sql OPFactory templates
+type MatchFactory struct {
+	//Now returns the time relative date conditions are resolved against, time.Now is used when nil
+	Now     func() time.Time
+	factory map[string]MatchFx
+	rw      sync.RWMutex
+}
+
+// now returns the current time of the factory
+func (m *MatchFactory) now() time.Time {
+	if m.Now != nil {
+		return m.Now()
+	}
+	return time.Now()
+}
+
+// NewMatchFactory returns a new MatchFactory instance
+func NewMatchFactory() *MatchFactory {
+	return &MatchFactory{factory: make(map[string]MatchFx)}
+}
+
+// Has returns true/false if a matcher exists for the condition type
+func (m *MatchFactory) Has(tag string) bool {
+	m.rw.RLock()
+	_, ok := m.factory[tag]
+	m.rw.RUnlock()
+	return ok
+}
+
+// Add adds a matcher for a condition type, replacing any existing one
+func (m *MatchFactory) Add(tag string, fx MatchFx) {
+	m.rw.Lock()
+	m.factory[tag] = fx
+	m.rw.Unlock()
+}
+
+// Remove removes the matcher of a condition type
+func (m *MatchFactory) Remove(tag string) {
+	m.rw.Lock()
+	delete(m.factory, tag)
+	m.rw.Unlock()
+}
+
+// Match evaluates the value against the collector using the matcher of the collector's type
+func (m *MatchFactory) Match(val interface{}, c parser.Collector) (bool, error) {
+	tag, _ := c.Get("type").(string)
+
+	m.rw.RLock()
+	fx, ok := m.factory[tag]
+	m.rw.RUnlock()
+
+	if !ok {
+		return false, fmt.Errorf(MatcherNotFoundMessage, tag)
+	}
+
+	return fx(val, c)
+}
+
+// DefaultMatchers provides a singleton of the default in-memory condition matchers
+var DefaultMatchers = NewMatchFactory()
+
+// Unwrap returns the underline value of a parser.Param or the value itself
+func Unwrap(val interface{}) interface{} {
+	if pm, ok := val.(parser.Param); ok {
+		return pm.Value
+	}
+	return val
+}
+
+// ToFloat returns the numeric value of a number or a string holding one
+func ToFloat(val interface{}) (float64, bool) {
+	switch mo := Unwrap(val).(type) {
+	case int:
+		return float64(mo), true
+	case int8:
+		return float64(mo), true
+	case int16:
+		return float64(mo), true
+	case int32:
+		return float64(mo), true
+	case int64:
+		return float64(mo), true
+	case uint:
+		return float64(mo), true
+	case uint8:
+		return float64(mo), true
+	case uint16:
+		return float64(mo), true
+	case uint32:
+		return float64(mo), true
+	case uint64:
+		return float64(mo), true
+	case float32:
+		return float64(mo), true
+	case float64:
+		return mo, true
+	case fmt.Stringer:
+		num, err := strconv.ParseFloat(strings.TrimSpace(mo.String()), 64)
+		return num, err == nil
+	case string:
+		num, err := strconv.ParseFloat(parser.Unquote(mo), 64)
+		return num, err == nil
+	}
+	return 0, false
+}
+
+// ToString returns the string form of a value as used for comparisons
+func ToString(val interface{}) string {
+	switch mo := Unwrap(val).(type) {
+	case nil:
+		return ""
+	case string:
+		return parser.Unquote(mo)
+	case []byte:
+		return string(mo)
+	default:
+		return fmt.Sprintf("%v", mo)
+	}
+}
+
+// Compare compares two values numerically when both are numbers and as strings otherwise, returning -1,0 or 1
+func Compare(a, b interface{}) int {
+	if fa, ok := ToFloat(a); ok {
+		if fb, ok := ToFloat(b); ok {
+			switch {
+			case fa < fb:
+				return -1
+			case fa > fb:
+				return 1
+			}
+			return 0
+		}
+	}
+
+	return strings.Compare(ToString(a), ToString(b))
+}
+
+// Equals returns true if the field value equals the condition value, where the condition value 'null' equals a missing or nil field
+func Equals(val, cond interface{}) bool {
+	cond = Unwrap(cond)
+
+	if val == nil || cond == nil {
+		return val == nil && (cond == nil || ToString(cond) == "null")
+	}
+
+	return Compare(val, cond) == 0
+}
+
+// KeyOf returns a comparable key for a value so equal numbers of different types share the same key
+func KeyOf(val interface{}) string {
+	if num, ok := ToFloat(val); ok {
+		return strconv.FormatFloat(num, 'f', -1, 64)
+	}
+	return ToString(val)
+}
+
+// ToTime returns the time of a time.Time or a string holding an ISO-8601 date
+func ToTime(val interface{}) (time.Time, bool) {
+	switch mo := Unwrap(val).(type) {
+	case time.Time:
+		return mo, true
+	case *time.Time:
+		if mo != nil {
+			return *mo, true
+		}
+	case string:
+		date, err := parser.ParseDate(mo)
+
+		if err == nil && !date.Relative {
+			return date.Time, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// dateMatch returns a matcher testing the time of a date value against the condition at the factory's current time, values that are not dates never match
+func dateMatch(m *MatchFactory, test func(tm time.Time, c parser.Collector, now time.Time) (bool, error)) MatchFx {
+	return func(val interface{}, c parser.Collector) (bool, error) {
+		tm, ok := ToTime(val)
+
+		if !ok {
+			return false, nil
+		}
+
+		return test(tm, c, m.now())
+	}
+}
+
+// inPeriod returns true if the time is within the period, including its bounds
+func inPeriod(tm, from, to time.Time) bool {
+	return !tm.Before(from) && !tm.After(to)
+}
+
+// numericMatch returns a matcher comparing a value against the collector's numeric value with the giving test
+func numericMatch(test func(int) bool) MatchFx {
+	return func(val interface{}, c parser.Collector) (bool, error) {
+		if !c.Has("value") {
+			return false, parser.ErrInvalidCollector
+		}
+
+		if _, ok := ToFloat(val); !ok {
+			return false, nil
+		}
+
+		return test(Compare(val, c.Get("value"))), nil
+	}
+}
+
+// textMatch returns a matcher testing the string form of a value against the collector's unquoted value, nil values never match
+func textMatch(test func(val, pattern string) (bool, error)) MatchFx {
+	return func(val interface{}, c parser.Collector) (bool, error) {
+		if !c.Has("value") {
+			return false, parser.ErrInvalidCollector
+		}
+
+		if val == nil {
+			return false, nil
+		}
+
+		return test(ToString(val), ToString(c.Get("value")))
+	}
+}
+
+// LikePattern returns the regular expression of a sql LIKE pattern, where % matches any run of characters and _ any single one
+func LikePattern(pattern string) string {
+	var buf bytes.Buffer
+	buf.WriteString("(?s)^")
+
+	for _, r := range pattern {
+		switch r {
+		case '%':
+			buf.WriteString(".*")
+		case '_':
+			buf.WriteString(".")
+		default:
+			buf.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+
+	buf.WriteString("$")
+	return buf.String()
+}
+
+// maxPatterns bounds the compiled patterns kept by matchPattern
+const maxPatterns = 256
+
+var patterns = struct {
+	compiled map[string]*regexp.Regexp
+	rw       sync.RWMutex
+}{compiled: make(map[string]*regexp.Regexp)}
+
+// matchPattern returns true if the value matches the regular expression, compiled expressions are reused across records
+func matchPattern(val, pattern string) (bool, error) {
+	patterns.rw.RLock()
+	re, ok := patterns.compiled[pattern]
+	patterns.rw.RUnlock()
+
+	if !ok {
+		var err error
+
+		if re, err = regexp.Compile(pattern); err != nil {
+			return false, err
+		}
+
+		patterns.rw.Lock()
+		if len(patterns.compiled) >= maxPatterns {
+			patterns.compiled = make(map[string]*regexp.Regexp)
+		}
+		patterns.compiled[pattern] = re
+		patterns.rw.Unlock()
+	}
+
+	return re.MatchString(val), nil
+}
+
+// SearchTerms returns the lowercase words of a text, as matched by the in-memory search condition
+func SearchTerms(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// AddDefaultMatchers adds in-memory matchers for the default inspections to the supplied MatchFactory
+func AddDefaultMatchers(m *MatchFactory) {
+	m.Add("is", func(val interface{}, c parser.Collector) (bool, error) {
+		if !c.Has("value") {
+			return false, parser.ErrInvalidCollector
+		}
+		return Equals(val, c.Get("value")), nil
+	})
+
+	m.Add("isnot", func(val interface{}, c parser.Collector) (bool, error) {
+		if !c.Has("value") {
+			return false, parser.ErrInvalidCollector
+		}
+		return !Equals(val, c.Get("value")), nil
+	})
+
+	//a missing field is null
+	m.Add("isnull", func(val interface{}, c parser.Collector) (bool, error) {
+		want, ok := c.Get("value").(bool)
+
+		if !ok {
+			return false, parser.ErrInvalidCollector
+		}
+
+		return (val == nil) == want, nil
+	})
+
+	m.Add("notnull", func(val interface{}, c parser.Collector) (bool, error) {
+		want, ok := c.Get("value").(bool)
+
+		if !ok {
+			return false, parser.ErrInvalidCollector
+		}
+
+		return (val != nil) == want, nil
+	})
+
+	m.Add("before", dateMatch(m, func(tm time.Time, c parser.Collector, now time.Time) (bool, error) {
+		date, err := parser.DateValue(c.Get("value"))
+		return err == nil && tm.Before(date.Resolve(now)), err
+	}))
+
+	m.Add("after", dateMatch(m, func(tm time.Time, c parser.Collector, now time.Time) (bool, error) {
+		date, err := parser.DateValue(c.Get("value"))
+		return err == nil && tm.After(date.Resolve(now)), err
+	}))
+
+	m.Add("within", dateMatch(m, func(tm time.Time, c parser.Collector, now time.Time) (bool, error) {
+		date, err := parser.DateValue(c.Get("value"))
+
+		if err != nil {
+			return false, err
+		}
+
+		from, to := date.Within(now)
+		return inPeriod(tm, from, to), nil
+	}))
+
+	m.Add("between", dateMatch(m, func(tm time.Time, c parser.Collector, now time.Time) (bool, error) {
+		from, err := parser.DateValue(c.Get("min"))
+
+		if err != nil {
+			return false, err
+		}
+
+		to, err := parser.DateValue(c.Get("max"))
+
+		if err != nil {
+			return false, err
+		}
+
+		return inPeriod(tm, from.Resolve(now), to.Resolve(now)), nil
+	}))
+
+	m.Add("gt", numericMatch(func(n int) bool { return n > 0 }))
+	m.Add("gte", numericMatch(func(n int) bool { return n >= 0 }))
+	m.Add("lt", numericMatch(func(n int) bool { return n < 0 }))
+	m.Add("lte", numericMatch(func(n int) bool { return n <= 0 }))
+
+	m.Add("range", func(val interface{}, c parser.Collector) (bool, error) {
+		if !c.Has("max") || !c.Has("min") {
+			return false, parser.ErrInvalidCollector
+		}
+
+		if _, ok := ToFloat(val); !ok {
+			return false, nil
+		}
+
+		return Compare(val, c.Get("min")) >= 0 && Compare(val, c.Get("max")) <= 0, nil
+	})
+
+	m.Add("in", func(val interface{}, c parser.Collector) (bool, error) {
+		items, ok := InValues(c)
+
+		if !ok {
+			return false, parser.ErrInvalidCollector
+		}
+
+		for _, item := range items {
+			if Equals(val, item) {
+				return true, nil
+			}
+		}
+
+		return false, nil
+	})
+
+	m.Add("like", textMatch(func(val, pattern string) (bool, error) {
+		return matchPattern(val, LikePattern(pattern))
+	}))
+
+	m.Add("ilike", textMatch(func(val, pattern string) (bool, error) {
+		return matchPattern(val, "(?i)"+LikePattern(pattern))
+	}))
+
+	m.Add("startswith", textMatch(func(val, pattern string) (bool, error) {
+		return strings.HasPrefix(val, pattern), nil
+	}))
+
+	m.Add("endswith", textMatch(func(val, pattern string) (bool, error) {
+		return strings.HasSuffix(val, pattern), nil
+	}))
+
+	m.Add("contains", textMatch(func(val, pattern string) (bool, error) {
+		return strings.Contains(val, pattern), nil
+	}))
+
+	m.Add("match", textMatch(matchPattern))
+
+	//search matches values holding every word of the query in any order, ignoring case and punctuation
+	m.Add("search", textMatch(func(val, query string) (bool, error) {
+		words := make(map[string]bool)
+
+		for _, word := range SearchTerms(val) {
+			words[word] = true
+		}
+
+		terms := SearchTerms(query)
+
+		for _, term := range terms {
+			if !words[term] {
+				return false, nil
+			}
+		}
+
+		return len(terms) > 0, nil
+	}))
+}
+
+func init() {
+	AddDefaultMatchers(DefaultMatchers)
+}
@@ -0,0 +1,496 @@
+package adaptors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/influx6/data/query/parser"
+	"github.com/influx6/ds"
+	"github.com/influx6/flux"
+)
+
+//CollectionNotFoundMessage provides error for collections missing from a Source
+const CollectionNotFoundMessage = "Collection '%s' Not Found!"
+
+// ErrStopEach can be returned from a Source iterator function to end the iteration early without an error
+var ErrStopEach = errors.New("Stop iteration")
+
+// Record defines a single document or row used by the in-memory adaptors
+type Record map[string]interface{}
+
+// Source defines a provider of named collections of records for in-memory evaluation, streaming each record of the named collection into the function
+type Source interface {
+	Each(name string, fx func(Record) error) error
+}
+
+// Seeker is an optional Source extension that can satisfy a condition on a field through an index or ordered keys instead of a full scan, returning false when it cannot, it is also used to fetch the joined records of 'with' blocks
+type Seeker interface {
+	Seek(name, field string, c parser.Collector, fx func(Record) error) (bool, error)
+}
+
+// Evaluator executes parsed query graphs against a Source in memory, producing the same tree as the sql JSONBuilder
+type Evaluator struct {
+	source  Source
+	match   *MatchFactory
+	scalars *ScalarFactory
+	indexes map[string]map[string][]Record
+	ctx     context.Context
+}
+
+// NewEvaluator returns a new Evaluator for the source using the matchers, DefaultMatchers is used when the factory is nil
+func NewEvaluator(src Source, mf *MatchFactory) *Evaluator {
+	if mf == nil {
+		mf = DefaultMatchers
+	}
+
+	return &Evaluator{
+		source:  src,
+		match:   mf,
+		scalars: DefaultScalars,
+	}
+}
+
+// queryTree defines the records of a graph with the list of children of each record
+type queryTree struct {
+	root     *parser.ParseNode
+	children map[string][]*parser.ParseNode
+}
+
+// buildTree collects the records of the graph in depth-first order grouped by their parents
+func buildTree(gs ds.Graphs) (*queryTree, error) {
+	mo, err := DFGraph(gs)
+
+	if err != nil {
+		return nil, err
+	}
+
+	tree := &queryTree{children: make(map[string][]*parser.ParseNode)}
+
+	for mo.Next() == nil {
+		node := mo.Node().(*parser.ParseNode)
+
+		if tree.root == nil {
+			tree.root = node
+			continue
+		}
+
+		tree.children[node.PKey] = append(tree.children[node.PKey], node)
+	}
+
+	if tree.root == nil {
+		return nil, ErrGraphType
+	}
+
+	return tree, nil
+}
+
+// Evaluate runs the graph against the source returning a map of the root record name to its list of records
+func (e *Evaluator) Evaluate(gs ds.Graphs) (map[string]interface{}, error) {
+	return e.EvaluateContext(context.Background(), gs)
+}
+
+// EvaluateContext runs the graph against the source like Evaluate, ending early with the context's error once it is done
+func (e *Evaluator) EvaluateContext(ctx context.Context, gs ds.Graphs) (map[string]interface{}, error) {
+	tree, err := buildTree(gs)
+
+	if err != nil {
+		return nil, err
+	}
+
+	e.ctx = ctx
+	e.indexes = make(map[string]map[string][]Record)
+
+	records := []Record{}
+
+	err = e.scan(tree.root, func(rec Record) error {
+		ok, err := e.Matches(tree.root, rec)
+
+		if err == nil && ok {
+			ok, err = e.exists(tree, tree.root, rec)
+		}
+
+		if err != nil || !ok {
+			return err
+		}
+
+		shaped, err := e.shape(tree, tree.root, rec)
+
+		if err != nil {
+			return err
+		}
+
+		records = append(records, shaped)
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{tree.root.Name(): records}, nil
+}
+
+// guard wraps the iterator function to stop once the evaluation's context is done
+func (e *Evaluator) guard(fx func(Record) error) func(Record) error {
+	return func(rec Record) error {
+		if e.ctx != nil {
+			if err := e.ctx.Err(); err != nil {
+				return err
+			}
+		}
+		return fx(rec)
+	}
+}
+
+// scan iterates the collection of the node, letting a Seeker source satisfy one of its rules or field conditions first
+func (e *Evaluator) scan(node *parser.ParseNode, fx func(Record) error) error {
+	fx = e.guard(fx)
+
+	if seek, ok := e.source.(Seeker); ok {
+		var done bool
+		var err error
+
+		try := func(field string, c parser.Collector, stop func()) {
+			if IsControlKey(field) {
+				return
+			}
+
+			if done, err = seek.Seek(node.Name(), field, c, fx); done || err != nil {
+				stop()
+			}
+		}
+
+		node.Rules.EachCondition(try)
+
+		if !done && err == nil {
+			node.Records.EachCondition(try)
+		}
+
+		if done || err != nil {
+			return stopped(err)
+		}
+	}
+
+	return stopped(e.source.Each(node.Name(), fx))
+}
+
+// stopped clears ErrStopEach returned by an iterator
+func stopped(err error) error {
+	if err == ErrStopEach {
+		return nil
+	}
+	return err
+}
+
+// Matches returns true if the record satisfies the rules and field conditions of the node
+func (e *Evaluator) Matches(node *parser.ParseNode, rec Record) (bool, error) {
+	ok := true
+	var err error
+
+	test := func(field string, c parser.Collector, stop func()) {
+		if IsControlKey(field) {
+			return
+		}
+
+		if ok, err = e.match.Match(rec[field], c); err != nil || !ok {
+			stop()
+		}
+	}
+
+	node.Rules.EachCondition(test)
+
+	if ok && err == nil {
+		node.Records.EachCondition(test)
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	return ok, nil
+}
+
+// shape returns the selected and computed fields of the record with its child records nested under their names, a record without any
+// selected or computed fields keeps all of its fields
+func (e *Evaluator) shape(tree *queryTree, node *parser.ParseNode, rec Record) (Record, error) {
+	shaped := make(Record)
+	keys := node.Records.Keys()
+
+	if len(keys) == 0 && len(node.Computed) == 0 {
+		for key, val := range rec {
+			shaped[key] = val
+		}
+	}
+
+	for _, key := range keys {
+		shaped[key] = rec[key]
+	}
+
+	for _, cf := range node.Computed {
+		val, err := e.scalars.Compute(cf.Expr, rec)
+
+		if err != nil {
+			return nil, node.Report(cf.Name, err.Error())
+		}
+
+		shaped[cf.Name] = val
+	}
+
+	for _, child := range tree.children[node.Key] {
+		//existence rules only filter the record
+		if _, ok := Existence(child); ok {
+			continue
+		}
+
+		val, err := e.nest(tree, child, rec)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if val == nil {
+			delete(shaped, child.Name())
+			continue
+		}
+
+		shaped[child.Name()] = val
+	}
+
+	return shaped, nil
+}
+
+// Relation returns the [childkey parentkey] pair of the node's 'with' rule if it has one
+func Relation(node *parser.ParseNode) ([]string, bool) {
+	co, err := node.Rules.Get("with")
+
+	if err != nil || len(co) == 0 {
+		return nil, false
+	}
+
+	keys, ok := co[0].Get("value").([]string)
+
+	if !ok || len(keys) != 2 {
+		return nil, false
+	}
+
+	return keys, true
+}
+
+// Existence returns whether the node's 'exists' or 'notexists' rule requires its parent to have any of its records, ok is false when it has neither
+func Existence(node *parser.ParseNode) (want bool, ok bool) {
+	for _, key := range []string{"exists", "notexists"} {
+		co, err := node.Rules.Get(key)
+
+		if err != nil || len(co) == 0 {
+			continue
+		}
+
+		val, _ := co[0].Get("value").(bool)
+		return val == (key == "exists"), true
+	}
+
+	return false, false
+}
+
+// exists returns true if the record satisfies the existence rules of the node's children
+func (e *Evaluator) exists(tree *queryTree, node *parser.ParseNode, rec Record) (bool, error) {
+	for _, child := range tree.children[node.Key] {
+		want, ok := Existence(child)
+
+		if !ok {
+			continue
+		}
+
+		val, err := e.nest(tree, child, rec)
+
+		if err != nil {
+			return false, err
+		}
+
+		found := val != nil
+
+		if recs, ok := val.([]Record); ok {
+			found = len(recs) > 0
+		}
+
+		if found != want {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// nest returns the child records of the parent record, either joined from the child's own collection through its 'with' rule or taken from the embedded object or list of the same name
+func (e *Evaluator) nest(tree *queryTree, child *parser.ParseNode, rec Record) (interface{}, error) {
+	if keys, ok := Relation(child); ok {
+		candidates, err := e.related(child.Name(), keys[0], rec[keys[1]])
+
+		if err != nil {
+			return nil, err
+		}
+
+		return e.filter(tree, child, candidates)
+	}
+
+	switch mo := rec[child.Name()].(type) {
+	case map[string]interface{}:
+		return e.single(tree, child, Record(mo))
+	case Record:
+		return e.single(tree, child, mo)
+	case []interface{}:
+		var items []Record
+
+		for _, item := range mo {
+			switch im := item.(type) {
+			case map[string]interface{}:
+				items = append(items, Record(im))
+			case Record:
+				items = append(items, im)
+			}
+		}
+
+		return e.filter(tree, child, items)
+	case []Record:
+		return e.filter(tree, child, mo)
+	case []map[string]interface{}:
+		var items []Record
+
+		for _, item := range mo {
+			items = append(items, Record(item))
+		}
+
+		return e.filter(tree, child, items)
+	}
+
+	return nil, nil
+}
+
+// single returns the shaped embedded object if it matches the child's conditions
+func (e *Evaluator) single(tree *queryTree, child *parser.ParseNode, rec Record) (interface{}, error) {
+	ok, err := e.Matches(child, rec)
+
+	if err == nil && ok {
+		ok, err = e.exists(tree, child, rec)
+	}
+
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	return e.shape(tree, child, rec)
+}
+
+// filter returns the shaped records of the list that match the child's conditions
+func (e *Evaluator) filter(tree *queryTree, child *parser.ParseNode, recs []Record) ([]Record, error) {
+	res := []Record{}
+
+	for _, rec := range recs {
+		ok, err := e.Matches(child, rec)
+
+		if err == nil && ok {
+			ok, err = e.exists(tree, child, rec)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		if !ok {
+			continue
+		}
+
+		shaped, err := e.shape(tree, child, rec)
+
+		if err != nil {
+			return nil, err
+		}
+
+		res = append(res, shaped)
+	}
+
+	return res, nil
+}
+
+// related returns the records of the collection whose field equals the value, seeking them when the source is a Seeker and using the evaluation's index otherwise
+func (e *Evaluator) related(name, field string, val interface{}) ([]Record, error) {
+	//a null key never joins, as NULL never equals NULL in sql
+	if Unwrap(val) == nil {
+		return nil, nil
+	}
+
+	if seek, ok := e.source.(Seeker); ok {
+		var recs []Record
+
+		done, err := seek.Seek(name, field, parser.Collector{"type": "is", "value": val}, e.guard(func(rec Record) error {
+			recs = append(recs, rec)
+			return nil
+		}))
+
+		if err = stopped(err); err != nil {
+			return nil, err
+		}
+
+		if done {
+			return recs, nil
+		}
+	}
+
+	ind, err := e.index(name, field)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return ind[KeyOf(val)], nil
+}
+
+// index returns the records of the collection grouped by their value of the field, building it once per evaluation
+func (e *Evaluator) index(name, field string) (map[string][]Record, error) {
+	id := fmt.Sprintf("%s.%s", name, field)
+
+	if ind, ok := e.indexes[id]; ok {
+		return ind, nil
+	}
+
+	ind := make(map[string][]Record)
+
+	err := e.source.Each(name, e.guard(func(rec Record) error {
+		if Unwrap(rec[field]) == nil {
+			return nil
+		}
+
+		key := KeyOf(rec[field])
+		ind[key] = append(ind[key], rec)
+		return nil
+	}))
+
+	if err = stopped(err); err != nil {
+		return nil, err
+	}
+
+	e.indexes[id] = ind
+	return ind, nil
+}
+
+// EvaluateAdaptor returns a reactor that executes each ds.Graphs against the source in memory, replying with the result tree
+func EvaluateAdaptor(src Source, mf *MatchFactory) flux.Reactor {
+	return QueryAdaptor(func(r flux.Reactor, gs ds.Graphs) {
+		res, err := NewEvaluator(src, mf).Evaluate(gs)
+
+		if err != nil {
+			r.ReplyError(err)
+			return
+		}
+
+		r.Reply(res)
+	})
+}
+
+// BuildSourceQuero generates a full query parser that executes its queries against the source in memory
+func BuildSourceQuero(src Source, mf *MatchFactory, ds *parser.InspectionFactory) flux.Reactor {
+	co := ChunkParser(ds)
+	co.Bind(EvaluateAdaptor(src, mf), true)
+	return co
+}
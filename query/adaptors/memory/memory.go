@@ -0,0 +1,208 @@
+package memory
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influx6/data/query/adaptors"
+	"github.com/influx6/data/query/parser"
+	"github.com/influx6/flux"
+)
+
+// ErrNotCollection is returned when a registered value is not a slice of structs or maps
+var ErrNotCollection = errors.New("Value is not a slice of structs or maps")
+
+// ErrNotCollectionMap is returned when a registered value is not a map of string to slices
+var ErrNotCollectionMap = errors.New("Value is not a map[string] of slices")
+
+// Store provides an adaptors.Source over named Go collections eg []User or map[string][]User, where struct fields are named by the store's tag or their lowercased field name
+type Store struct {
+	tag         string
+	collections map[string]reflect.Value
+	rw          sync.RWMutex
+}
+
+// NewStore returns a new Store using the struct tag eg 'json' to name fields, fields tagged '-' are skipped
+func NewStore(tag string) *Store {
+	return &Store{
+		tag:         tag,
+		collections: make(map[string]reflect.Value),
+	}
+}
+
+// Register adds a slice of structs,pointers to structs or maps as the named collection
+func (s *Store) Register(name string, items interface{}) error {
+	mo := reflect.ValueOf(items)
+
+	if mo.Kind() == reflect.Ptr {
+		mo = mo.Elem()
+	}
+
+	if mo.Kind() != reflect.Slice && mo.Kind() != reflect.Array {
+		return ErrNotCollection
+	}
+
+	s.rw.Lock()
+	s.collections[name] = mo
+	s.rw.Unlock()
+
+	return nil
+}
+
+// RegisterMap adds each slice within a map[string][]T as a collection named by its key
+func (s *Store) RegisterMap(items interface{}) error {
+	mo := reflect.ValueOf(items)
+
+	if mo.Kind() != reflect.Map || mo.Type().Key().Kind() != reflect.String {
+		return ErrNotCollectionMap
+	}
+
+	for _, key := range mo.MapKeys() {
+		if err := s.Register(key.String(), mo.MapIndex(key).Interface()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Remove removes the named collection
+func (s *Store) Remove(name string) {
+	s.rw.Lock()
+	delete(s.collections, name)
+	s.rw.Unlock()
+}
+
+// Each iterates the records of the named collection, converting each item as it goes
+func (s *Store) Each(name string, fx func(adaptors.Record) error) error {
+	s.rw.RLock()
+	col, ok := s.collections[name]
+	s.rw.RUnlock()
+
+	if !ok {
+		return fmt.Errorf(adaptors.CollectionNotFoundMessage, name)
+	}
+
+	for i := 0; i < col.Len(); i++ {
+		rec, ok := s.convert(col.Index(i)).(adaptors.Record)
+
+		if !ok {
+			continue
+		}
+
+		if err := fx(rec); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// timeType is skipped when converting structs since it is a value rather than a record
+var timeType = reflect.TypeOf(time.Time{})
+
+// convert turns structs and maps into adaptors.Record and slices of them into []adaptors.Record,leaving other values as they are
+func (s *Store) convert(mo reflect.Value) interface{} {
+	for mo.Kind() == reflect.Ptr || mo.Kind() == reflect.Interface {
+		if mo.IsNil() {
+			return nil
+		}
+		mo = mo.Elem()
+	}
+
+	switch mo.Kind() {
+	case reflect.Struct:
+		if mo.Type() == timeType {
+			return mo.Interface()
+		}
+
+		rec := make(adaptors.Record)
+		mot := mo.Type()
+
+		for i := 0; i < mot.NumField(); i++ {
+			fl := mot.Field(i)
+
+			//unexported fields cant be collected,so we skip them
+			if fl.PkgPath != "" {
+				continue
+			}
+
+			name := s.fieldName(fl)
+
+			if name == "" {
+				continue
+			}
+
+			rec[name] = s.convert(mo.Field(i))
+		}
+
+		return rec
+	case reflect.Map:
+		if mo.Type().Key().Kind() != reflect.String {
+			return mo.Interface()
+		}
+
+		rec := make(adaptors.Record)
+
+		for _, key := range mo.MapKeys() {
+			rec[key.String()] = s.convert(mo.MapIndex(key))
+		}
+
+		return rec
+	case reflect.Slice, reflect.Array:
+		if mo.Kind() == reflect.Slice && mo.Type().Elem().Kind() == reflect.Uint8 {
+			return mo.Interface()
+		}
+
+		var items []adaptors.Record
+
+		for i := 0; i < mo.Len(); i++ {
+			rec, ok := s.convert(mo.Index(i)).(adaptors.Record)
+
+			//lists of plain values are kept as they are
+			if !ok {
+				return mo.Interface()
+			}
+
+			items = append(items, rec)
+		}
+
+		return items
+	case reflect.Invalid:
+		return nil
+	}
+
+	return mo.Interface()
+}
+
+// fieldName returns the record name of a struct field or an empty string if it should be skipped
+func (s *Store) fieldName(fl reflect.StructField) string {
+	if s.tag == "" {
+		return strings.ToLower(fl.Name)
+	}
+
+	tag := strings.Split(fl.Tag.Get(s.tag), ",")[0]
+
+	switch tag {
+	case "-":
+		return ""
+	case "":
+		return strings.ToLower(fl.Name)
+	}
+
+	return tag
+}
+
+// BuildQuero generates a full query parser that executes its queries against the store
+func BuildQuero(st *Store, mf *adaptors.MatchFactory, ds *parser.InspectionFactory) flux.Reactor {
+	return adaptors.BuildSourceQuero(st, mf, ds)
+}
+
+// Quero returns a new instance of a complete query handler over the store using the default inspections and matchers
+func Quero(st *Store) flux.Reactor {
+	return BuildQuero(st, adaptors.DefaultMatchers, parser.DefaultInspectionFactory)
+}
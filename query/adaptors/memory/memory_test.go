@@ -0,0 +1,267 @@
+package memory
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/influx6/data/query/adaptors"
+	"github.com/influx6/flux"
+)
+
+type photo struct {
+	ID     int    `dq:"id"`
+	UserID int    `dq:"user_id"`
+	URL    string `dq:"url"`
+}
+
+type skill struct {
+	Name  string `dq:"name"`
+	Level int    `dq:"level"`
+}
+
+type user struct {
+	ID       int     `dq:"id"`
+	Name     string  `dq:"name"`
+	Age      int     `dq:"age"`
+	Skills   []skill `dq:"skills"`
+	Password string  `dq:"-"`
+}
+
+func TestStoreQuery(t *testing.T) {
+	st := NewStore("dq")
+
+	err := st.RegisterMap(map[string]interface{}{
+		"users": []user{
+			{ID: 1, Name: "alex", Age: 21, Skills: []skill{{"go", 8}, {"sql", 5}}},
+			{ID: 2, Name: "josh", Age: 32, Skills: []skill{{"js", 7}}},
+		},
+		"photos": []*photo{
+			{ID: 1, UserID: 2, URL: "./images/sock.jpg"},
+			{ID: 2, UserID: 1, URL: "./images/winnie.jpg"},
+		},
+	})
+
+	if err != nil {
+		flux.FatalFailed(t, "Failed to register collections: %+s", err)
+	}
+
+	st.Each("users", func(rec adaptors.Record) error {
+		if _, ok := rec["password"]; ok {
+			flux.FatalFailed(t, "Expected '-' tagged field to be skipped: %+v", rec)
+		}
+		return nil
+	})
+
+	var ws sync.WaitGroup
+	ws.Add(1)
+
+	qo := Quero(st)
+
+	qo.React(func(r flux.Reactor, err error, d interface{}) {
+		defer ws.Done()
+
+		if err != nil {
+			flux.FatalFailed(t, "Failed to query store: %+s", err)
+		}
+
+		users := d.(map[string]interface{})["users"].([]adaptors.Record)
+
+		if len(users) != 1 || users[0]["name"] != "josh" {
+			flux.FatalFailed(t, "Expected only 'josh' to match age > 30: %+v", users)
+		}
+
+		if photos := users[0]["photos"].([]adaptors.Record); len(photos) != 1 || photos[0]["url"] != "./images/sock.jpg" {
+			flux.FatalFailed(t, "Expected josh's photo to be joined: %+v", photos)
+		}
+
+		if skills := users[0]["skills"].([]adaptors.Record); len(skills) != 1 {
+			flux.FatalFailed(t, "Expected josh's skills from the struct field: %+v", skills)
+		}
+
+		flux.LogPassed(t, "Queried Go collections with struct fields and joins")
+	}, true)
+
+	qo.Send(`users(){ name, age(gt: 30), skills{ name }, photos(with: [user_id id]){ url } }`)
+
+	ws.Wait()
+	qo.Close()
+}
+
+func TestStorePatterns(t *testing.T) {
+	st := NewStore("dq")
+
+	err := st.RegisterMap(map[string]interface{}{
+		"users": []user{
+			{ID: 1, Name: "Alexander", Age: 21},
+			{ID: 2, Name: "josh", Age: 32},
+			{ID: 3, Name: "alex_b", Age: 45},
+		},
+	})
+
+	if err != nil {
+		flux.FatalFailed(t, "Failed to register collections: %+s", err)
+	}
+
+	cases := map[string][]string{
+		`users(){ name(like: 'alex%') }`:      {"alex_b"},
+		`users(){ name(ilike: 'ALEX%') }`:     {"Alexander", "alex_b"},
+		`users(){ name(contains: '_') }`:      {"alex_b"},
+		`users(){ name(endswith: 'sh') }`:     {"josh"},
+		`users(){ name(match: '^[a-z]+$') }`:  {"josh"},
+		`users(){ name(search: 'B alex') }`:   {"alex_b"},
+		`users(){ name(startswith: 'alex') }`: {"alex_b"},
+	}
+
+	for query, names := range cases {
+		var ws sync.WaitGroup
+		ws.Add(1)
+
+		qo := Quero(st)
+
+		qo.React(func(r flux.Reactor, err error, d interface{}) {
+			defer ws.Done()
+
+			if err != nil {
+				flux.FatalFailed(t, "Failed to query store with %q: %+s", query, err)
+			}
+
+			users := d.(map[string]interface{})["users"].([]adaptors.Record)
+
+			if len(users) != len(names) {
+				flux.FatalFailed(t, "Expected %+v to match %q but got %+v", names, query, users)
+			}
+
+			for index, name := range names {
+				if users[index]["name"] != name {
+					flux.FatalFailed(t, "Expected %+v to match %q but got %+v", names, query, users)
+				}
+			}
+		}, true)
+
+		qo.Send(query)
+
+		ws.Wait()
+		qo.Close()
+	}
+
+	flux.LogPassed(t, "Matched records with pattern conditions in memory")
+}
+
+func TestStoreExists(t *testing.T) {
+	st := NewStore("dq")
+
+	err := st.RegisterMap(map[string]interface{}{
+		"users": []map[string]interface{}{
+			{"id": 1, "name": "alex", "email": "alex@dq.io"},
+			{"id": 2, "name": "josh", "email": nil},
+			{"id": 3, "name": "kate"},
+		},
+		"photos": []*photo{
+			{ID: 1, UserID: 2, URL: "./images/sock.jpg"},
+			{ID: 2, UserID: 1, URL: "./images/winnie.jpg"},
+		},
+	})
+
+	if err != nil {
+		flux.FatalFailed(t, "Failed to register collections: %+s", err)
+	}
+
+	cases := map[string][]string{
+		`users(){ name, photos(exists: true, with: [user_id id]) }`:                        {"alex", "josh"},
+		`users(){ name, photos(notexists: true, with: [user_id id]) }`:                     {"kate"},
+		`users(){ name, photos(exists: true, with: [user_id id]){ url(contains: sock) } }`: {"josh"},
+		`users(){ name, email(isnull: true) }`:                                             {"josh", "kate"},
+		`users(){ name, email(notnull: true) }`:                                            {"alex"},
+		`users(email: null){ name }`:                                                       {"josh", "kate"},
+	}
+
+	for query, names := range cases {
+		var ws sync.WaitGroup
+		ws.Add(1)
+
+		qo := Quero(st)
+
+		qo.React(func(r flux.Reactor, err error, d interface{}) {
+			defer ws.Done()
+
+			if err != nil {
+				flux.FatalFailed(t, "Failed to query store with %q: %+s", query, err)
+			}
+
+			users := d.(map[string]interface{})["users"].([]adaptors.Record)
+
+			if len(users) != len(names) {
+				flux.FatalFailed(t, "Expected %+v to match %q but got %+v", names, query, users)
+			}
+
+			for index, name := range names {
+				if users[index]["name"] != name {
+					flux.FatalFailed(t, "Expected %+v to match %q but got %+v", names, query, users)
+				}
+
+				if _, ok := users[index]["photos"]; ok {
+					flux.FatalFailed(t, "Expected existence rules to only filter records: %+v", users[index])
+				}
+			}
+		}, true)
+
+		qo.Send(query)
+
+		ws.Wait()
+		qo.Close()
+	}
+
+	flux.LogPassed(t, "Filtered records with null checks and existence rules in memory")
+}
+
+func TestStoreComputed(t *testing.T) {
+	st := NewStore("dq")
+
+	err := st.RegisterMap(map[string]interface{}{
+		"users": []user{
+			{ID: 1, Name: "alex", Age: 21},
+		},
+	})
+
+	if err != nil {
+		flux.FatalFailed(t, "Failed to register collections: %+s", err)
+	}
+
+	var ws sync.WaitGroup
+	ws.Add(1)
+
+	qo := Quero(st)
+
+	qo.React(func(r flux.Reactor, err error, d interface{}) {
+		defer ws.Done()
+
+		if err != nil {
+			flux.FatalFailed(t, "Failed to query store: %+s", err)
+		}
+
+		users := d.(map[string]interface{})["users"].([]adaptors.Record)
+
+		if len(users) != 1 {
+			flux.FatalFailed(t, "Expected a single user but got %+v", users)
+		}
+
+		expected := adaptors.Record{"name": "alex", "shout": "ALEX!", "ageInMonths": float64(252), "nick": "alex", "half": 10.5}
+
+		if len(users[0]) != len(expected) {
+			flux.FatalFailed(t, "Expected %+v but got %+v", expected, users[0])
+		}
+
+		for key, val := range expected {
+			if users[0][key] != val {
+				flux.FatalFailed(t, "Expected %s to be %+v but got %+v", key, val, users[0][key])
+			}
+		}
+	}, true)
+
+	qo.Send(`users(){ name, shout: upper(concat(name, missing, "!")), ageInMonths: age * 12, nick: coalesce(missing, name), half: round(age / 2, 1) }`)
+
+	ws.Wait()
+	qo.Close()
+
+	flux.LogPassed(t, "Computed fields over records in memory")
+}
@@ -0,0 +1,1071 @@
+package mongo
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influx6/data/query/adaptors"
+)
+
+//UnknownStageMessage provides error for pipeline stages the Engine does not support
+const UnknownStageMessage = "Engine: Unsupported pipeline stage '%s'"
+
+//UnknownOperatorMessage provides error for operators the Engine does not support
+const UnknownOperatorMessage = "Engine: Unsupported operator '%s'"
+
+//InvalidArgumentMessage provides error for operators given the wrong arguments
+const InvalidArgumentMessage = "Engine: Invalid arguments for '%s'"
+
+// remove is the value of the $$REMOVE variable, dropping a field when assigned
+var remove = &struct{}{}
+
+// Engine provides an embedded in-memory document store that runs the subset of the aggregation pipeline generated by the Compiler, so queries can be executed without a mongo server
+type Engine struct {
+	collections map[string][]M
+	rw          sync.RWMutex
+}
+
+// NewEngine returns a new Engine instance
+func NewEngine() *Engine {
+	return &Engine{collections: make(map[string][]M)}
+}
+
+// Insert adds the documents to the named collection
+func (e *Engine) Insert(name string, docs ...M) {
+	e.rw.Lock()
+	e.collections[name] = append(e.collections[name], docs...)
+	e.rw.Unlock()
+}
+
+// Load decodes a json object of collection names to their lists of documents into the engine
+func (e *Engine) Load(r io.Reader) error {
+	var data map[string][]M
+
+	if err := json.NewDecoder(r).Decode(&data); err != nil {
+		return err
+	}
+
+	for name, docs := range data {
+		e.Insert(name, docs...)
+	}
+
+	return nil
+}
+
+// Aggregate runs the pipeline stages against the documents of the collection
+func (e *Engine) Aggregate(collection string, stages []M) ([]M, error) {
+	list, _ := toList(stages)
+	return e.run(collection, list, nil)
+}
+
+// run runs the pipeline stages against the collection using the variables of an enclosing $lookup
+func (e *Engine) run(collection string, stages []interface{}, vars M) ([]M, error) {
+	e.rw.RLock()
+	docs := append([]M{}, e.collections[collection]...)
+	e.rw.RUnlock()
+
+	var err error
+
+	for _, stage := range stages {
+		st, ok := toDoc(stage)
+
+		if !ok || len(st) != 1 {
+			return nil, fmt.Errorf(InvalidArgumentMessage, "stage")
+		}
+
+		for name, arg := range st {
+			if docs, err = e.stage(name, arg, docs, vars); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return docs, nil
+}
+
+// stage runs a single pipeline stage against the documents
+func (e *Engine) stage(name string, arg interface{}, docs []M, vars M) ([]M, error) {
+	spec, ok := toDoc(arg)
+
+	if !ok {
+		return nil, fmt.Errorf(InvalidArgumentMessage, name)
+	}
+
+	var res []M
+
+	for _, doc := range docs {
+		switch name {
+		case "$match":
+			ok, err := matchDoc(doc, spec, vars)
+
+			if err != nil {
+				return nil, err
+			}
+
+			if ok {
+				res = append(res, doc)
+			}
+		case "$lookup":
+			out, err := e.lookup(doc, spec, vars)
+
+			if err != nil {
+				return nil, err
+			}
+
+			res = append(res, out)
+		case "$project":
+			out, err := project(doc, spec, vars)
+
+			if err != nil {
+				return nil, err
+			}
+
+			res = append(res, out)
+		case "$addFields":
+			out := clone(doc)
+
+			for key, ex := range spec {
+				val, err := evalExpr(ex, doc, vars)
+
+				if err != nil {
+					return nil, err
+				}
+
+				if val == remove {
+					delete(out, key)
+					continue
+				}
+
+				out[key] = val
+			}
+
+			res = append(res, out)
+		default:
+			return nil, fmt.Errorf(UnknownStageMessage, name)
+		}
+	}
+
+	return res, nil
+}
+
+// lookup joins the documents of another collection into the document, supporting both the let/pipeline and localField/foreignField forms
+func (e *Engine) lookup(doc M, spec M, vars M) (M, error) {
+	from, _ := spec["from"].(string)
+	as, _ := spec["as"].(string)
+
+	if from == "" || as == "" {
+		return nil, fmt.Errorf(InvalidArgumentMessage, "$lookup")
+	}
+
+	stages, _ := toList(spec["pipeline"])
+
+	if local, ok := spec["localField"].(string); ok {
+		foreign, _ := spec["foreignField"].(string)
+		stages = append([]interface{}{M{"$match": M{foreign: M{"$eq": lookupPath(doc, local)}}}}, stages...)
+	}
+
+	inner := make(M)
+
+	for key, val := range vars {
+		inner[key] = val
+	}
+
+	if let, ok := toDoc(spec["let"]); ok {
+		for key, ex := range let {
+			val, err := evalExpr(ex, doc, vars)
+
+			if err != nil {
+				return nil, err
+			}
+
+			inner[key] = val
+		}
+	}
+
+	joined, err := e.run(from, stages, inner)
+
+	if err != nil {
+		return nil, err
+	}
+
+	items := []interface{}{}
+
+	for _, item := range joined {
+		items = append(items, item)
+	}
+
+	out := clone(doc)
+	out[as] = items
+	return out, nil
+}
+
+// matchDoc returns true if the document satisfies the query filter
+func matchDoc(doc M, filter M, vars M) (bool, error) {
+	for key, cond := range filter {
+		switch key {
+		case "$and", "$or":
+			list, ok := toList(cond)
+
+			if !ok {
+				return false, fmt.Errorf(InvalidArgumentMessage, key)
+			}
+
+			matched := false
+
+			for _, item := range list {
+				sub, ok := toDoc(item)
+
+				if !ok {
+					return false, fmt.Errorf(InvalidArgumentMessage, key)
+				}
+
+				ok, err := matchDoc(doc, sub, vars)
+
+				if err != nil {
+					return false, err
+				}
+
+				if key == "$and" && !ok {
+					return false, nil
+				}
+
+				matched = matched || ok
+			}
+
+			if key == "$or" && !matched {
+				return false, nil
+			}
+		case "$expr":
+			val, err := evalExpr(cond, doc, vars)
+
+			if err != nil {
+				return false, err
+			}
+
+			if !truthy(val) {
+				return false, nil
+			}
+		default:
+			ok, err := matchField(lookupPath(doc, key), cond)
+
+			if err != nil || !ok {
+				return false, err
+			}
+		}
+	}
+
+	return true, nil
+}
+
+// matchField returns true if the value satisfies the operators of the condition or equals it, where a list value matches if any of its items does
+func matchField(val interface{}, cond interface{}) (bool, error) {
+	ops, ok := toDoc(cond)
+
+	if !ok || !isOperators(ops) {
+		ops = M{"$eq": cond}
+	}
+
+	for op, arg := range ops {
+		var ok bool
+		var err error
+
+		switch op {
+		case "$options":
+			continue
+		case "$regex":
+			arg = regexArg(arg, ops["$options"])
+		}
+
+		if list, isList := val.([]interface{}); isList && op != "$ne" && op != "$nin" {
+			for _, item := range list {
+				if ok, err = compareOp(op, item, arg); ok || err != nil {
+					break
+				}
+			}
+		} else if isList {
+			ok = true
+
+			for _, item := range list {
+				if ok, err = compareOp(op, item, arg); !ok || err != nil {
+					break
+				}
+			}
+		} else {
+			ok, err = compareOp(op, val, arg)
+		}
+
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+// isOperators returns true if the keys of the document are operators
+func isOperators(m M) bool {
+	for key := range m {
+		if !strings.HasPrefix(key, "$") {
+			return false
+		}
+	}
+	return len(m) > 0
+}
+
+// compareOp applies the comparison operator between the value and the argument
+func compareOp(op string, val, arg interface{}) (bool, error) {
+	switch op {
+	case "$eq":
+		return equal(val, arg), nil
+	case "$ne":
+		return !equal(val, arg), nil
+	case "$gt", "$gte", "$lt", "$lte":
+		n, ok := order(val, arg)
+
+		if !ok {
+			return false, nil
+		}
+
+		switch op {
+		case "$gt":
+			return n > 0, nil
+		case "$gte":
+			return n >= 0, nil
+		case "$lt":
+			return n < 0, nil
+		}
+
+		return n <= 0, nil
+	case "$in", "$nin":
+		list, ok := toList(arg)
+
+		if !ok {
+			return false, fmt.Errorf(InvalidArgumentMessage, op)
+		}
+
+		found := false
+
+		for _, item := range list {
+			if equal(val, item) {
+				found = true
+				break
+			}
+		}
+
+		return found == (op == "$in"), nil
+	case "$regex":
+		pattern, ok := arg.(string)
+
+		if !ok {
+			return false, fmt.Errorf(InvalidArgumentMessage, op)
+		}
+
+		str, ok := val.(string)
+
+		if !ok {
+			return false, nil
+		}
+
+		re, err := regexp.Compile(pattern)
+
+		if err != nil {
+			return false, fmt.Errorf(InvalidArgumentMessage, op)
+		}
+
+		return re.MatchString(str), nil
+	}
+
+	return false, fmt.Errorf(UnknownOperatorMessage, op)
+}
+
+// regexArg returns the $regex pattern with the flags of its $options, only the i, m and s options are supported
+func regexArg(arg, options interface{}) interface{} {
+	pattern, ok := arg.(string)
+	opts, _ := options.(string)
+
+	if !ok || opts == "" {
+		return arg
+	}
+
+	var flags string
+
+	for _, flag := range opts {
+		if strings.ContainsRune("ims", flag) {
+			flags += string(flag)
+		}
+	}
+
+	if flags == "" {
+		return pattern
+	}
+
+	return "(?" + flags + ")" + pattern
+}
+
+// equal returns true if both values are nil or compare as equal
+func equal(a, b interface{}) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return adaptors.Compare(a, b) == 0
+}
+
+// order compares two numbers,two strings or a time with a date string, returning false for values of different types
+func order(a, b interface{}) (int, bool) {
+	_, at := a.(time.Time)
+	_, bt := b.(time.Time)
+
+	if at || bt {
+		ta, aok := adaptors.ToTime(a)
+		tb, bok := adaptors.ToTime(b)
+
+		if !aok || !bok {
+			return 0, false
+		}
+
+		switch {
+		case ta.Before(tb):
+			return -1, true
+		case ta.After(tb):
+			return 1, true
+		}
+
+		return 0, true
+	}
+
+	_, an := adaptors.ToFloat(a)
+	_, bn := adaptors.ToFloat(b)
+	_, as := a.(string)
+	_, bs := b.(string)
+
+	if (an && bn) || (as && bs) {
+		return adaptors.Compare(a, b), true
+	}
+
+	return 0, false
+}
+
+// truthy returns the boolean value of an expression result
+func truthy(val interface{}) bool {
+	switch mo := val.(type) {
+	case nil:
+		return false
+	case bool:
+		return mo
+	}
+
+	if val == remove {
+		return false
+	}
+
+	if num, ok := adaptors.ToFloat(val); ok {
+		if _, isString := val.(string); !isString {
+			return num != 0
+		}
+	}
+
+	return true
+}
+
+// evalExpr evaluates an aggregation expression against the document and variables
+func evalExpr(ex interface{}, doc M, vars M) (interface{}, error) {
+	switch mo := ex.(type) {
+	case string:
+		if strings.HasPrefix(mo, "$$") {
+			path := strings.SplitN(mo[2:], ".", 2)
+
+			if path[0] == "REMOVE" {
+				return remove, nil
+			}
+
+			if path[0] == "ROOT" {
+				if len(path) == 1 {
+					return doc, nil
+				}
+				return lookupPath(doc, path[1]), nil
+			}
+
+			val, ok := vars[path[0]]
+
+			if !ok {
+				return nil, fmt.Errorf(InvalidArgumentMessage, mo)
+			}
+
+			if len(path) == 1 {
+				return val, nil
+			}
+
+			sub, _ := toDoc(val)
+			return lookupPath(sub, path[1]), nil
+		}
+
+		if strings.HasPrefix(mo, "$") {
+			return lookupPath(doc, mo[1:]), nil
+		}
+
+		return mo, nil
+	case []interface{}, []M:
+		list, _ := toList(mo)
+		var res []interface{}
+
+		for _, item := range list {
+			val, err := evalExpr(item, doc, vars)
+
+			if err != nil {
+				return nil, err
+			}
+
+			res = append(res, val)
+		}
+
+		return res, nil
+	case M, map[string]interface{}:
+		spec, _ := toDoc(mo)
+
+		if !isOperators(spec) {
+			res := make(M)
+
+			for key, item := range spec {
+				val, err := evalExpr(item, doc, vars)
+
+				if err != nil {
+					return nil, err
+				}
+
+				res[key] = val
+			}
+
+			return res, nil
+		}
+
+		if len(spec) != 1 {
+			return nil, fmt.Errorf(InvalidArgumentMessage, "expression")
+		}
+
+		for op, arg := range spec {
+			return evalOperator(op, arg, doc, vars)
+		}
+	}
+
+	return ex, nil
+}
+
+// evalOperator evaluates a single expression operator
+func evalOperator(op string, arg interface{}, doc M, vars M) (interface{}, error) {
+	switch op {
+	case "$filter":
+		spec, ok := toDoc(arg)
+
+		if !ok {
+			return nil, fmt.Errorf(InvalidArgumentMessage, op)
+		}
+
+		input, err := evalExpr(spec["input"], doc, vars)
+
+		if err != nil {
+			return nil, err
+		}
+
+		list, ok := toList(input)
+
+		if !ok {
+			return nil, nil
+		}
+
+		as, _ := spec["as"].(string)
+
+		if as == "" {
+			as = "this"
+		}
+
+		inner := make(M)
+
+		for key, val := range vars {
+			inner[key] = val
+		}
+
+		res := []interface{}{}
+
+		for _, item := range list {
+			inner[as] = item
+
+			val, err := evalExpr(spec["cond"], doc, inner)
+
+			if err != nil {
+				return nil, err
+			}
+
+			if truthy(val) {
+				res = append(res, item)
+			}
+		}
+
+		return res, nil
+	case "$cond":
+		var parts []interface{}
+
+		if spec, ok := toDoc(arg); ok {
+			parts = []interface{}{spec["if"], spec["then"], spec["else"]}
+		} else if list, ok := toList(arg); ok && len(list) == 3 {
+			parts = list
+		} else {
+			return nil, fmt.Errorf(InvalidArgumentMessage, op)
+		}
+
+		test, err := evalExpr(parts[0], doc, vars)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if truthy(test) {
+			return evalExpr(parts[1], doc, vars)
+		}
+
+		return evalExpr(parts[2], doc, vars)
+	case "$literal":
+		return arg, nil
+	case "$dateTrunc":
+		spec, ok := toDoc(arg)
+
+		if !ok {
+			return nil, fmt.Errorf(InvalidArgumentMessage, op)
+		}
+
+		date, err := evalExpr(spec["date"], doc, vars)
+
+		if err != nil {
+			return nil, err
+		}
+
+		unit, err := evalExpr(spec["unit"], doc, vars)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if date == nil || unit == nil {
+			return nil, nil
+		}
+
+		tm, ok := adaptors.ToTime(date)
+
+		if !ok {
+			return nil, fmt.Errorf(InvalidArgumentMessage, op)
+		}
+
+		return adaptors.TruncTime(tm, adaptors.ToString(unit))
+	}
+
+	list, ok := toList(arg)
+
+	if !ok {
+		list = []interface{}{arg}
+	}
+
+	var args []interface{}
+
+	for _, item := range list {
+		val, err := evalExpr(item, doc, vars)
+
+		if err != nil {
+			return nil, err
+		}
+
+		args = append(args, val)
+	}
+
+	switch op {
+	case "$isArray":
+		if len(args) != 1 {
+			return nil, fmt.Errorf(InvalidArgumentMessage, op)
+		}
+		_, ok := args[0].([]interface{})
+		return ok, nil
+	case "$not":
+		if len(args) != 1 {
+			return nil, fmt.Errorf(InvalidArgumentMessage, op)
+		}
+		return !truthy(args[0]), nil
+	case "$and":
+		for _, item := range args {
+			if !truthy(item) {
+				return false, nil
+			}
+		}
+		return true, nil
+	case "$or":
+		for _, item := range args {
+			if truthy(item) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case "$eq", "$ne", "$gt", "$gte", "$lt", "$lte", "$in", "$nin":
+		if len(args) != 2 {
+			return nil, fmt.Errorf(InvalidArgumentMessage, op)
+		}
+		return compareOp(op, args[0], args[1])
+	case "$size":
+		list, ok := toList(args[0])
+
+		if len(args) != 1 || !ok {
+			return nil, fmt.Errorf(InvalidArgumentMessage, op)
+		}
+		return len(list), nil
+	case "$ifNull":
+		for _, item := range args {
+			if item != nil && item != remove {
+				return item, nil
+			}
+		}
+		return nil, nil
+	case "$toLower", "$toUpper":
+		if len(args) != 1 {
+			return nil, fmt.Errorf(InvalidArgumentMessage, op)
+		}
+
+		//like mongo,null values become empty strings
+		if args[0] == nil || args[0] == remove {
+			return "", nil
+		}
+
+		if op == "$toLower" {
+			return strings.ToLower(adaptors.ToString(args[0])), nil
+		}
+		return strings.ToUpper(adaptors.ToString(args[0])), nil
+	case "$toString":
+		if len(args) != 1 {
+			return nil, fmt.Errorf(InvalidArgumentMessage, op)
+		}
+		return toString(args[0]), nil
+	case "$toDate":
+		if len(args) != 1 {
+			return nil, fmt.Errorf(InvalidArgumentMessage, op)
+		}
+
+		if args[0] == nil || args[0] == remove {
+			return nil, nil
+		}
+
+		tm, ok := adaptors.ToTime(args[0])
+
+		if !ok {
+			return nil, fmt.Errorf(InvalidArgumentMessage, op)
+		}
+		return tm, nil
+	case "$concat":
+		var text []string
+
+		for _, item := range args {
+			str, ok := item.(string)
+
+			if item == nil || item == remove {
+				return nil, nil
+			}
+
+			if !ok {
+				return nil, fmt.Errorf(InvalidArgumentMessage, op)
+			}
+
+			text = append(text, str)
+		}
+
+		return strings.Join(text, ""), nil
+	case "$add", "$subtract", "$multiply", "$divide":
+		return arithmeticOp(op, args)
+	case "$round":
+		if len(args) < 1 || len(args) > 2 {
+			return nil, fmt.Errorf(InvalidArgumentMessage, op)
+		}
+
+		var places float64
+
+		if len(args) > 1 {
+			places, _ = adaptors.ToFloat(args[1])
+		}
+
+		if args[0] == nil || args[0] == remove {
+			return nil, nil
+		}
+
+		num, ok := toNumber(args[0])
+
+		if !ok {
+			return nil, fmt.Errorf(InvalidArgumentMessage, op)
+		}
+
+		//mongo rounds halves to even
+		scale := math.Pow(10, math.Trunc(places))
+		return math.RoundToEven(num*scale) / scale, nil
+	}
+
+	return nil, fmt.Errorf(UnknownOperatorMessage, op)
+}
+
+// arithmeticOp applies the arithmetic operator to two numbers, like mongo a null argument gives null, any other value is invalid as
+// is division by zero
+func arithmeticOp(op string, args []interface{}) (interface{}, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf(InvalidArgumentMessage, op)
+	}
+
+	for _, item := range args {
+		if item == nil || item == remove {
+			return nil, nil
+		}
+	}
+
+	a, aok := toNumber(args[0])
+	b, bok := toNumber(args[1])
+
+	if !aok || !bok {
+		return nil, fmt.Errorf(InvalidArgumentMessage, op)
+	}
+
+	switch op {
+	case "$add":
+		return a + b, nil
+	case "$subtract":
+		return a - b, nil
+	case "$multiply":
+		return a * b, nil
+	}
+
+	if b == 0 {
+		return nil, fmt.Errorf(InvalidArgumentMessage, op)
+	}
+
+	return a / b, nil
+}
+
+// toNumber returns the value of a number, strings are not numbers in aggregation expressions
+func toNumber(val interface{}) (float64, bool) {
+	if _, ok := val.(string); ok {
+		return 0, false
+	}
+	return adaptors.ToFloat(val)
+}
+
+// toString returns the string of a value as $toString does, dates in ISO form and null as null
+func toString(val interface{}) interface{} {
+	switch mo := val.(type) {
+	case nil:
+		return nil
+	case time.Time:
+		return mo.UTC().Format("2006-01-02T15:04:05.000Z")
+	}
+
+	if val == remove {
+		return nil
+	}
+
+	return adaptors.ToString(val)
+}
+
+// project returns the included fields of the document, where dotted paths select fields of embedded objects and lists
+func project(doc M, spec M, vars M) (M, error) {
+	out := make(M)
+
+	if id, ok := doc["_id"]; ok {
+		if val, has := spec["_id"]; !has || truthy(val) {
+			out["_id"] = id
+		}
+	}
+
+	for key, val := range spec {
+		if key == "_id" {
+			continue
+		}
+
+		switch val.(type) {
+		case int, float64, bool:
+			if truthy(val) {
+				projectPath(doc, out, strings.Split(key, "."))
+			}
+			continue
+		}
+
+		res, err := evalExpr(val, doc, vars)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if res != remove {
+			out[key] = res
+		}
+	}
+
+	return out, nil
+}
+
+// projectPath copies the value at the path from the source into the target document
+func projectPath(src, dest M, path []string) {
+	val, ok := src[path[0]]
+
+	if !ok {
+		return
+	}
+
+	if len(path) == 1 {
+		dest[path[0]] = val
+		return
+	}
+
+	if sub, ok := toDoc(val); ok {
+		target, ok := dest[path[0]].(M)
+
+		if !ok {
+			target = make(M)
+			dest[path[0]] = target
+		}
+
+		projectPath(sub, target, path[1:])
+		return
+	}
+
+	list, ok := toList(val)
+
+	if !ok {
+		return
+	}
+
+	targets, ok := dest[path[0]].([]interface{})
+
+	if !ok {
+		targets = nil
+
+		for _, item := range list {
+			if _, ok := toDoc(item); ok {
+				targets = append(targets, make(M))
+			}
+		}
+
+		if targets == nil {
+			targets = []interface{}{}
+		}
+
+		dest[path[0]] = targets
+	}
+
+	var index int
+
+	for _, item := range list {
+		sub, ok := toDoc(item)
+
+		if !ok {
+			continue
+		}
+
+		projectPath(sub, targets[index].(M), path[1:])
+		index++
+	}
+}
+
+// lookupPath returns the value at the dotted path of the document, collecting the values of every item when the path crosses a list
+func lookupPath(doc M, path string) interface{} {
+	if doc == nil {
+		return nil
+	}
+
+	parts := strings.SplitN(path, ".", 2)
+	val, ok := doc[parts[0]]
+
+	if !ok {
+		return nil
+	}
+
+	if len(parts) == 1 {
+		return normalize(val)
+	}
+
+	if sub, ok := toDoc(val); ok {
+		return lookupPath(sub, parts[1])
+	}
+
+	list, ok := toList(val)
+
+	if !ok {
+		return nil
+	}
+
+	var res []interface{}
+
+	for _, item := range list {
+		sub, ok := toDoc(item)
+
+		if !ok {
+			continue
+		}
+
+		if found := lookupPath(sub, parts[1]); found != nil {
+			res = append(res, found)
+		}
+	}
+
+	return res
+}
+
+// normalize converts lists of documents into []interface{} so they are handled alike
+func normalize(val interface{}) interface{} {
+	if list, ok := toList(val); ok {
+		return list
+	}
+	return val
+}
+
+// clone returns a shallow copy of the document
+func clone(doc M) M {
+	out := make(M, len(doc))
+
+	for key, val := range doc {
+		out[key] = val
+	}
+
+	return out
+}
+
+// toDoc returns the value as a document if it is one
+func toDoc(val interface{}) (M, bool) {
+	switch mo := val.(type) {
+	case M:
+		return mo, true
+	case map[string]interface{}:
+		return M(mo), true
+	case adaptors.Record:
+		return M(mo), true
+	}
+	return nil, false
+}
+
+// toList returns the value as a list if it is one
+func toList(val interface{}) ([]interface{}, bool) {
+	switch mo := val.(type) {
+	case []interface{}:
+		return mo, true
+	case []M:
+		var res []interface{}
+
+		for _, item := range mo {
+			res = append(res, item)
+		}
+
+		return res, true
+	case []map[string]interface{}:
+		var res []interface{}
+
+		for _, item := range mo {
+			res = append(res, item)
+		}
+
+		return res, true
+	}
+	return nil, false
+}
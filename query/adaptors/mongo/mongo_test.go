@@ -0,0 +1,285 @@
+package mongo
+
+import (
+	"context"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influx6/data/query/adaptors"
+	"github.com/influx6/data/query/parser"
+	"github.com/influx6/flux"
+)
+
+func engine(t *testing.T) *Engine {
+	fs, err := os.Open("./../../fixtures/users.json")
+
+	if err != nil {
+		flux.FatalFailed(t, "File.Error occured: %+s", err)
+	}
+
+	defer fs.Close()
+
+	en := NewEngine()
+
+	if err := en.Load(fs); err != nil {
+		flux.FatalFailed(t, "Failed to load documents: %+s", err)
+	}
+
+	return en
+}
+
+func send(t *testing.T, qo flux.Reactor, query string) interface{} {
+	var ws sync.WaitGroup
+	ws.Add(1)
+
+	var res interface{}
+
+	qo.React(func(r flux.Reactor, err error, d interface{}) {
+		defer ws.Done()
+
+		if err != nil {
+			flux.FatalFailed(t, "Failed to run query: %+s", err)
+		}
+
+		res = d
+	}, true)
+
+	qo.Send(query)
+
+	ws.Wait()
+	qo.Close()
+
+	return res
+}
+
+func TestPipeline(t *testing.T) {
+	qo := BuildPreQuero(DefaultOperators, parser.DefaultInspectionFactory)
+	pipe := send(t, qo, `users(){ name, age(gt: 20, lt: 40), photos(with: [user_id id]){ url } }`).(*Pipeline)
+
+	if pipe.Collection != "users" {
+		flux.FatalFailed(t, "Expected pipeline on 'users' but got %q", pipe.Collection)
+	}
+
+	js, err := pipe.JSON()
+
+	if err != nil {
+		flux.FatalFailed(t, "Failed to marshal pipeline: %+s", err)
+	}
+
+	expected := `[{"$match":{"age":{"$gt":20,"$lt":40}}},` +
+		`{"$lookup":{"as":"photos","from":"photos","let":{"pk":"$id"},"pipeline":[{"$match":{"$expr":{"$eq":["$user_id","$$pk"]}}},{"$project":{"_id":0,"url":1}}]}},` +
+		`{"$project":{"_id":0,"age":1,"name":1,"photos":1}}]`
+
+	if js != expected {
+		flux.FatalFailed(t, "Expected pipeline:\n%s\nbut got:\n%s", expected, js)
+	}
+
+	flux.LogPassed(t, "Compiled query into aggregation pipeline")
+}
+
+func TestEngineQuery(t *testing.T) {
+	res := send(t, Quero(engine(t)), `users(){ name, age(gt: 20, lt: 40), photos(with: [user_id id]){ url } }`).(map[string]interface{})
+
+	users := res["users"].([]M)
+
+	if len(users) != 2 {
+		flux.FatalFailed(t, "Expected 2 users aged between 20 and 40 but got %d", len(users))
+	}
+
+	if photos := users[0]["photos"].([]interface{}); len(photos) != 2 {
+		flux.FatalFailed(t, "Expected 2 joined photos for 'alex' but got %d", len(photos))
+	}
+
+	if _, ok := users[0]["street"]; ok {
+		flux.FatalFailed(t, "Expected only selected fields but found 'street'")
+	}
+
+	flux.LogPassed(t, "Executed pipeline with conditions and lookups on the engine")
+}
+
+func TestEngineEmbedded(t *testing.T) {
+	res := send(t, Quero(engine(t)), `users(id: 1){ name, address{ city }, skills(){ name, level(gte: 6) } }`).(map[string]interface{})
+
+	users := res["users"].([]M)
+
+	if len(users) != 1 {
+		flux.FatalFailed(t, "Expected user with id 1 but got %d users", len(users))
+	}
+
+	if addr, ok := users[0]["address"].(M); !ok || addr["city"] != "lagos" || addr["zip"] != nil {
+		flux.FatalFailed(t, "Expected embedded address with only its city: %+v", users[0]["address"])
+	}
+
+	if skills := users[0]["skills"].([]interface{}); len(skills) != 1 {
+		flux.FatalFailed(t, "Expected 1 embedded skill with level >= 6 but got %d", len(skills))
+	}
+
+	flux.LogPassed(t, "Filtered embedded objects and lists on the engine")
+}
+
+func TestEnginePatterns(t *testing.T) {
+	qo := BuildPreQuero(DefaultOperators, parser.DefaultInspectionFactory)
+	pipe := send(t, qo, `users(){ name(ilike: 'a%'), street(contains: 'new.') }`).(*Pipeline)
+
+	js, err := pipe.JSON()
+
+	if err != nil {
+		flux.FatalFailed(t, "Failed to marshal pipeline: %+s", err)
+	}
+
+	if !strings.Contains(js, `"name":{"$options":"i","$regex":"(?s)^a.*$"}`) || !strings.Contains(js, `"$regex":"new\\."`) {
+		flux.FatalFailed(t, "Expected $regex conditions in pipeline: %s", js)
+	}
+
+	res := send(t, Quero(engine(t)), `users(){ name, street(startswith: 'new ') }`).(map[string]interface{})
+
+	if users := res["users"].([]M); len(users) != 1 || users[0]["name"] != "josh" {
+		flux.FatalFailed(t, "Expected only 'josh' to live on a street starting with 'new ': %+v", users)
+	}
+
+	flux.LogPassed(t, "Matched documents with $regex conditions on the engine")
+}
+
+func TestEngineDates(t *testing.T) {
+	now := time.Date(2016, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	ops := NewOperators()
+	ops.Now = func() time.Time { return now }
+	AddDefaultOperators(ops)
+
+	graphs, err := adaptors.Parse(parser.DefaultInspectionFactory, `users(){ name, joined(within: 7d) }`)
+
+	if err != nil {
+		flux.FatalFailed(t, "Failed to parse query: %+s", err)
+	}
+
+	pipe, err := NewCompiler(ops).Compile(graphs[0])
+
+	if err != nil {
+		flux.FatalFailed(t, "Failed to compile pipeline: %+s", err)
+	}
+
+	js, err := pipe.JSON()
+
+	if err != nil {
+		flux.FatalFailed(t, "Failed to marshal pipeline: %+s", err)
+	}
+
+	if !strings.Contains(js, `"joined":{"$gte":"2016-03-03T12:00:00Z","$lte":"2016-03-10T12:00:00Z"}`) {
+		flux.FatalFailed(t, "Expected the period resolved against the clock: %s", js)
+	}
+
+	en := NewEngine()
+	en.Insert("users", M{"name": "alex", "joined": "2016-03-08T10:00:00Z"}, M{"name": "josh", "joined": "2016-01-01"})
+
+	docs, err := en.Aggregate(pipe.Collection, pipe.Resolved())
+
+	if err != nil {
+		flux.FatalFailed(t, "Failed to run pipeline: %+s", err)
+	}
+
+	if len(docs) != 1 || docs[0]["name"] != "alex" {
+		flux.FatalFailed(t, "Expected only 'alex' to have joined within 7 days: %+v", docs)
+	}
+
+	flux.LogPassed(t, "Matched date conditions resolved against the operators clock")
+
+	//the compiled pipeline is resolved again whenever it runs, as a watched query does
+	now = now.AddDate(0, 1, 0)
+
+	res, err := NewAdaptor(en, ops).Execute(context.Background(), pipe)
+
+	if err != nil {
+		flux.FatalFailed(t, "Failed to run pipeline: %+s", err)
+	}
+
+	if users, ok := res["users"].([]M); !ok || len(users) != 0 {
+		flux.FatalFailed(t, "Expected no user to have joined within 7 days a month later: %+v", res)
+	}
+
+	flux.LogPassed(t, "Resolved relative dates when the compiled pipeline runs")
+}
+
+func TestEngineComputed(t *testing.T) {
+	qo := BuildPreQuero(DefaultOperators, parser.DefaultInspectionFactory)
+	pipe := send(t, qo, `users(id: 1){ name, label: upper(name), tag: concat(name, '-', street, zip), half: age / 2, none: age / 0 }`).(*Pipeline)
+
+	js, err := pipe.JSON()
+
+	if err != nil {
+		flux.FatalFailed(t, "Failed to marshal pipeline: %+s", err)
+	}
+
+	if !strings.Contains(js, `"$addFields"`) || !strings.Contains(js, `"$toUpper":"$name"`) || !strings.Contains(js, `"$divide":["$age",2]`) {
+		flux.FatalFailed(t, "Expected computed fields added as expressions: %s", js)
+	}
+
+	docs, err := engine(t).Aggregate(pipe.Collection, pipe.Stages)
+
+	if err != nil {
+		flux.FatalFailed(t, "Failed to run pipeline: %+s", err)
+	}
+
+	if len(docs) != 1 {
+		flux.FatalFailed(t, "Expected user with id 1 but got %d users", len(docs))
+	}
+
+	user := docs[0]
+
+	if user["label"] != "ALEX" || user["tag"] != "alex-lagos" || user["half"] != 10.5 || user["none"] != nil {
+		flux.FatalFailed(t, "Expected computed fields matching their in-memory values: %+v", user)
+	}
+
+	if !NewAdaptor(nil, nil).Capabilities().SupportsFunction("upper") {
+		flux.FatalFailed(t, "Expected the mongo adaptor to support the default functions")
+	}
+
+	flux.LogPassed(t, "Computed fields with aggregation expressions on the engine")
+}
+
+func TestEngineExists(t *testing.T) {
+	res := send(t, Quero(engine(t)), `users(){ name, photos(exists: true, with: [user_id id]){ url(contains: 'lagos') } }`).(map[string]interface{})
+
+	users := res["users"].([]M)
+
+	if len(users) != 1 || users[0]["name"] != "alex" {
+		flux.FatalFailed(t, "Expected only 'alex' to have a photo of lagos: %+v", users)
+	}
+
+	if _, ok := users[0]["photos"]; ok {
+		flux.FatalFailed(t, "Expected existence rules to only filter the record: %+v", users[0])
+	}
+
+	res = send(t, Quero(engine(t)), `users(){ name, photos(notexists: true, with: [user_id id]), skills(exists: true){ level(gte: 7) } }`).(map[string]interface{})
+
+	if users := res["users"].([]M); len(users) != 0 {
+		flux.FatalFailed(t, "Expected no user without photos to have a skill of level 7: %+v", users)
+	}
+
+	res = send(t, Quero(engine(t)), `users(){ name, skills(notexists: true) }`).(map[string]interface{})
+
+	if users := res["users"].([]M); len(users) != 1 || users[0]["name"] != "sara" {
+		flux.FatalFailed(t, "Expected only 'sara' to have no skills: %+v", users)
+	}
+
+	flux.LogPassed(t, "Filtered documents by the existence of joined and embedded records on the engine")
+}
+
+func TestEngineSearch(t *testing.T) {
+	res := send(t, Quero(engine(t)), `users(){ name, street(search: 'YORK new') }`).(map[string]interface{})
+
+	if users := res["users"].([]M); len(users) != 1 || users[0]["name"] != "josh" {
+		flux.FatalFailed(t, "Expected only 'josh' to live on a street with both words: %+v", users)
+	}
+
+	res = send(t, Quero(engine(t)), `users(){ name, street(search: 'new lagos') }`).(map[string]interface{})
+
+	if users := res["users"].([]M); len(users) != 0 {
+		flux.FatalFailed(t, "Expected no street with both words: %+v", users)
+	}
+
+	flux.LogPassed(t, "Matched every word of search conditions on the engine")
+}
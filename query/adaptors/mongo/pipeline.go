@@ -0,0 +1,979 @@
+package mongo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influx6/data/query/adaptors"
+	"github.com/influx6/data/query/parser"
+	"github.com/influx6/ds"
+	"github.com/influx6/flux"
+)
+
+// M defines a single mongo document eg a filter,projection or pipeline stage
+type M map[string]interface{}
+
+// Pipeline defines an aggregation pipeline run against a root collection, Now returns the time its Clock values are resolved against
+// when it runs, time.Now is used when nil
+type Pipeline struct {
+	Collection string
+	Stages     []M
+	Now        func() time.Time
+}
+
+// Clock defines a value of a pipeline that depends on the time the pipeline runs at eg the date of a relative date condition,
+// as a compiled pipeline may be run again long after it was compiled
+type Clock func(now time.Time) interface{}
+
+// Resolved returns the stages of the pipeline with every Clock replaced by its value at the pipeline's current time
+func (p *Pipeline) Resolved() []M {
+	now := time.Now()
+
+	if p.Now != nil {
+		now = p.Now()
+	}
+
+	stages := make([]M, len(p.Stages))
+
+	for index, stage := range p.Stages {
+		stages[index] = resolve(stage, now).(M)
+	}
+
+	return stages
+}
+
+// resolve returns a copy of the value with every Clock within it replaced by its value at now
+func resolve(val interface{}, now time.Time) interface{} {
+	switch mo := val.(type) {
+	case Clock:
+		return mo(now)
+	case M:
+		doc := make(M, len(mo))
+
+		for key, item := range mo {
+			doc[key] = resolve(item, now)
+		}
+
+		return doc
+	case []M:
+		docs := make([]M, len(mo))
+
+		for index, item := range mo {
+			docs[index] = resolve(item, now).(M)
+		}
+
+		return docs
+	case []interface{}:
+		items := make([]interface{}, len(mo))
+
+		for index, item := range mo {
+			items[index] = resolve(item, now)
+		}
+
+		return items
+	}
+
+	return val
+}
+
+// JSON returns the stages of the pipeline resolved at its current time as a json string
+func (p *Pipeline) JSON() (string, error) {
+	bo, err := json.Marshal(p.Resolved())
+
+	if err != nil {
+		return "", err
+	}
+
+	return string(bo), nil
+}
+
+//OperatorNotFoundMessage provides error for conditions without a mongo operator
+const OperatorNotFoundMessage = "Error: Mongo operator for condition '%s' not Found!"
+
+// ErrNestedEmbedded is returned when an embedded record holds conditioned or joined records of its own
+var ErrNestedEmbedded = errors.New("Conditions or joins on records within embedded records are not supported")
+
+//FunctionNotFoundMessage provides error for functions of computed fields without an aggregation expression
+const FunctionNotFoundMessage = "Error: Mongo expression for function '%s' not Found!"
+
+// OperatorFx defines a function type that returns the mongo query operators for a condition eg {$gt: 20}
+type OperatorFx func(c parser.Collector) (M, error)
+
+// Operators provides a factory of mongo query operators for condition types
+type Operators struct {
+	//Now returns the time relative date conditions are resolved against when their pipelines run, time.Now is used when nil
+	Now     func() time.Time
+	factory map[string]OperatorFx
+	rw      sync.RWMutex
+}
+
+// now returns the current time of the operators
+func (o *Operators) now() time.Time {
+	if o.Now != nil {
+		return o.Now()
+	}
+	return time.Now()
+}
+
+// NewOperators returns a new Operators instance
+func NewOperators() *Operators {
+	return &Operators{factory: make(map[string]OperatorFx)}
+}
+
+// Add adds the operator function for a condition type, replacing any existing one
+func (o *Operators) Add(tag string, fx OperatorFx) {
+	o.rw.Lock()
+	o.factory[tag] = fx
+	o.rw.Unlock()
+}
+
+// Has returns true/false if an operator exists for the condition type
+func (o *Operators) Has(tag string) bool {
+	o.rw.RLock()
+	_, ok := o.factory[tag]
+	o.rw.RUnlock()
+	return ok
+}
+
+// Process returns the mongo operators for the collector using the function of its type
+func (o *Operators) Process(c parser.Collector) (M, error) {
+	tag, _ := c.Get("type").(string)
+
+	o.rw.RLock()
+	fx, ok := o.factory[tag]
+	o.rw.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf(OperatorNotFoundMessage, tag)
+	}
+
+	return fx(c)
+}
+
+// DefaultOperators provides a singleton of the mongo operators for the default inspections
+var DefaultOperators = NewOperators()
+
+// FunctionFx defines a function type that returns the aggregation expression of a function call from the expressions of its arguments
+type FunctionFx func(args []interface{}) (interface{}, error)
+
+// Functions provides a factory of the aggregation expressions of the functions computed fields call
+type Functions struct {
+	factory map[string]FunctionFx
+	rw      sync.RWMutex
+}
+
+// NewFunctions returns a new Functions instance
+func NewFunctions() *Functions {
+	return &Functions{factory: make(map[string]FunctionFx)}
+}
+
+// Add adds the expression function of a function, replacing any existing one
+func (f *Functions) Add(name string, fx FunctionFx) {
+	f.rw.Lock()
+	f.factory[strings.ToLower(name)] = fx
+	f.rw.Unlock()
+}
+
+// Has returns true/false if the function has an expression
+func (f *Functions) Has(name string) bool {
+	f.rw.RLock()
+	_, ok := f.factory[strings.ToLower(name)]
+	f.rw.RUnlock()
+	return ok
+}
+
+// Process returns the aggregation expression of the function over the expressions of its arguments
+func (f *Functions) Process(name string, args []interface{}) (interface{}, error) {
+	f.rw.RLock()
+	fx, ok := f.factory[strings.ToLower(name)]
+	f.rw.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf(FunctionNotFoundMessage, name)
+	}
+
+	return fx(args)
+}
+
+// DefaultFunctions provides a singleton of the aggregation expressions of the default functions
+var DefaultFunctions = NewFunctions()
+
+// nullable returns the expression as null when the value is null or missing, as the in-memory functions are
+func nullable(val interface{}, ex interface{}) M {
+	return M{"$cond": []interface{}{M{"$eq": []interface{}{M{"$ifNull": []interface{}{val, nil}}, nil}}, nil, ex}}
+}
+
+// AddDefaultFunctions adds the lower, upper, concat, coalesce, round and date_trunc expressions to the factory, matching the nulls
+// of their in-memory implementations
+func AddDefaultFunctions(f *Functions) {
+	f.Add("lower", func(args []interface{}) (interface{}, error) {
+		return nullable(args[0], M{"$toLower": args[0]}), nil
+	})
+
+	f.Add("upper", func(args []interface{}) (interface{}, error) {
+		return nullable(args[0], M{"$toUpper": args[0]}), nil
+	})
+
+	//$concat is null when any argument is, so null arguments are skipped as empty strings
+	f.Add("concat", func(args []interface{}) (interface{}, error) {
+		var parts []interface{}
+
+		for _, arg := range args {
+			parts = append(parts, M{"$ifNull": []interface{}{M{"$toString": arg}, ""}})
+		}
+
+		return M{"$concat": parts}, nil
+	})
+
+	f.Add("coalesce", func(args []interface{}) (interface{}, error) {
+		return M{"$ifNull": append(append([]interface{}{}, args...), nil)}, nil
+	})
+
+	f.Add("round", func(args []interface{}) (interface{}, error) {
+		return M{"$round": args}, nil
+	})
+
+	f.Add("date_trunc", func(args []interface{}) (interface{}, error) {
+		return M{"$dateTrunc": M{"date": M{"$toDate": args[1]}, "unit": args[0]}}, nil
+	})
+}
+
+// arithmetic maps the operators of computed fields to their aggregation operators
+var arithmetic = map[string]string{"+": "$add", "-": "$subtract", "*": "$multiply", "/": "$divide"}
+
+// Value converts a condition value into its document form, numbers become numbers, quotes are removed and 'null' becomes nil
+func Value(val interface{}) interface{} {
+	val = adaptors.Unwrap(val)
+
+	so, ok := val.(string)
+
+	if !ok {
+		return val
+	}
+
+	so = strings.TrimSpace(so)
+
+	if so == "null" {
+		return nil
+	}
+
+	if num, err := strconv.ParseFloat(so, 64); err == nil {
+		return num
+	}
+
+	return parser.Unquote(so)
+}
+
+// valueOperator returns an OperatorFx that places the collector's value under the operator
+func valueOperator(op string) OperatorFx {
+	return func(c parser.Collector) (M, error) {
+		if !c.Has("value") {
+			return nil, parser.ErrInvalidCollector
+		}
+		return M{op: Value(c.Get("value"))}, nil
+	}
+}
+
+// AddDefaultOperators adds the mongo operators of the default inspections to the supplied Operators
+func AddDefaultOperators(o *Operators) {
+	o.Add("is", valueOperator("$eq"))
+	o.Add("isnot", valueOperator("$ne"))
+	o.Add("gt", valueOperator("$gt"))
+	o.Add("gte", valueOperator("$gte"))
+	o.Add("lt", valueOperator("$lt"))
+	o.Add("lte", valueOperator("$lte"))
+
+	o.Add("range", func(c parser.Collector) (M, error) {
+		if !c.Has("max") || !c.Has("min") {
+			return nil, parser.ErrInvalidCollector
+		}
+		return M{"$gte": Value(c.Get("min")), "$lte": Value(c.Get("max"))}, nil
+	})
+
+	o.Add("in", func(c parser.Collector) (M, error) {
+		ranges, ok := adaptors.InValues(c)
+
+		if !ok {
+			return nil, parser.ErrInvalidCollector
+		}
+
+		var items []interface{}
+
+		for _, item := range ranges {
+			items = append(items, Value(item))
+		}
+
+		return M{"$in": items}, nil
+	})
+
+	o.Add("isnull", nullOperator(true))
+	o.Add("notnull", nullOperator(false))
+
+	o.Add("before", dateOperator(func(date parser.DateExpr) M { return M{"$lt": dateAt(date)} }))
+	o.Add("after", dateOperator(func(date parser.DateExpr) M { return M{"$gt": dateAt(date)} }))
+
+	o.Add("within", dateOperator(func(date parser.DateExpr) M {
+		return M{
+			"$gte": Clock(func(now time.Time) interface{} {
+				from, _ := date.Within(now)
+				return from
+			}),
+			"$lte": Clock(func(now time.Time) interface{} {
+				_, to := date.Within(now)
+				return to
+			}),
+		}
+	}))
+
+	o.Add("between", func(c parser.Collector) (M, error) {
+		from, err := parser.DateValue(c.Get("min"))
+
+		if err != nil {
+			return nil, err
+		}
+
+		to, err := parser.DateValue(c.Get("max"))
+
+		if err != nil {
+			return nil, err
+		}
+
+		return M{"$gte": dateAt(from), "$lte": dateAt(to)}, nil
+	})
+
+	o.Add("like", regexOperator(adaptors.LikePattern, ""))
+	o.Add("ilike", regexOperator(adaptors.LikePattern, "i"))
+	o.Add("startswith", regexOperator(func(val string) string { return "^" + regexp.QuoteMeta(val) }, ""))
+	o.Add("endswith", regexOperator(func(val string) string { return regexp.QuoteMeta(val) + "$" }, ""))
+	o.Add("contains", regexOperator(regexp.QuoteMeta, ""))
+	o.Add("match", regexOperator(nil, ""))
+
+	//search matches values holding every word of the query in any order ignoring case, as the in-memory search condition, each word
+	//is a regex of its own so they are combined with $and by the Compiler
+	o.Add("search", func(c parser.Collector) (M, error) {
+		if !c.Has("value") {
+			return nil, parser.ErrInvalidCollector
+		}
+
+		var words []M
+
+		for _, term := range adaptors.SearchTerms(adaptors.ToString(c.Get("value"))) {
+			words = append(words, M{"$regex": `\b` + regexp.QuoteMeta(term) + `\b`, "$options": "i"})
+		}
+
+		if len(words) == 0 {
+			return M{"$regex": ""}, nil
+		}
+
+		return M{"$and": words}, nil
+	})
+}
+
+// nullOperator returns an OperatorFx matching null or missing fields when the collector's boolean equals null and any other field otherwise
+func nullOperator(null bool) OperatorFx {
+	return func(c parser.Collector) (M, error) {
+		val, ok := c.Get("value").(bool)
+
+		if !ok {
+			return nil, parser.ErrInvalidCollector
+		}
+
+		if val == null {
+			return M{"$eq": nil}, nil
+		}
+
+		return M{"$ne": nil}, nil
+	}
+}
+
+// dateOperator returns an OperatorFx placing the collector's date under the operators of the build function
+func dateOperator(build func(date parser.DateExpr) M) OperatorFx {
+	return func(c parser.Collector) (M, error) {
+		date, err := parser.DateValue(c.Get("value"))
+
+		if err != nil {
+			return nil, err
+		}
+
+		return build(date), nil
+	}
+}
+
+// dateAt returns the time of an absolute date or a Clock resolving a relative date when its pipeline runs
+func dateAt(date parser.DateExpr) interface{} {
+	if !date.Relative {
+		return date.Time
+	}
+
+	return Clock(func(now time.Time) interface{} {
+		return date.Resolve(now)
+	})
+}
+
+// regexOperator returns an OperatorFx matching the field against the regular expression of the collector's unquoted value, turned into a pattern when given
+func regexOperator(pattern func(string) string, options string) OperatorFx {
+	return func(c parser.Collector) (M, error) {
+		if !c.Has("value") {
+			return nil, parser.ErrInvalidCollector
+		}
+
+		val := adaptors.ToString(c.Get("value"))
+
+		if pattern != nil {
+			val = pattern(val)
+		}
+
+		if options == "" {
+			return M{"$regex": val}, nil
+		}
+
+		return M{"$regex": val, "$options": options}, nil
+	}
+}
+
+// Compiler turns parsed query graphs into aggregation pipelines
+type Compiler struct {
+	ops *Operators
+	fns *Functions
+}
+
+// NewCompiler returns a new Compiler using the operators and DefaultFunctions, DefaultOperators is used when nil
+func NewCompiler(ops *Operators) *Compiler {
+	if ops == nil {
+		ops = DefaultOperators
+	}
+	return &Compiler{ops: ops, fns: DefaultFunctions}
+}
+
+// UseFunctions sets the expressions of the functions computed fields call
+func (c *Compiler) UseFunctions(fns *Functions) *Compiler {
+	c.fns = fns
+	return c
+}
+
+// Compile returns the aggregation pipeline of the graph, where child records with a 'with' rule become $lookup stages and others are embedded fields of their parent
+func (c *Compiler) Compile(gs ds.Graphs) (*Pipeline, error) {
+	mo, err := adaptors.DFGraph(gs)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var root *parser.ParseNode
+	children := make(map[string][]*parser.ParseNode)
+
+	for mo.Next() == nil {
+		node := mo.Node().(*parser.ParseNode)
+
+		if root == nil {
+			root = node
+			continue
+		}
+
+		children[node.PKey] = append(children[node.PKey], node)
+	}
+
+	if root == nil {
+		return nil, adaptors.ErrGraphType
+	}
+
+	stages, err := c.stages(root, children, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &Pipeline{Collection: root.Name(), Stages: stages, Now: c.ops.now}, nil
+}
+
+// Filter returns the query filter of the node's rules and field conditions, operators returned under $and are conditions of their own on the field
+func (c *Compiler) Filter(node *parser.ParseNode) (M, error) {
+	filter := make(M)
+	var err error
+
+	add := func(field string, co parser.Collector, stop func()) {
+		if adaptors.IsControlKey(field) {
+			return
+		}
+
+		var ops M
+
+		if ops, err = c.ops.Process(co); err != nil {
+			stop()
+			return
+		}
+
+		for op, val := range ops {
+			if op == "$and" {
+				and, _ := filter["$and"].([]interface{})
+
+				for _, sub := range val.([]M) {
+					and = append(and, M{field: sub})
+				}
+
+				filter["$and"] = and
+				continue
+			}
+
+			cur, _ := filter[field].(M)
+
+			if cur == nil {
+				cur = make(M)
+				filter[field] = cur
+			}
+
+			cur[op] = val
+		}
+	}
+
+	node.Rules.EachCondition(add)
+
+	if err == nil {
+		node.Records.EachCondition(add)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return filter, nil
+}
+
+// stages returns the pipeline stages retrieving the node, its joined children and its embedded children, where join holds the $match linking the node to its parent
+func (c *Compiler) stages(node *parser.ParseNode, children map[string][]*parser.ParseNode, join M) ([]M, error) {
+	var stages []M
+
+	filter, err := c.Filter(node)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for key, val := range join {
+		filter[key] = val
+	}
+
+	if len(filter) > 0 {
+		stages = append(stages, M{"$match": filter})
+	}
+
+	keys := node.Records.Keys()
+	project := make(M)
+
+	for _, key := range keys {
+		project[key] = 1
+	}
+
+	//existence rules only filter the record
+	for _, child := range children[node.Key] {
+		if want, ok := adaptors.Existence(child); ok {
+			exists, err := c.existence(child, children, want)
+
+			if err != nil {
+				return nil, err
+			}
+
+			stages = append(stages, exists...)
+		}
+	}
+
+	if len(node.Computed) > 0 {
+		fields := make(M)
+
+		for _, cf := range node.Computed {
+			ex, err := c.Computed(cf.Expr)
+
+			if err != nil {
+				return nil, node.Report(cf.Name, err.Error())
+			}
+
+			fields[cf.Name] = ex
+			project[cf.Name] = 1
+		}
+
+		stages = append(stages, M{"$addFields": fields})
+	}
+
+	for _, child := range children[node.Key] {
+		if _, ok := adaptors.Existence(child); ok {
+			continue
+		}
+
+		if rel, ok := adaptors.Relation(child); ok {
+			let := M{"pk": fmt.Sprintf("$%s", rel[1])}
+			link := M{"$expr": M{"$eq": []interface{}{fmt.Sprintf("$%s", rel[0]), "$$pk"}}}
+
+			pipeline, err := c.stages(child, children, link)
+
+			if err != nil {
+				return nil, err
+			}
+
+			stages = append(stages, M{"$lookup": M{
+				"from":     child.Name(),
+				"let":      let,
+				"pipeline": pipeline,
+				"as":       child.Name(),
+			}})
+
+			project[child.Name()] = 1
+			continue
+		}
+
+		embed, err := c.embedded(child, children)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if embed != nil {
+			stages = append(stages, M{"$addFields": M{child.Name(): embed}})
+		}
+
+		childKeys := child.Records.Keys()
+
+		if len(childKeys) == 0 {
+			project[child.Name()] = 1
+		}
+
+		for _, key := range childKeys {
+			project[fmt.Sprintf("%s.%s", child.Name(), key)] = 1
+		}
+	}
+
+	if len(keys) > 0 {
+		if _, ok := project["_id"]; !ok {
+			project["_id"] = 0
+		}
+		stages = append(stages, M{"$project": project})
+	}
+
+	return stages, nil
+}
+
+// existence returns the stages keeping the documents that have or lack any of the child's records, joined records are looked up
+// into a field removed once they are counted
+func (c *Compiler) existence(child *parser.ParseNode, children map[string][]*parser.ParseNode, want bool) ([]M, error) {
+	var stages []M
+	var source interface{}
+	var cleanup M
+
+	if rel, ok := adaptors.Relation(child); ok {
+		link := M{"$expr": M{"$eq": []interface{}{fmt.Sprintf("$%s", rel[0]), "$$pk"}}}
+
+		pipeline, err := c.stages(child, children, link)
+
+		if err != nil {
+			return nil, err
+		}
+
+		as := fmt.Sprintf("_exists_%s", child.Key)
+
+		stages = append(stages, M{"$lookup": M{
+			"from":     child.Name(),
+			"let":      M{"pk": fmt.Sprintf("$%s", rel[1])},
+			"pipeline": pipeline,
+			"as":       as,
+		}})
+
+		source = fmt.Sprintf("$%s", as)
+		cleanup = M{"$addFields": M{as: "$$REMOVE"}}
+	} else {
+		embed, err := c.embedded(child, children)
+
+		if err != nil {
+			return nil, err
+		}
+
+		source = embed
+
+		if embed == nil {
+			source = fmt.Sprintf("$%s", child.Name())
+		}
+	}
+
+	found := M{"$cond": []interface{}{
+		M{"$isArray": source},
+		M{"$gt": []interface{}{M{"$size": source}, 0}},
+		M{"$ne": []interface{}{M{"$ifNull": []interface{}{source, nil}}, nil}},
+	}}
+
+	if !want {
+		found = M{"$not": []interface{}{found}}
+	}
+
+	stages = append(stages, M{"$match": M{"$expr": found}})
+
+	if cleanup != nil {
+		stages = append(stages, cleanup)
+	}
+
+	return stages, nil
+}
+
+// Computed returns the aggregation expression of a computed field, division by zero is null as it is in memory
+func (c *Compiler) Computed(ex parser.Expr) (interface{}, error) {
+	switch mo := ex.(type) {
+	case *parser.ColumnExpr:
+		return fmt.Sprintf("$%s", mo.Name), nil
+	case *parser.LiteralExpr:
+		if _, ok := mo.Value.(string); ok {
+			return M{"$literal": mo.Value}, nil
+		}
+		return mo.Value, nil
+	case *parser.CallExpr:
+		var args []interface{}
+
+		for _, arg := range mo.Args {
+			val, err := c.Computed(arg)
+
+			if err != nil {
+				return nil, err
+			}
+
+			args = append(args, val)
+		}
+
+		return c.fns.Process(mo.Func, args)
+	case *parser.BinaryExpr:
+		left, err := c.Computed(mo.Left)
+
+		if err != nil {
+			return nil, err
+		}
+
+		right, err := c.Computed(mo.Right)
+
+		if err != nil {
+			return nil, err
+		}
+
+		op, ok := arithmetic[mo.Op]
+
+		if !ok {
+			return nil, fmt.Errorf("Unknown operator %s", mo.Op)
+		}
+
+		calc := M{op: []interface{}{left, right}}
+
+		if op == "$divide" {
+			return M{"$cond": []interface{}{M{"$eq": []interface{}{right, 0}}, nil, calc}}, nil
+		}
+
+		return calc, nil
+	}
+
+	return nil, fmt.Errorf("Unknown expression %s", ex)
+}
+
+// embedded returns the expression filtering an embedded list or object by the child's conditions, or nil if it has none
+func (c *Compiler) embedded(child *parser.ParseNode, children map[string][]*parser.ParseNode) (interface{}, error) {
+	for _, sub := range children[child.Key] {
+		if _, ok := adaptors.Relation(sub); ok {
+			return nil, ErrNestedEmbedded
+		}
+
+		filter, err := c.Filter(sub)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if len(filter) > 0 {
+			return nil, ErrNestedEmbedded
+		}
+	}
+
+	filter, err := c.Filter(child)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(filter) == 0 {
+		return nil, nil
+	}
+
+	source := fmt.Sprintf("$%s", child.Name())
+
+	return M{"$cond": []interface{}{
+		M{"$isArray": source},
+		M{"$filter": M{
+			"input": source,
+			"as":    "item",
+			"cond":  Expression("$$item", filter),
+		}},
+		M{"$cond": []interface{}{
+			Expression(source, filter),
+			source,
+			"$$REMOVE",
+		}},
+	}}, nil
+}
+
+// Expression converts a query filter into the equivalent aggregation expression over the fields of the giving path eg {age: {$gt: 20}} => {$and: [{$gt: ["$$item.age", 20]}]}
+func Expression(path string, filter M) M {
+	var conds []interface{}
+
+	for _, field := range sortedKeys(filter) {
+		if field == "$and" {
+			for _, sub := range filter[field].([]interface{}) {
+				conds = append(conds, Expression(path, sub.(M)))
+			}
+			continue
+		}
+
+		ops := filter[field].(M)
+		fpath := fmt.Sprintf("%s.%s", path, field)
+
+		for _, op := range sortedKeys(ops) {
+			conds = append(conds, M{op: []interface{}{fpath, ops[op]}})
+		}
+	}
+
+	return M{"$and": conds}
+}
+
+// sortedKeys returns the keys of the document in order
+func sortedKeys(m M) []string {
+	var keys []string
+
+	for key := range m {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+	return keys
+}
+
+// Executor defines a runner of aggregation pipelines eg the embedded Engine or a mongo driver wrapper
+type Executor interface {
+	Aggregate(collection string, stages []M) ([]M, error)
+}
+
+// ErrInvalidPipeline is returned when the data is not a *Pipeline
+var ErrInvalidPipeline = errors.New("Data type not *Pipeline")
+
+// PipelineBuilder returns a reactor that compiles each ds.Graphs into a *Pipeline
+func PipelineBuilder(ops *Operators) flux.Reactor {
+	co := NewCompiler(ops)
+
+	return adaptors.QueryAdaptor(func(r flux.Reactor, gs ds.Graphs) {
+		pipe, err := co.Compile(gs)
+
+		if err != nil {
+			r.ReplyError(err)
+			return
+		}
+
+		r.Reply(pipe)
+	})
+}
+
+// PipelineExecutor returns a reactor that runs each *Pipeline on the executor, replying with a map of the root collection to its documents
+func PipelineExecutor(ex Executor) flux.Reactor {
+	return flux.Reactive(func(r flux.Reactor, err error, d interface{}) {
+		if err != nil {
+			r.ReplyError(err)
+			return
+		}
+
+		pipe, ok := d.(*Pipeline)
+
+		if !ok {
+			r.ReplyError(ErrInvalidPipeline)
+			return
+		}
+
+		docs, err := ex.Aggregate(pipe.Collection, pipe.Resolved())
+
+		if err != nil {
+			r.ReplyError(err)
+			return
+		}
+
+		r.Reply(map[string]interface{}{pipe.Collection: docs})
+	})
+}
+
+// Adaptor provides the adaptors.Adaptor of an Executor, its plans are *Pipeline
+type Adaptor struct {
+	ex  Executor
+	co  *Compiler
+	ops *Operators
+}
+
+// NewAdaptor returns a new Adaptor running pipelines on the executor using the operators, DefaultOperators is used when nil
+func NewAdaptor(ex Executor, ops *Operators) *Adaptor {
+	co := NewCompiler(ops)
+	return &Adaptor{ex: ex, co: co, ops: co.ops}
+}
+
+// Compile returns the *Pipeline of the graph
+func (a *Adaptor) Compile(gs ds.Graphs) (adaptors.Plan, error) {
+	return a.co.Compile(gs)
+}
+
+// Execute runs the *Pipeline of the plan on the executor
+func (a *Adaptor) Execute(ctx context.Context, p adaptors.Plan) (adaptors.Result, error) {
+	pipe, ok := p.(*Pipeline)
+
+	if !ok {
+		return nil, adaptors.ErrInvalidPlan
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	docs, err := a.ex.Aggregate(pipe.Collection, pipe.Resolved())
+
+	if err != nil {
+		return nil, err
+	}
+
+	return adaptors.Result{pipe.Collection: docs}, nil
+}
+
+// Capabilities returns the capabilities of the mongo adaptor
+func (a *Adaptor) Capabilities() adaptors.Capabilities {
+	return adaptors.Capabilities{
+		Joins:      true,
+		Embedded:   true,
+		Conditions: a.ops,
+		Computed:   true,
+		Functions:  a.co.fns,
+	}
+}
+
+// BuildPreQuero generates a query parser producing aggregation pipelines without executing them
+func BuildPreQuero(ops *Operators, ds *parser.InspectionFactory) flux.Reactor {
+	co := adaptors.ChunkParser(ds)
+	co.Bind(PipelineBuilder(ops), true)
+	return co
+}
+
+// BuildQuero generates a full query parser that executes its pipelines on the executor
+func BuildQuero(ex Executor, ops *Operators, ds *parser.InspectionFactory) flux.Reactor {
+	co := BuildPreQuero(ops, ds)
+	co.Bind(PipelineExecutor(ex), true)
+	return co
+}
+
+// Quero returns a new instance of a complete query handler over the executor using the default inspections and operators
+func Quero(ex Executor) flux.Reactor {
+	return BuildQuero(ex, DefaultOperators, parser.DefaultInspectionFactory)
+}
+
+func init() {
+	AddDefaultOperators(DefaultOperators)
+	AddDefaultFunctions(DefaultFunctions)
+}
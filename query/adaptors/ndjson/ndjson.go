@@ -0,0 +1,180 @@
+package ndjson
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/influx6/data/query/adaptors"
+	"github.com/influx6/data/query/parser"
+	"github.com/influx6/flux"
+)
+
+// DefaultSample is the number of records read to infer the kinds of a table's columns
+const DefaultSample = 100
+
+// Source provides an adaptors.Source over newline-delimited json files, where each table is made up of one or more files holding a json object per line
+type Source struct {
+	Sample  int
+	tables  map[string][]string
+	columns map[string][]adaptors.Column
+	rw      sync.RWMutex
+}
+
+// NewSource returns a new Source without any tables
+func NewSource() *Source {
+	return &Source{
+		Sample:  DefaultSample,
+		tables:  make(map[string][]string),
+		columns: make(map[string][]adaptors.Column),
+	}
+}
+
+// Open returns a Source over the .ndjson/.jsonl file or directory of such files at the path, see adaptors.Tables
+func Open(path string) (*Source, error) {
+	tables, err := adaptors.Tables(path, ".ndjson", ".jsonl")
+
+	if err != nil {
+		return nil, err
+	}
+
+	src := NewSource()
+
+	for name, files := range tables {
+		src.Add(name, files...)
+	}
+
+	return src, nil
+}
+
+// Add adds the files as the records of the named table
+func (s *Source) Add(name string, files ...string) {
+	s.rw.Lock()
+	s.tables[name] = append(s.tables[name], files...)
+	delete(s.columns, name)
+	s.rw.Unlock()
+}
+
+// files returns the files of the named table
+func (s *Source) files(name string) ([]string, error) {
+	s.rw.RLock()
+	files, ok := s.tables[name]
+	s.rw.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf(adaptors.CollectionNotFoundMessage, name)
+	}
+
+	return files, nil
+}
+
+// Columns returns the fields of the named table sorted by name with their kinds inferred from its first records
+func (s *Source) Columns(name string) ([]adaptors.Column, error) {
+	s.rw.RLock()
+	cols, ok := s.columns[name]
+	s.rw.RUnlock()
+
+	if ok {
+		return cols, nil
+	}
+
+	kinds := make(map[string]adaptors.Kind)
+	var count int
+
+	err := s.Each(name, func(rec adaptors.Record) error {
+		if count >= s.Sample {
+			return adaptors.ErrStopEach
+		}
+
+		count++
+
+		for key, val := range rec {
+			kinds[key] = adaptors.Widen(kinds[key], adaptors.KindOfValue(val))
+		}
+
+		return nil
+	})
+
+	if err != nil && err != adaptors.ErrStopEach {
+		return nil, err
+	}
+
+	for key, kind := range kinds {
+		if kind == adaptors.KindUnknown {
+			kind = adaptors.KindText
+		}
+		cols = append(cols, adaptors.Column{Name: key, Kind: kind})
+	}
+
+	sort.Sort(byName(cols))
+
+	s.rw.Lock()
+	s.columns[name] = cols
+	s.rw.Unlock()
+
+	return cols, nil
+}
+
+// byName sorts columns by their names
+type byName []adaptors.Column
+
+func (b byName) Len() int           { return len(b) }
+func (b byName) Less(i, j int) bool { return b[i].Name < b[j].Name }
+func (b byName) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
+
+// Each streams the records of the named table's files, skipping blank lines
+func (s *Source) Each(name string, fx func(adaptors.Record) error) error {
+	files, err := s.files(name)
+
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		if err := each(file, fx); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// each streams the json objects of a single file
+func each(file string, fx func(adaptors.Record) error) error {
+	fs, err := os.Open(file)
+
+	if err != nil {
+		return err
+	}
+
+	defer fs.Close()
+
+	dec := json.NewDecoder(fs)
+
+	for {
+		var rec adaptors.Record
+
+		if err := dec.Decode(&rec); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("%s: %s", file, err)
+		}
+
+		if err := fx(rec); err != nil {
+			return err
+		}
+	}
+}
+
+// BuildQuero generates a full query parser that executes its queries against the ndjson tables
+func BuildQuero(src *Source, mf *adaptors.MatchFactory, ds *parser.InspectionFactory) flux.Reactor {
+	return adaptors.BuildSourceQuero(src, mf, ds)
+}
+
+// Quero returns a new instance of a complete query handler over the ndjson tables using the default inspections and matchers
+func Quero(src *Source) flux.Reactor {
+	return BuildQuero(src, adaptors.DefaultMatchers, parser.DefaultInspectionFactory)
+}
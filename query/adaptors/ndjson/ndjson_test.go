@@ -0,0 +1,85 @@
+package ndjson
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/influx6/data/query/adaptors"
+	"github.com/influx6/flux"
+)
+
+func run(t *testing.T, src *Source, query string) []adaptors.Record {
+	var ws sync.WaitGroup
+	ws.Add(1)
+
+	var res map[string]interface{}
+
+	qo := Quero(src)
+
+	qo.React(func(r flux.Reactor, err error, d interface{}) {
+		defer ws.Done()
+
+		if err != nil {
+			flux.FatalFailed(t, "Failed to query ndjson tables: %+s", err)
+		}
+
+		res = d.(map[string]interface{})
+	}, true)
+
+	qo.Send(query)
+
+	ws.Wait()
+	qo.Close()
+
+	return res["users"].([]adaptors.Record)
+}
+
+func TestNDJSONColumns(t *testing.T) {
+	src, err := Open("./../../fixtures/ndjson")
+
+	if err != nil {
+		flux.FatalFailed(t, "Failed to open ndjson directory: %+s", err)
+	}
+
+	cols, err := src.Columns("users")
+
+	if err != nil {
+		flux.FatalFailed(t, "Failed to infer columns: %+s", err)
+	}
+
+	kinds := make(map[string]adaptors.Kind)
+
+	for _, col := range cols {
+		kinds[col.Name] = col.Kind
+	}
+
+	if kinds["id"] != adaptors.KindInt || kinds["age"] != adaptors.KindFloat || kinds["name"] != adaptors.KindText {
+		flux.FatalFailed(t, "Expected id:int, age:float and name:text but got %+v", cols)
+	}
+
+	flux.LogPassed(t, "Inferred ndjson field kinds from sample records")
+}
+
+func TestNDJSONQuery(t *testing.T) {
+	src, err := Open("./../../fixtures/ndjson")
+
+	if err != nil {
+		flux.FatalFailed(t, "Failed to open ndjson directory: %+s", err)
+	}
+
+	users := run(t, src, `users(){ name, age(range: 20..40), address{ city }, photos(with: [user_id id]){ url } }`)
+
+	if len(users) != 2 {
+		flux.FatalFailed(t, "Expected 2 users aged between 20 and 40 but got %d", len(users))
+	}
+
+	if photos := users[0]["photos"].([]adaptors.Record); len(photos) != 2 {
+		flux.FatalFailed(t, "Expected 2 photos joined from the partitioned photos table but got %d", len(photos))
+	}
+
+	if addr, ok := users[1]["address"].(adaptors.Record); !ok || addr["city"] != "new york" {
+		flux.FatalFailed(t, "Expected embedded address for 'josh': %+v", users[1]["address"])
+	}
+
+	flux.LogPassed(t, "Queried ndjson tables with conditions, embedded records and joins across files")
+}
@@ -0,0 +1,69 @@
+package adaptors
+
+import (
+	"context"
+	"sync"
+)
+
+// Parallel runs the task for each index below count with at most limit of them running at once, a limit of one or less runs them in order on the
+// calling goroutine. The first task to fail cancels the context of the others and its error is returned, tasks keep results deterministic by
+// writing them to their own index
+func Parallel(ctx context.Context, limit, count int, task func(ctx context.Context, index int) error) error {
+	if limit <= 1 || count <= 1 {
+		for index := 0; index < count; index++ {
+			if err := task(ctx, index); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	slots := make(chan struct{}, limit)
+
+	var wg sync.WaitGroup
+	var once sync.Once
+	var first error
+
+	launched := 0
+
+	for ; launched < count; launched++ {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+		}
+
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+
+		go func(index int) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			if err := task(ctx, index); err != nil {
+				once.Do(func() {
+					first = err
+					cancel()
+				})
+			}
+		}(launched)
+	}
+
+	wg.Wait()
+
+	if first != nil {
+		return first
+	}
+
+	//the parent context was done before every task could start
+	if launched < count {
+		return ctx.Err()
+	}
+
+	return nil
+}
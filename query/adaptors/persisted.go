@@ -0,0 +1,302 @@
+package adaptors
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/influx6/data/query/parser"
+	"github.com/influx6/ds"
+)
+
+//PersistedNotFoundMessage provides error for references to queries that were never persisted
+const PersistedNotFoundMessage = "Persisted query '%s' not found"
+
+//DuplicatePersistedMessage provides error for two different queries registered under the same id
+const DuplicatePersistedMessage = "Persisted query id '%s' is already registered"
+
+// ErrNotPersisted is returned when a query that is not persisted is run where only persisted queries are allowed
+var ErrNotPersisted = errors.New("Query is not persisted")
+
+// PersistedQuery defines a registered query, parsed once and addressed by its ID or the Hash of its canonical text
+type PersistedQuery struct {
+	ID        string
+	Hash      string
+	Canonical string
+	graphs    []ds.Graphs
+}
+
+// Graphs returns copies of the parsed graphs of the query, ready to be bound and executed
+func (p *PersistedQuery) Graphs() ([]ds.Graphs, error) {
+	var graphs []ds.Graphs
+
+	for _, gs := range p.graphs {
+		cl, err := CloneGraph(gs)
+
+		if err != nil {
+			return nil, err
+		}
+
+		graphs = append(graphs, cl)
+	}
+
+	return graphs, nil
+}
+
+// QueryStore defines a set of persisted queries looked up by their id or hash
+type QueryStore interface {
+	Get(ref string) (*PersistedQuery, error)
+}
+
+// Hash returns the hex SHA-256 of the canonical text of the graphs, so differently formatted texts of a query share a hash
+func Hash(graphs []ds.Graphs) (string, string, error) {
+	var texts []string
+
+	for _, gs := range graphs {
+		text, err := parser.Canonical(gs)
+
+		if err != nil {
+			return "", "", err
+		}
+
+		texts = append(texts, text)
+	}
+
+	canonical := strings.Join(texts, "\n")
+	sum := sha256.Sum256([]byte(canonical))
+
+	return hex.EncodeToString(sum[:]), canonical, nil
+}
+
+// MemoryQueryStore provides a QueryStore of queries registered in code
+type MemoryQueryStore struct {
+	inspect *parser.InspectionFactory
+	ids     map[string]*PersistedQuery
+	hashes  map[string]*PersistedQuery
+	rw      sync.RWMutex
+}
+
+// NewMemoryQueryStore returns a new MemoryQueryStore parsing its queries with the inspections, parser.DefaultInspectionFactory is used when nil
+func NewMemoryQueryStore(inspect *parser.InspectionFactory) *MemoryQueryStore {
+	if inspect == nil {
+		inspect = parser.DefaultInspectionFactory
+	}
+
+	return &MemoryQueryStore{
+		inspect: inspect,
+		ids:     make(map[string]*PersistedQuery),
+		hashes:  make(map[string]*PersistedQuery),
+	}
+}
+
+// Add parses and persists the query under the id, an empty id uses the query's hash as its id
+func (m *MemoryQueryStore) Add(id, query string) (*PersistedQuery, error) {
+	graphs, err := Parse(m.inspect, query)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return m.add(id, graphs)
+}
+
+// add persists the parsed graphs under the id
+func (m *MemoryQueryStore) add(id string, graphs []ds.Graphs) (*PersistedQuery, error) {
+	hash, canonical, err := Hash(graphs)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if id == "" {
+		id = hash
+	}
+
+	pq := &PersistedQuery{ID: id, Hash: hash, Canonical: canonical, graphs: graphs}
+
+	m.rw.Lock()
+	defer m.rw.Unlock()
+
+	if old, ok := m.ids[id]; ok && old.Hash != hash {
+		return nil, fmt.Errorf(DuplicatePersistedMessage, id)
+	}
+
+	m.ids[id] = pq
+	m.hashes[hash] = pq
+
+	return pq, nil
+}
+
+// Get returns the persisted query of the id or hash
+func (m *MemoryQueryStore) Get(ref string) (*PersistedQuery, error) {
+	m.rw.RLock()
+	defer m.rw.RUnlock()
+
+	if pq, ok := m.ids[ref]; ok {
+		return pq, nil
+	}
+
+	if pq, ok := m.hashes[strings.ToLower(ref)]; ok {
+		return pq, nil
+	}
+
+	return nil, fmt.Errorf(PersistedNotFoundMessage, ref)
+}
+
+// IDs returns the sorted ids of the persisted queries
+func (m *MemoryQueryStore) IDs() []string {
+	m.rw.RLock()
+	defer m.rw.RUnlock()
+
+	var ids []string
+
+	for id := range m.ids {
+		ids = append(ids, id)
+	}
+
+	sort.Strings(ids)
+	return ids
+}
+
+// Persisted returns the persisted query sharing the canonical text of the graphs, ErrNotPersisted is returned when there is none
+func Persisted(store QueryStore, graphs []ds.Graphs) (*PersistedQuery, error) {
+	hash, _, err := Hash(graphs)
+
+	if err != nil {
+		return nil, err
+	}
+
+	pq, err := store.Get(hash)
+
+	if err != nil {
+		return nil, ErrNotPersisted
+	}
+
+	return pq, nil
+}
+
+// FileQueryStore provides a QueryStore of the .dq files of a directory, each root record of a file is persisted under the id 'file/record' eg 'users/users'
+type FileQueryStore struct {
+	path    string
+	inspect *parser.InspectionFactory
+	mem     *MemoryQueryStore
+	rw      sync.RWMutex
+}
+
+// NewFileQueryStore returns a new FileQueryStore loaded from the .dq file or directory of .dq files at the path, parser.DefaultInspectionFactory is used when the inspections are nil
+func NewFileQueryStore(path string, inspect *parser.InspectionFactory) (*FileQueryStore, error) {
+	fs := &FileQueryStore{path: path, inspect: inspect}
+
+	if err := fs.Reload(); err != nil {
+		return nil, err
+	}
+
+	return fs, nil
+}
+
+// Reload replaces the persisted queries with those of the files, keeping the previous queries if any file fails to load
+func (f *FileQueryStore) Reload() error {
+	mem := NewMemoryQueryStore(f.inspect)
+
+	files, err := queryFiles(f.path)
+
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		if err := mem.load(file); err != nil {
+			return fmt.Errorf("%s: %s", file, err)
+		}
+	}
+
+	f.rw.Lock()
+	f.mem = mem
+	f.rw.Unlock()
+
+	return nil
+}
+
+// Get returns the persisted query of the id or hash
+func (f *FileQueryStore) Get(ref string) (*PersistedQuery, error) {
+	f.rw.RLock()
+	defer f.rw.RUnlock()
+	return f.mem.Get(ref)
+}
+
+// IDs returns the sorted ids of the persisted queries
+func (f *FileQueryStore) IDs() []string {
+	f.rw.RLock()
+	defer f.rw.RUnlock()
+	return f.mem.IDs()
+}
+
+// load persists each root record of the file
+func (m *MemoryQueryStore) load(file string) error {
+	data, err := ioutil.ReadFile(file)
+
+	if err != nil {
+		return err
+	}
+
+	stem := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+	ps := parser.NewParser(m.inspect)
+
+	var chunks []string
+
+	if err := parser.ScanChunks(parser.NewScanner(bytes.NewBuffer(data)), func(chunk string) {
+		chunks = append(chunks, chunk)
+	}); err != nil {
+		return err
+	}
+
+	for _, chunk := range chunks {
+		gs, err := ps.Scan(bytes.NewBufferString(chunk))
+
+		if err != nil {
+			return err
+		}
+
+		root, err := GetRoot(gs)
+
+		if err != nil {
+			return err
+		}
+
+		if _, err := m.add(stem+"/"+root.(*parser.ParseNode).Name(), []ds.Graphs{gs}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// queryFiles returns the .dq file at the path or the sorted .dq files of the directory
+func queryFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	files, err := filepath.Glob(filepath.Join(path, "*.dq"))
+
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(files)
+	return files, nil
+}
@@ -0,0 +1,381 @@
+package adaptors
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/influx6/data/query/parser"
+	"github.com/influx6/ds"
+	"github.com/influx6/flux"
+)
+
+// ControlKeys are rule keys that configure how a record is retrieved rather than naming one of its fields
+var ControlKeys = []string{"with", "rel", "cache", "strategy", "exists", "notexists"}
+
+// IsControlKey returns true if the rule key is one of the ControlKeys
+func IsControlKey(key string) bool {
+	_, ok := FindMatch(ControlKeys, key)
+	return ok
+}
+
+// Principal defines the identity a query is executed on behalf of
+type Principal struct {
+	ID     string
+	Roles  []string
+	Values map[string]interface{}
+}
+
+// NewPrincipal returns a new Principal instance
+func NewPrincipal(id string, roles ...string) *Principal {
+	return &Principal{
+		ID:     id,
+		Roles:  roles,
+		Values: make(map[string]interface{}),
+	}
+}
+
+// Set sets a value eg a tenant id on the principal for use by row filters
+func (p *Principal) Set(key string, val interface{}) *Principal {
+	p.Values[key] = val
+	return p
+}
+
+// Get returns a value of the principal
+func (p *Principal) Get(key string) (interface{}, bool) {
+	val, ok := p.Values[key]
+	return val, ok
+}
+
+// RowFilterFx returns the value a record's field must equal for the giving principal
+type RowFilterFx func(*Principal) (interface{}, error)
+
+// RowFilter defines a mandatory condition injected into every query of a table
+type RowFilter struct {
+	Field string
+	Value RowFilterFx
+}
+
+// ValueOf returns a RowFilterFx that reads the key from the principal's values
+func ValueOf(key string) RowFilterFx {
+	return func(p *Principal) (interface{}, error) {
+		if val, ok := p.Get(key); ok {
+			return val, nil
+		}
+		return nil, fmt.Errorf("Principal '%s' has no value for '%s'", p.ID, key)
+	}
+}
+
+// TablePolicy defines the access rules for a single table
+type TablePolicy struct {
+	Deny    bool
+	Allow   []string
+	Denied  []string
+	Filters []RowFilter
+}
+
+// AllowsColumn returns true if the column is not denied and,when an allow list exists, is within it
+func (t *TablePolicy) AllowsColumn(col string) bool {
+	if _, denied := FindMatch(t.Denied, col); denied {
+		return false
+	}
+
+	if len(t.Allow) == 0 {
+		return true
+	}
+
+	_, ok := FindMatch(t.Allow, col)
+	return ok
+}
+
+// AuthorizationError provides a custom error for queries rejected by a Policy
+type AuthorizationError struct {
+	Table   string
+	Column  string
+	Message string
+}
+
+// Error returns a string that match the error interface{}
+func (a *AuthorizationError) Error() string {
+	if a.Column == "" {
+		return fmt.Sprintf("Access(%s): Message: %s", a.Table, a.Message)
+	}
+	return fmt.Sprintf("Access(%s): Column: %s -> Message: %s", a.Table, a.Column, a.Message)
+}
+
+// Link defines how a child record joins its parent, Keys holds the [childkey parentkey] pair and Via names the table linking
+// them, if any, with the [childkey parentkey] columns of the via table in ViaKeys
+type Link struct {
+	Keys    []string
+	Via     string
+	ViaKeys []string
+}
+
+// Linker resolves the Link of a child record to its parent when a query omits the 'with' rule, as adaptors that infer relations do
+type Linker interface {
+	Link(child, parent string) (*Link, error)
+}
+
+// ErrNoPrincipal is returned when a policy with row filters is evaluated without a principal
+var ErrNoPrincipal = errors.New("Policy requires a principal for row filters")
+
+// Policy provides a thread-safe set of table and column access rules evaluated against parsed query graphs
+type Policy struct {
+	allowAll bool
+	tables   map[string]*TablePolicy
+	linker   Linker
+	rw       sync.RWMutex
+}
+
+// NewPolicy returns a new Policy, when allowAll is false only tables added through AllowTable can be queried
+func NewPolicy(allowAll bool) *Policy {
+	return &Policy{
+		allowAll: allowAll,
+		tables:   make(map[string]*TablePolicy),
+	}
+}
+
+// table returns the TablePolicy for a table, creating it if it does not exist
+func (p *Policy) table(name string) *TablePolicy {
+	name = strings.ToLower(name)
+
+	tp, ok := p.tables[name]
+
+	if !ok {
+		tp = &TablePolicy{}
+		p.tables[name] = tp
+	}
+
+	return tp
+}
+
+// AllowTable allows the table to be queried,if columns are supplied only those columns may be used
+func (p *Policy) AllowTable(name string, columns ...string) {
+	p.rw.Lock()
+	defer p.rw.Unlock()
+
+	tp := p.table(name)
+	tp.Deny = false
+	tp.Allow = append(tp.Allow, columns...)
+}
+
+// DenyTable denies all queries against the table
+func (p *Policy) DenyTable(name string) {
+	p.rw.Lock()
+	defer p.rw.Unlock()
+
+	p.table(name).Deny = true
+}
+
+// DenyColumns denies the use of the columns of a table, either as fields or within conditions
+func (p *Policy) DenyColumns(name string, columns ...string) {
+	p.rw.Lock()
+	defer p.rw.Unlock()
+
+	tp := p.table(name)
+	tp.Denied = append(tp.Denied, columns...)
+}
+
+// UseLinker checks the keys and via tables of child records joined without a 'with' rule through the Linker, which should resolve
+// relations as the adaptor executing the queries does
+func (p *Policy) UseLinker(l Linker) {
+	p.rw.Lock()
+	defer p.rw.Unlock()
+
+	p.linker = l
+}
+
+// AddRowFilter adds a mandatory 'field = value' condition to every query of the table, where the value is provided by the principal
+func (p *Policy) AddRowFilter(name, field string, fx RowFilterFx) {
+	p.rw.Lock()
+	defer p.rw.Unlock()
+
+	tp := p.table(name)
+	tp.Filters = append(tp.Filters, RowFilter{Field: field, Value: fx})
+}
+
+// Authorize checks every record of the graph against the policy and injects the row filters of the principal as bound conditions
+func (p *Policy) Authorize(pr *Principal, gs ds.Graphs) error {
+	mo, err := DFGraph(gs)
+
+	if err != nil {
+		return err
+	}
+
+	p.rw.RLock()
+	defer p.rw.RUnlock()
+
+	parents := make(map[string]*parser.ParseNode)
+
+	for mo.Next() == nil {
+		node := mo.Node().(*parser.ParseNode)
+		parents[node.Key] = node
+
+		link, err := p.link(node, parents[node.PKey])
+
+		if err != nil {
+			return err
+		}
+
+		//the parent key of the link is a column of the parent's table and is read through the join
+		if link != nil {
+			parent := parents[node.PKey]
+
+			if ptp, ok := p.tables[strings.ToLower(parent.Name())]; ok && !ptp.AllowsColumn(link.Keys[1]) {
+				return &AuthorizationError{Table: parent.Name(), Column: link.Keys[1], Message: "column is not allowed"}
+			}
+		}
+
+		//a via table is read through the join, so it must be allowed as any queried table is
+		if link != nil && link.Via != "" {
+			if err := p.allowsVia(link); err != nil {
+				return err
+			}
+		}
+
+		tp, ok := p.tables[strings.ToLower(node.Name())]
+
+		if !ok && !p.allowAll {
+			return &AuthorizationError{Table: node.Name(), Message: "table is not allowed"}
+		}
+
+		if !ok {
+			continue
+		}
+
+		if tp.Deny {
+			return &AuthorizationError{Table: node.Name(), Message: "table is denied"}
+		}
+
+		var denied string
+
+		check := func(_ []parser.Collector, col string, stop func()) {
+			if tp.AllowsColumn(col) {
+				return
+			}
+			denied = col
+			stop()
+		}
+
+		node.Records.Each(check)
+
+		//control keys only configure retrieval within rules, a field of the same name is still a column
+		if denied == "" {
+			node.Rules.Each(func(co []parser.Collector, col string, stop func()) {
+				if !IsControlKey(col) {
+					check(co, col, stop)
+				}
+			})
+		}
+
+		//the child key of the link is a column of the record's own table
+		if link != nil && denied == "" && !tp.AllowsColumn(link.Keys[0]) {
+			denied = link.Keys[0]
+		}
+
+		//computed fields may only read allowed columns
+		for _, cf := range node.Computed {
+			for _, col := range parser.ExprColumns(cf.Expr) {
+				if denied == "" && !tp.AllowsColumn(col) {
+					denied = col
+				}
+			}
+		}
+
+		if denied != "" {
+			return &AuthorizationError{Table: node.Name(), Column: denied, Message: "column is not allowed"}
+		}
+
+		for _, filter := range tp.Filters {
+			if pr == nil {
+				return ErrNoPrincipal
+			}
+
+			val, err := filter.Value(pr)
+
+			if err != nil {
+				return &AuthorizationError{Table: node.Name(), Column: filter.Field, Message: err.Error()}
+			}
+
+			cond := parser.NewCondition("is")
+			cond.Set("value", parser.Param{Value: val})
+
+			//a row filter replaces any condition the query placed on the same field
+			node.Rules.Set(filter.Field, []parser.Collector{cond})
+		}
+	}
+
+	return nil
+}
+
+// link returns the Link of a child record to its parent, given by its 'with' rule or else resolved through the linker, it returns nil for
+// root records and child records no link is known for
+func (p *Policy) link(node, parent *parser.ParseNode) (*Link, error) {
+	if parent == nil {
+		return nil, nil
+	}
+
+	if keys, ok := Relation(node); ok {
+		return &Link{Keys: keys}, nil
+	}
+
+	if p.linker == nil {
+		return nil, nil
+	}
+
+	link, err := p.linker.Link(node.Name(), parent.Name())
+
+	if err != nil {
+		return nil, node.Report("", err.Error())
+	}
+
+	if len(link.Keys) != 2 || (link.Via != "" && len(link.ViaKeys) != 2) {
+		return nil, node.Report("", fmt.Sprintf("Invalid link between '%s' and its parent '%s'", node.Name(), parent.Name()))
+	}
+
+	return link, nil
+}
+
+// allowsVia returns an AuthorizationError if the via table of the link or its keys are not allowed
+func (p *Policy) allowsVia(link *Link) error {
+	tp, ok := p.tables[strings.ToLower(link.Via)]
+
+	if !ok && !p.allowAll {
+		return &AuthorizationError{Table: link.Via, Message: "table is not allowed"}
+	}
+
+	if !ok {
+		return nil
+	}
+
+	if tp.Deny {
+		return &AuthorizationError{Table: link.Via, Message: "table is denied"}
+	}
+
+	for _, key := range link.ViaKeys {
+		if !tp.AllowsColumn(key) {
+			return &AuthorizationError{Table: link.Via, Column: key, Message: "column is not allowed"}
+		}
+	}
+
+	return nil
+}
+
+// PolicyAdaptor returns a reactor that authorizes each ds.Graphs against the policy for the principal before passing it on
+func PolicyAdaptor(po *Policy, pr *Principal) flux.Reactor {
+	return QueryAdaptor(func(r flux.Reactor, gs ds.Graphs) {
+		if err := po.Authorize(pr, gs); err != nil {
+			r.ReplyError(err)
+			return
+		}
+		r.Reply(gs)
+	})
+}
+
+// PolicyParser returns a Reactor that combines the ChunkParser with the PolicyAdaptor to authorize stringed queries for the principal
+func PolicyParser(ds *parser.InspectionFactory, po *Policy, pr *Principal) flux.Reactor {
+	ms := ChunkParser(ds)
+	ms.Bind(PolicyAdaptor(po, pr), true)
+	return ms
+}
@@ -0,0 +1,241 @@
+package adaptors
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influx6/data/query/parser"
+)
+
+//ScalarNotFoundMessage provides error for functions without an in-memory implementation
+const ScalarNotFoundMessage = "Error: Scalar function '%s' not Found!"
+
+// ScalarFx defines a function type that computes the value of a function call from its evaluated arguments in memory
+type ScalarFx func(args []interface{}) (interface{}, error)
+
+// ScalarFactory provides the in-memory implementations of the functions computed fields call, the counterpart of the sql function handlers
+type ScalarFactory struct {
+	factory map[string]ScalarFx
+	rw      sync.RWMutex
+}
+
+// NewScalarFactory returns a new ScalarFactory instance
+func NewScalarFactory() *ScalarFactory {
+	return &ScalarFactory{factory: make(map[string]ScalarFx)}
+}
+
+// Has returns true/false if the function is implemented
+func (s *ScalarFactory) Has(name string) bool {
+	s.rw.RLock()
+	_, ok := s.factory[strings.ToLower(name)]
+	s.rw.RUnlock()
+	return ok
+}
+
+// Add adds the implementation of a function, replacing any existing one
+func (s *ScalarFactory) Add(name string, fx ScalarFx) {
+	s.rw.Lock()
+	s.factory[strings.ToLower(name)] = fx
+	s.rw.Unlock()
+}
+
+// Call returns the value of the function for the arguments
+func (s *ScalarFactory) Call(name string, args []interface{}) (interface{}, error) {
+	s.rw.RLock()
+	fx, ok := s.factory[strings.ToLower(name)]
+	s.rw.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf(ScalarNotFoundMessage, name)
+	}
+
+	return fx(args)
+}
+
+// Compute returns the value of the expression over the record, arithmetic on a missing or non numeric value and division by zero
+// are nil as they are NULL in sql
+func (s *ScalarFactory) Compute(ex parser.Expr, rec Record) (interface{}, error) {
+	switch mo := ex.(type) {
+	case *parser.ColumnExpr:
+		return Unwrap(rec[mo.Name]), nil
+	case *parser.LiteralExpr:
+		return mo.Value, nil
+	case *parser.CallExpr:
+		args := make([]interface{}, len(mo.Args))
+
+		for i, arg := range mo.Args {
+			val, err := s.Compute(arg, rec)
+
+			if err != nil {
+				return nil, err
+			}
+
+			args[i] = val
+		}
+
+		return s.Call(mo.Func, args)
+	case *parser.BinaryExpr:
+		left, err := s.Compute(mo.Left, rec)
+
+		if err != nil {
+			return nil, err
+		}
+
+		right, err := s.Compute(mo.Right, rec)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return arithmetic(mo.Op, left, right), nil
+	}
+
+	return nil, fmt.Errorf("Unknown expression %s", ex)
+}
+
+// arithmetic returns the result of the operator over two numbers or nil
+func arithmetic(op string, left, right interface{}) interface{} {
+	if left == nil || right == nil {
+		return nil
+	}
+
+	a, ok := ToFloat(left)
+
+	if !ok {
+		return nil
+	}
+
+	b, ok := ToFloat(right)
+
+	if !ok {
+		return nil
+	}
+
+	switch op {
+	case "+":
+		return a + b
+	case "-":
+		return a - b
+	case "*":
+		return a * b
+	case "/":
+		if b == 0 {
+			return nil
+		}
+		return a / b
+	}
+
+	return nil
+}
+
+// scalarText returns the text of a value as concatenated and cased by the string functions
+func scalarText(val interface{}) string {
+	switch mo := val.(type) {
+	case string:
+		return mo
+	case time.Time:
+		return mo.Format("2006-01-02 15:04:05")
+	}
+	return ToString(val)
+}
+
+// TruncTime returns the time truncated to the start of its year, month, day, hour, minute or second
+func TruncTime(tm time.Time, unit string) (time.Time, error) {
+	y, mo, d := tm.Date()
+	h, m, s := tm.Clock()
+
+	switch unit {
+	case "year":
+		return time.Date(y, 1, 1, 0, 0, 0, 0, tm.Location()), nil
+	case "month":
+		return time.Date(y, mo, 1, 0, 0, 0, 0, tm.Location()), nil
+	case "day":
+		return time.Date(y, mo, d, 0, 0, 0, 0, tm.Location()), nil
+	case "hour":
+		return time.Date(y, mo, d, h, 0, 0, 0, tm.Location()), nil
+	case "minute":
+		return time.Date(y, mo, d, h, m, 0, 0, tm.Location()), nil
+	case "second":
+		return time.Date(y, mo, d, h, m, s, 0, tm.Location()), nil
+	}
+
+	return tm, fmt.Errorf("Function 'date_trunc' does not support the unit %q", unit)
+}
+
+//DefaultScalars provides a singleton of the default in-memory scalar functions
+var DefaultScalars = NewScalarFactory()
+
+// AddDefaultScalars adds the lower, upper, concat, coalesce, round and date_trunc functions to the factory, each of them is nil
+// for a nil argument except concat, which skips them, and coalesce
+func AddDefaultScalars(s *ScalarFactory) {
+	s.Add("lower", func(args []interface{}) (interface{}, error) {
+		if args[0] == nil {
+			return nil, nil
+		}
+		return strings.ToLower(scalarText(args[0])), nil
+	})
+
+	s.Add("upper", func(args []interface{}) (interface{}, error) {
+		if args[0] == nil {
+			return nil, nil
+		}
+		return strings.ToUpper(scalarText(args[0])), nil
+	})
+
+	s.Add("concat", func(args []interface{}) (interface{}, error) {
+		var text []string
+
+		for _, arg := range args {
+			if arg != nil {
+				text = append(text, scalarText(arg))
+			}
+		}
+
+		return strings.Join(text, ""), nil
+	})
+
+	s.Add("coalesce", func(args []interface{}) (interface{}, error) {
+		for _, arg := range args {
+			if arg != nil {
+				return arg, nil
+			}
+		}
+		return nil, nil
+	})
+
+	s.Add("round", func(args []interface{}) (interface{}, error) {
+		num, ok := ToFloat(args[0])
+
+		if args[0] == nil || !ok {
+			return nil, nil
+		}
+
+		var places float64
+
+		if len(args) > 1 {
+			if places, ok = ToFloat(args[1]); !ok {
+				return nil, nil
+			}
+		}
+
+		scale := math.Pow(10, math.Trunc(places))
+		return math.Round(num*scale) / scale, nil
+	})
+
+	s.Add("date_trunc", func(args []interface{}) (interface{}, error) {
+		tm, ok := ToTime(args[1])
+
+		if !ok {
+			return nil, nil
+		}
+
+		return TruncTime(tm, scalarText(args[0]))
+	})
+}
+
+func init() {
+	AddDefaultScalars(DefaultScalars)
+}
@@ -0,0 +1,105 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/influx6/data/query/adaptors"
+	"github.com/influx6/data/query/parser"
+	"github.com/influx6/ds"
+)
+
+// Adaptor provides the adaptors.Adaptor of a sql database, its plans are *Statement
+type Adaptor struct {
+	db        *sql.DB
+	op, specs *parser.OPFactory
+	rs        RelationResolver
+	cache     Cache
+	fanout    int
+	parallel  int
+}
+
+// NewAdaptor returns a new Adaptor over the database using the OPFactories, the RelationResolver may be nil to require 'with' rules on child records
+func NewAdaptor(db *sql.DB, rs RelationResolver, op, specs *parser.OPFactory) *Adaptor {
+	return &Adaptor{
+		db:    db,
+		op:    op,
+		specs: specs,
+		rs:    rs,
+	}
+}
+
+// DefaultAdaptor returns a new Adaptor over the database using the default query formatters
+func DefaultAdaptor(db *sql.DB, rs RelationResolver) *Adaptor {
+	return NewAdaptor(db, rs, TemplatesQueries, RelQueries)
+}
+
+// UseCache serves statements with a 'cache' rule from the cache, a nil cache turns caching off
+func (a *Adaptor) UseCache(c Cache) *Adaptor {
+	a.cache = c
+	return a
+}
+
+// BatchAbove batches the child records that would make a statement join more than fanout child records, see PlanBatches
+func (a *Adaptor) BatchAbove(fanout int) *Adaptor {
+	a.fanout = fanout
+	return a
+}
+
+// Parallel runs the sibling batches of each statement with at most limit queries at once on the database pool, see ExecuteConcurrent
+func (a *Adaptor) Parallel(limit int) *Adaptor {
+	a.parallel = limit
+	return a
+}
+
+// Compile returns the *Statement of the graph
+func (a *Adaptor) Compile(gs ds.Graphs) (adaptors.Plan, error) {
+	tables, err := BuildTables(gs, a.op, a.specs, a.rs)
+
+	if err != nil {
+		return nil, err
+	}
+
+	PlanBatches(tables, a.fanout)
+	return BuildStatement(tables), nil
+}
+
+// Execute runs the *Statement of the plan and builds its record tree
+func (a *Adaptor) Execute(ctx context.Context, p adaptors.Plan) (adaptors.Result, error) {
+	stl, ok := p.(*Statement)
+
+	if !ok {
+		return nil, adaptors.ErrInvalidPlan
+	}
+
+	//plans may be executed again, so only rows looked up for this execution count as cached
+	stl.Cached = false
+
+	if a.cache == nil || !LookupStatement(a.cache, stl) {
+		if err := ExecuteConcurrent(ctx, a.db, stl, a.parallel); err != nil {
+			return nil, err
+		}
+
+		if a.cache != nil {
+			StoreStatement(a.cache, stl)
+		}
+	}
+
+	tree, err := BuildJSON(stl)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return adaptors.Result(tree), nil
+}
+
+// Capabilities returns the capabilities of the sql adaptor, child records are always joined
+func (a *Adaptor) Capabilities() adaptors.Capabilities {
+	return adaptors.Capabilities{
+		Joins:      true,
+		Conditions: a.op,
+		Computed:   true,
+		Functions:  functionSet{a.op},
+	}
+}
@@ -0,0 +1,113 @@
+package sql
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/influx6/data/query/adaptors"
+	"github.com/influx6/data/query/parser"
+	"github.com/influx6/flux"
+)
+
+func TestAdaptor(t *testing.T) {
+	reg := adaptors.NewRegistry()
+	reg.Register("sql", DefaultAdaptor(nil, nil))
+
+	en := adaptors.NewEngine(reg, parser.DefaultInspectionFactory)
+
+	graphs, err := adaptors.Parse(parser.DefaultInspectionFactory, `users@sql(id: 1){ name, photos(with: [user_id id]){ url } }`)
+
+	if err != nil || len(graphs) != 1 {
+		flux.FatalFailed(t, "Failed to parse query: %+s", err)
+	}
+
+	name, ad, err := en.Route(graphs[0])
+
+	if err != nil || name != "sql" {
+		flux.FatalFailed(t, "Expected query routed to 'sql': %+s", err)
+	}
+
+	plan, err := ad.Compile(graphs[0])
+
+	if err != nil {
+		flux.FatalFailed(t, "Failed to compile plan: %+s", err)
+	}
+
+	stl, ok := plan.(*Statement)
+
+	if !ok || !strings.Contains(stl.Query, "FROM USERS") || !strings.Contains(stl.Query, ".user_id = ") {
+		flux.FatalFailed(t, "Expected sql statement joining users and photos: %+v", plan)
+	}
+
+	stl.Data = [][]interface{}{{"alex", "a.jpg"}}
+
+	for i := 0; i < 2; i++ {
+		tree, err := BuildJSON(stl)
+
+		if err != nil {
+			flux.FatalFailed(t, "Failed to build json: %+s", err)
+		}
+
+		if users := tree["users"].([]map[string]interface{}); len(users) != 1 {
+			flux.FatalFailed(t, "Expected 1 user on build %d but got %d", i+1, len(users))
+		}
+	}
+
+	flux.LogPassed(t, "Compiled routed query into a reusable sql.Statement")
+}
+
+// records provides an adaptors.Source over a single collection of records
+type records []adaptors.Record
+
+func (r records) Each(name string, fx func(adaptors.Record) error) error {
+	for _, rec := range r {
+		if err := fx(rec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// capturingAdaptor keeps the statements compiled for it instead of executing them
+type capturingAdaptor struct {
+	*Adaptor
+	statements []*Statement
+}
+
+func (c *capturingAdaptor) Execute(ctx context.Context, p adaptors.Plan) (adaptors.Result, error) {
+	c.statements = append(c.statements, p.(*Statement))
+	return adaptors.Result{}, nil
+}
+
+func TestFederatedKeys(t *testing.T) {
+	key := `\' OR 1=1 -- `
+
+	db := &capturingAdaptor{Adaptor: DefaultAdaptor(nil, nil)}
+
+	reg := adaptors.NewRegistry()
+	reg.Register("live", adaptors.NewSourceAdaptor(records{{"id": key, "name": "alex"}, {"id": 2, "name": "josh"}}, nil))
+	reg.Register("db", db)
+
+	en := adaptors.NewEngine(reg, parser.DefaultInspectionFactory)
+
+	if _, err := en.Query(context.Background(), `users@live(){ name, photos@db(with: [user_id id]){ url } }`); err != nil {
+		flux.FatalFailed(t, "Failed to federate query: %+s", err)
+	}
+
+	if len(db.statements) != 1 {
+		flux.FatalFailed(t, "Expected 1 statement for the federated photos but got %d", len(db.statements))
+	}
+
+	stl := db.statements[0]
+
+	if strings.Contains(stl.Query, "OR 1=1") || strings.Count(stl.Query, ".user_id = ?") != 2 {
+		flux.FatalFailed(t, "Expected the parent keys bound as placeholders: %s", stl.Query)
+	}
+
+	if len(stl.Args) != 2 || stl.Args[0] != key || stl.Args[1] != 2 {
+		flux.FatalFailed(t, "Expected the parent keys as the statement's args: %+v", stl.Args)
+	}
+
+	flux.LogPassed(t, "Bound federated parent keys as statement args")
+}
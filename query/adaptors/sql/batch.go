@@ -0,0 +1,352 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/influx6/data/query/adaptors"
+	"github.com/influx6/data/query/parser"
+	"github.com/influx6/flux"
+)
+
+// strategyKey is the rule choosing how a child record is loaded, eg 'photos(with: [user_id id], strategy: batch){...}'
+var strategyKey = "strategy"
+
+const (
+	//JoinStrategy loads a child record within the statement of its parent
+	JoinStrategy = "join"
+	//BatchStrategy loads a child record with a statement of its own for the keys of its parent's rows
+	BatchStrategy = "batch"
+)
+
+//InvalidStrategyMessage provides error for strategy rules naming neither join nor batch
+const InvalidStrategyMessage = "Record '%s' has an unknown strategy '%v', expected join or batch"
+
+//BatchRelationMessage provides error for batched records that can not be looked up by their parent's keys
+const BatchRelationMessage = "Record '%s' is batched and needs a parent record and a 'with: [childkey parentkey]' rule or relation to look it up by"
+
+// keysMarker is replaced with a placeholder for each parent key when a batch statement is executed
+const keysMarker = "{{keys}}"
+
+// strategy returns the validated strategy rule of the record's table
+func strategy(uo *parser.ParseNode, table *Table) (string, error) {
+	co, err := uo.Rules.Get(strategyKey)
+
+	if err != nil || len(co) == 0 {
+		return "", err
+	}
+
+	val := co[0].Get("value")
+
+	switch fmt.Sprintf("%v", val) {
+	case JoinStrategy:
+		return JoinStrategy, nil
+	case BatchStrategy:
+		if table.Parent == "" || len(table.With) < 2 {
+			return "", uo.Report(strategyKey, fmt.Sprintf(BatchRelationMessage, uo.Name()))
+		}
+		return BatchStrategy, nil
+	}
+
+	return "", uo.Report(strategyKey, fmt.Sprintf(InvalidStrategyMessage, uo.Name(), val))
+}
+
+// PlanBatches batches the child records that would make a statement join more than fanout child records which may have many rows per parent.
+// Records with a declared has-one relation, an explicit strategy or no 'with' keys are left joined, a fanout of zero or less changes nothing
+func PlanBatches(tables Tables, fanout int) {
+	if fanout <= 0 {
+		return
+	}
+
+	heads := make(map[string]string)
+	joined := make(map[string]int)
+
+	for _, table := range tables {
+		if table.Parent == "" {
+			heads[table.Key] = table.Key
+			continue
+		}
+
+		head := heads[table.PKey]
+
+		if table.Strategy == "" && table.Kind != HasOne && len(table.With) > 1 && joined[head] >= fanout {
+			table.Strategy = BatchStrategy
+		}
+
+		if table.Strategy == BatchStrategy {
+			heads[table.Key] = table.Key
+			continue
+		}
+
+		heads[table.Key] = head
+
+		if table.Kind != HasOne {
+			joined[head]++
+		}
+	}
+}
+
+// BatchPlanner returns a reactor placed between the table builder and TableParser that batches child records above the fanout, see PlanBatches
+func BatchPlanner(fanout int) flux.Reactor {
+	return flux.Reactive(func(r flux.Reactor, err error, data interface{}) {
+		if err != nil {
+			r.ReplyError(err)
+			return
+		}
+
+		tables, ok := data.(Tables)
+
+		if !ok {
+			r.ReplyError(ErrInvalidTableData)
+			return
+		}
+
+		PlanBatches(tables, fanout)
+		r.Reply(tables)
+	})
+}
+
+// prepareBatch turns the table into the head of its own statement, looked up by an IN over its parent's keys instead of being joined to its parent
+func prepareBatch(table, parent *Table) {
+	conds := []string{fmt.Sprintf("{{table}}.%s IN (%s)", table.With[0], keysMarker)}
+
+	//conditions on the parent table can not be part of a statement without it
+	for _, cond := range table.Conditions {
+		if !strings.Contains(cond, "{{parentTable}}") {
+			conds = append(conds, cond)
+		}
+	}
+
+	table.Conditions = conds
+	table.Columns, table.Hidden = withColumn(table.Columns, table.Hidden, table.With[0])
+	parent.Columns, parent.Hidden = withColumn(parent.Columns, parent.Hidden, table.With[1])
+}
+
+// withColumn returns the columns with the column added as hidden when it is not selected already
+func withColumn(columns, hidden []string, column string) ([]string, []string) {
+	if _, ok := adaptors.FindMatch(columns, column); ok {
+		return columns, hidden
+	}
+
+	return append(columns, column), append(hidden, column)
+}
+
+// executeBatches runs the statement of each batch for the keys found in the rows of the statement, DefaultBatchSize keys at a time.
+// With a slots channel sibling batches run at once and each query waits for a free slot, otherwise everything runs in order
+func executeBatches(ctx context.Context, db *sql.DB, stl *Statement, slots chan struct{}) error {
+	limit := 1
+
+	if slots != nil {
+		limit = len(stl.Batches)
+	}
+
+	return adaptors.Parallel(ctx, limit, len(stl.Batches), func(ctx context.Context, index int) error {
+		batch := stl.Batches[index]
+		keys := batchKeys(stl, batch)
+		batch.Data = nil
+
+		for begin := 0; begin < len(keys); begin += adaptors.DefaultBatchSize {
+			end := begin + adaptors.DefaultBatchSize
+
+			if end > len(keys) {
+				end = len(keys)
+			}
+
+			marks := strings.TrimSuffix(strings.Repeat("?, ", end-begin), ", ")
+			query := Rebind(strings.Replace(batch.Query, keysMarker, marks, 1), batch.Placeholder)
+			args := append(append([]interface{}{}, keys[begin:end]...), batch.Args...)
+
+			rows, err := slotRows(ctx, db, slots, query, args, batch.Columns)
+
+			if err != nil {
+				return err
+			}
+
+			batch.Data = append(batch.Data, rows...)
+		}
+
+		return executeBatches(ctx, db, batch, slots)
+	})
+}
+
+// slotRows runs the query once a slot is free, slots are only held while the query runs so nested batches can not starve their parents
+func slotRows(ctx context.Context, db *sql.DB, slots chan struct{}, query string, args []interface{}, columns int) ([][]interface{}, error) {
+	if slots != nil {
+		select {
+		case slots <- struct{}{}:
+			defer func() { <-slots }()
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	return queryRows(ctx, db, query, args, columns)
+}
+
+// batchKeys returns the distinct parent key values of the batch within the rows of the statement
+func batchKeys(stl *Statement, batch *Statement) []interface{} {
+	info, ok := stl.Tables[batch.Parent]
+
+	if !ok {
+		return nil
+	}
+
+	col, ok := adaptors.FindMatch(info.Columns, batch.With[1])
+
+	if !ok {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+
+	var keys []interface{}
+
+	for _, row := range stl.Data {
+		val := row[info.Begin+col]
+
+		if val == nil {
+			continue
+		}
+
+		if key := adaptors.KeyOf(val); !seen[key] {
+			seen[key] = true
+			keys = append(keys, val)
+		}
+	}
+
+	return keys
+}
+
+// root returns the table of the statement whose parent is not part of it
+func (s *Statement) root() *TableInfo {
+	for _, info := range s.Tables {
+		if _, ok := s.Tables[info.ParentAlias]; info.ParentAlias == "" || !ok {
+			return info
+		}
+	}
+	return nil
+}
+
+// stitchBatch nests the records of the batch's tree under the records of its parent in the statement's tree, as a list or a single record for has-one relations
+func stitchBatch(stl *Statement, tree map[string]interface{}, batch *Statement, sub map[string]interface{}) {
+	head := batch.root()
+
+	if head == nil {
+		return
+	}
+
+	children := sectionsOf(sub[head.Name])
+	groups := make(map[string][]map[string]interface{})
+
+	for _, rec := range children {
+		if val := rec[batch.With[0]]; val != nil {
+			key := adaptors.KeyOf(val)
+			groups[key] = append(groups[key], rec)
+		}
+	}
+
+	if _, ok := adaptors.FindMatch(head.Hidden, batch.With[0]); ok {
+		for _, rec := range children {
+			delete(rec, batch.With[0])
+		}
+	}
+
+	for _, rec := range tableRecords(stl, tree, batch.Parent) {
+		var group []map[string]interface{}
+
+		if val := rec[batch.With[1]]; val != nil {
+			group = groups[adaptors.KeyOf(val)]
+		}
+
+		if head.Kind == HasOne {
+			if len(group) > 0 {
+				rec[head.Name] = group[0]
+			}
+			continue
+		}
+
+		if group == nil {
+			group = []map[string]interface{}{}
+		}
+
+		rec[head.Name] = group
+	}
+}
+
+// hideColumns removes the columns selected only to stitch batches from the records of the statement's tables, the key of a batch's head
+// is kept for its parent statement to group by
+func hideColumns(stl *Statement, tree map[string]interface{}) {
+	head := stl.root()
+
+	for _, info := range stl.Tables {
+		for _, column := range info.Hidden {
+			if info == head && len(stl.With) > 1 && column == stl.With[0] {
+				continue
+			}
+
+			for _, rec := range tableRecords(stl, tree, info.Alias) {
+				delete(rec, column)
+			}
+		}
+	}
+}
+
+// tableRecords returns the records of the aliased table within the statement's tree
+func tableRecords(stl *Statement, tree map[string]interface{}, alias string) []map[string]interface{} {
+	var path []string
+
+	info, ok := stl.Tables[alias]
+
+	if !ok {
+		return nil
+	}
+
+	for {
+		parent, ok := stl.Tables[info.ParentAlias]
+
+		if info.ParentAlias == "" || !ok {
+			break
+		}
+
+		path = append([]string{info.Name}, path...)
+		info = parent
+	}
+
+	recs := sectionsOf(tree[info.Name])
+
+	for _, step := range path {
+		var next []map[string]interface{}
+
+		for _, rec := range recs {
+			next = append(next, sectionsOf(rec[step])...)
+		}
+
+		recs = next
+	}
+
+	return recs
+}
+
+// sectionsOf returns the records held by a value of a record tree
+func sectionsOf(val interface{}) []map[string]interface{} {
+	switch mo := val.(type) {
+	case []map[string]interface{}:
+		return mo
+	case map[string]interface{}:
+		return []map[string]interface{}{mo}
+	case TableSection:
+		return []map[string]interface{}{mo}
+	case []TableSection:
+		recs := make([]map[string]interface{}, len(mo))
+
+		for index, section := range mo {
+			recs[index] = section
+		}
+
+		return recs
+	}
+
+	return nil
+}
@@ -0,0 +1,189 @@
+package sql
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/influx6/data/query/adaptors"
+	"github.com/influx6/data/query/parser"
+	"github.com/influx6/flux"
+)
+
+func TestBatchStrategy(t *testing.T) {
+	stl := compileStatement(t, DefaultAdaptor(nil, nil), `users(id: 1){
+		name,
+		photos(with: [user_id id], strategy: batch){ url }
+	}`)
+
+	if len(stl.Batches) != 1 || strings.Contains(stl.Query, "PHOTOS") || strings.Contains(stl.Query, "strategy") {
+		flux.FatalFailed(t, "Expected photos split from the users statement: %s", stl.Query)
+	}
+
+	batch := stl.Batches[0]
+
+	if batch.Parent != stl.Tables.Named("users")[0].Alias || !strings.Contains(batch.Query, ".user_id IN ({{keys}})") || strings.Contains(batch.Query, "USERS") {
+		flux.FatalFailed(t, "Expected photos looked up by user_id: %s", batch.Query)
+	}
+
+	if !strings.Contains(stl.Query, ".id") || stl.Columns != 2 || batch.Columns != 2 {
+		flux.FatalFailed(t, "Expected hidden key columns selected for stitching: %s / %s", stl.Query, batch.Query)
+	}
+
+	stl.Data = [][]interface{}{{"alex", 1}, {"josh", 2}, {"kate", 1}}
+
+	if keys := batchKeys(stl, batch); len(keys) != 2 {
+		flux.FatalFailed(t, "Expected 2 distinct parent keys: %+v", keys)
+	}
+
+	batch.Data = [][]interface{}{{"sock.jpg", 1}, {"winnie.jpg", 1}}
+
+	tree, err := BuildJSON(stl)
+
+	if err != nil {
+		flux.FatalFailed(t, "Failed to build json: %+s", err)
+	}
+
+	users := sectionsOf(tree["users"])
+
+	if len(users) != 3 {
+		flux.FatalFailed(t, "Expected 3 users: %+v", tree)
+	}
+
+	if _, ok := users[0]["id"]; ok {
+		flux.FatalFailed(t, "Expected hidden id column removed: %+v", users[0])
+	}
+
+	if photos := users[0]["photos"].([]map[string]interface{}); len(photos) != 2 || photos[0]["url"] != "sock.jpg" || photos[0]["user_id"] != nil {
+		flux.FatalFailed(t, "Expected alex's 2 photos without their user_id: %+v", users[0])
+	}
+
+	if photos := users[1]["photos"].([]map[string]interface{}); len(photos) != 0 {
+		flux.FatalFailed(t, "Expected josh to have no photos: %+v", users[1])
+	}
+
+	flux.LogPassed(t, "Loaded batched child records with their own statement and stitched them to their parents")
+}
+
+func TestBatchFanOut(t *testing.T) {
+	stl := compileStatement(t, DefaultAdaptor(nil, nil).BatchAbove(1), `users(id: 1){
+		name,
+		photos(with: [user_id id]){ url },
+		posts(with: [user_id id]){
+			title,
+			comments(with: [post_id id]){ body }
+		}
+	}`)
+
+	if len(stl.Batches) != 1 || stl.Batches[0].Parent != stl.Tables.Named("users")[0].Alias {
+		flux.FatalFailed(t, "Expected posts batched beyond a fanout of 1: %+v", stl.Batches)
+	}
+
+	posts := stl.Batches[0]
+
+	if len(posts.Tables.Named("comments")) != 1 || len(posts.Batches) != 0 {
+		flux.FatalFailed(t, "Expected comments joined within the posts batch: %+v", posts.Tables)
+	}
+
+	if queries := BatchQueries(stl); len(queries) != 1 || !strings.Contains(queries[0], ".user_id IN (?)") || !strings.Contains(queries[0], "COMMENTS") {
+		flux.FatalFailed(t, "Expected a batch query of posts with their comments: %+v", queries)
+	}
+
+	graphs, err := adaptors.Parse(parser.DefaultInspectionFactory, `users(strategy: batch){ name }`)
+
+	if err != nil {
+		flux.FatalFailed(t, "Failed to parse query: %+s", err)
+	}
+
+	if _, err := DefaultAdaptor(nil, nil).Compile(graphs[0]); err == nil {
+		flux.FatalFailed(t, "Expected batched root record to fail")
+	}
+
+	flux.LogPassed(t, "Batched child records above the fanout threshold")
+}
+
+func TestRepeatedTables(t *testing.T) {
+	stl := compileStatement(t, DefaultAdaptor(nil, nil), `users(id: 1){
+		name,
+		photos(with: [user_id id]){ url },
+		albums(with: [user_id id]){
+			title,
+			photos(with: [album_id id]){ url }
+		}
+	}`)
+
+	photos := stl.Tables.Named("photos")
+
+	if len(stl.Tables) != 4 || len(photos) != 2 {
+		flux.FatalFailed(t, "Expected both photos tables within the statement: %+v", stl.Tables)
+	}
+
+	//fill each column with the alias of its table to tell the photos tables apart
+	row := make([]interface{}, stl.Columns)
+
+	for _, info := range stl.Tables {
+		for index := range info.Columns {
+			row[info.Begin+index] = info.Alias
+		}
+	}
+
+	stl.Data = [][]interface{}{row}
+
+	tree, err := BuildJSON(stl)
+
+	if err != nil {
+		flux.FatalFailed(t, "Failed to build json: %+s", err)
+	}
+
+	users := sectionsOf(tree["users"])
+
+	if len(users) != 1 {
+		flux.FatalFailed(t, "Expected 1 user: %+v", tree)
+	}
+
+	albums := sectionsOf(users[0]["albums"])
+	own, albumed := sectionsOf(users[0]["photos"]), []map[string]interface{}{}
+
+	if len(albums) == 1 {
+		albumed = sectionsOf(albums[0]["photos"])
+	}
+
+	if len(own) != 1 || len(albumed) != 1 || own[0]["url"] == albumed[0]["url"] {
+		flux.FatalFailed(t, "Expected the user's photos and the album's photos from their own columns: %+v", tree)
+	}
+
+	for _, info := range photos {
+		url := own[0]["url"]
+
+		if info.ParentAlias != stl.Tables.Named("users")[0].Alias {
+			url = albumed[0]["url"]
+		}
+
+		if url != info.Alias {
+			flux.FatalFailed(t, "Expected photos of %q read from its own columns but got %v", info.ParentAlias, url)
+		}
+	}
+
+	flux.LogPassed(t, "Built the records of tables queried twice from their own columns")
+
+	stl = compileStatement(t, DefaultAdaptor(nil, nil), `users(id: 1){
+		id,
+		users(with: [manager_id id]){
+			id,
+			posts(with: [user_id id], strategy: batch){ title }
+		}
+	}`)
+
+	managers := stl.Tables.Named("users")
+
+	if len(managers) != 2 || len(stl.Batches) != 1 || stl.Batches[0].Parent != managers[1].Alias {
+		flux.FatalFailed(t, "Expected posts batched by the joined users: %+v", stl.Tables)
+	}
+
+	stl.Data = [][]interface{}{{1, 10}, {1, 11}}
+
+	if keys := batchKeys(stl, stl.Batches[0]); len(keys) != 2 || keys[0] != 10 || keys[1] != 11 {
+		flux.FatalFailed(t, "Expected the keys of the joined users but got %+v", keys)
+	}
+
+	flux.LogPassed(t, "Read the keys of a batch from its parent's columns in a self join")
+}
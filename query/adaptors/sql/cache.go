@@ -0,0 +1,251 @@
+package sql
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influx6/flux"
+)
+
+// cacheKey is the rule giving how long the rows of a record's statement may be served from a Cache eg 'users(cache: 60s){...}'
+var cacheKey = "cache"
+
+//InvalidCacheMessage provides error for cache rules whose value is not a positive duration
+const InvalidCacheMessage = "Record '%s' has an invalid cache duration '%v', expected a duration such as 30s or 5m"
+
+// Cache defines a store of statement rows keyed by their sql and args, remembering the tables each entry was read from so they can be invalidated by name
+type Cache interface {
+	Get(key string) ([][]interface{}, bool)
+	Set(key string, rows [][]interface{}, ttl time.Duration, tables []string)
+	Invalidate(tables ...string)
+}
+
+// CacheKey returns the key of the statement's rows, statements share a key only when both their sql and args are equal
+func CacheKey(stl *Statement) string {
+	sum := sha256.New()
+	fmt.Fprintf(sum, "%s\x00%#v", stl.Query, stl.Args)
+	return hex.EncodeToString(sum.Sum(nil))
+}
+
+// TableNames returns the sorted lowercase names of the statement's tables
+func (s *Statement) TableNames() []string {
+	var names []string
+
+	for _, info := range s.Tables {
+		names = append(names, strings.ToLower(info.Name))
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// cacheEntry defines the rows of a statement until they expire
+type cacheEntry struct {
+	rows    [][]interface{}
+	expires time.Time
+	tables  []string
+}
+
+// MemoryCache provides an in-memory Cache whose entries are dropped when they expire or any of their tables is invalidated
+type MemoryCache struct {
+	//Now returns the current time, time.Now is used when nil
+	Now     func() time.Time
+	entries map[string]*cacheEntry
+	tables  map[string]map[string]bool
+	rw      sync.RWMutex
+}
+
+// NewMemoryCache returns a new MemoryCache instance
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{
+		entries: make(map[string]*cacheEntry),
+		tables:  make(map[string]map[string]bool),
+	}
+}
+
+// now returns the current time of the cache
+func (m *MemoryCache) now() time.Time {
+	if m.Now != nil {
+		return m.Now()
+	}
+	return time.Now()
+}
+
+// Get returns the rows of the key if they have not expired
+func (m *MemoryCache) Get(key string) ([][]interface{}, bool) {
+	m.rw.RLock()
+	entry, ok := m.entries[key]
+	m.rw.RUnlock()
+
+	if !ok {
+		return nil, false
+	}
+
+	if !m.now().Before(entry.expires) {
+		m.rw.Lock()
+		if m.entries[key] == entry {
+			m.remove(key)
+		}
+		m.rw.Unlock()
+		return nil, false
+	}
+
+	return entry.rows, true
+}
+
+// Set stores the rows of the key for the ttl, a ttl of zero or less stores nothing
+func (m *MemoryCache) Set(key string, rows [][]interface{}, ttl time.Duration, tables []string) {
+	if ttl <= 0 {
+		return
+	}
+
+	m.rw.Lock()
+	defer m.rw.Unlock()
+
+	m.remove(key)
+	m.entries[key] = &cacheEntry{rows: rows, expires: m.now().Add(ttl), tables: tables}
+
+	for _, table := range tables {
+		table = strings.ToLower(table)
+
+		if m.tables[table] == nil {
+			m.tables[table] = make(map[string]bool)
+		}
+
+		m.tables[table][key] = true
+	}
+}
+
+// Invalidate drops every entry read from any of the tables
+func (m *MemoryCache) Invalidate(tables ...string) {
+	m.rw.Lock()
+	defer m.rw.Unlock()
+
+	for _, table := range tables {
+		for key := range m.tables[strings.ToLower(table)] {
+			m.remove(key)
+		}
+	}
+}
+
+// Len returns the number of entries held by the cache, including expired entries not yet dropped
+func (m *MemoryCache) Len() int {
+	m.rw.RLock()
+	defer m.rw.RUnlock()
+	return len(m.entries)
+}
+
+// remove drops the entry of the key and its table references, the write lock must be held
+func (m *MemoryCache) remove(key string) {
+	entry, ok := m.entries[key]
+
+	if !ok {
+		return
+	}
+
+	delete(m.entries, key)
+
+	for _, table := range entry.tables {
+		table = strings.ToLower(table)
+		delete(m.tables[table], key)
+
+		if len(m.tables[table]) == 0 {
+			delete(m.tables, table)
+		}
+	}
+}
+
+// LookupStatement fills the statement's Data from the cache when it has a TTL and its rows are cached, returning true on a hit
+func LookupStatement(c Cache, stl *Statement) bool {
+	if stl.TTL <= 0 {
+		return false
+	}
+
+	if !lookupRows(c, CacheKey(stl), stl) {
+		return false
+	}
+
+	stl.Cached = true
+	return true
+}
+
+// lookupRows fills the Data of the statement and its batches from the cache, batches are keyed by their position under their parent's key
+func lookupRows(c Cache, key string, stl *Statement) bool {
+	rows, ok := c.Get(key)
+
+	if !ok {
+		return false
+	}
+
+	for index, batch := range stl.Batches {
+		if !lookupRows(c, fmt.Sprintf("%s/%d", key, index), batch) {
+			return false
+		}
+	}
+
+	stl.Data = rows
+	return true
+}
+
+// StoreStatement caches the rows of an executed statement for its TTL, statements served from the cache or without a TTL are skipped
+func StoreStatement(c Cache, stl *Statement) {
+	if stl.TTL <= 0 || stl.Cached {
+		return
+	}
+
+	storeRows(c, CacheKey(stl), stl, stl.TTL)
+}
+
+// storeRows caches the Data of the statement and its batches for the ttl
+func storeRows(c Cache, key string, stl *Statement, ttl time.Duration) {
+	c.Set(key, stl.Data, ttl, stl.TableNames())
+
+	for index, batch := range stl.Batches {
+		storeRows(c, fmt.Sprintf("%s/%d", key, index), batch, ttl)
+	}
+}
+
+// CacheLookup returns a reactor placed between TableParser and DbExecutor that serves statements from the cache, DbExecutor skips the statements it fills
+func CacheLookup(c Cache) flux.Reactor {
+	return flux.Reactive(func(r flux.Reactor, err error, d interface{}) {
+		if err != nil {
+			r.ReplyError(err)
+			return
+		}
+
+		stl, ok := d.(*Statement)
+
+		if !ok {
+			r.ReplyError(ErrInvalidStatementType)
+			return
+		}
+
+		LookupStatement(c, stl)
+		r.Reply(stl)
+	})
+}
+
+// CacheStore returns a reactor placed after DbExecutor that caches the rows of executed statements for their TTL
+func CacheStore(c Cache) flux.Reactor {
+	return flux.Reactive(func(r flux.Reactor, err error, d interface{}) {
+		if err != nil {
+			r.ReplyError(err)
+			return
+		}
+
+		stl, ok := d.(*Statement)
+
+		if !ok {
+			r.ReplyError(ErrInvalidStatementType)
+			return
+		}
+
+		StoreStatement(c, stl)
+		r.Reply(stl)
+	})
+}
@@ -0,0 +1,109 @@
+package sql
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influx6/data/query/adaptors"
+	"github.com/influx6/data/query/parser"
+	"github.com/influx6/flux"
+)
+
+func compileStatement(t *testing.T, ad *Adaptor, query string) *Statement {
+	graphs, err := adaptors.Parse(parser.DefaultInspectionFactory, query)
+
+	if err != nil {
+		flux.FatalFailed(t, "Failed to parse query: %+s", err)
+	}
+
+	plan, err := ad.Compile(graphs[0])
+
+	if err != nil {
+		flux.FatalFailed(t, "Failed to compile query: %+s", err)
+	}
+
+	return plan.(*Statement)
+}
+
+func TestCache(t *testing.T) {
+	now := time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)
+	cache := NewMemoryCache()
+	cache.Now = func() time.Time { return now }
+
+	//a nil database fails any statement that is not served from the cache
+	ad := DefaultAdaptor(nil, nil).UseCache(cache)
+
+	stl := compileStatement(t, ad, `users(cache: 60s, id: 1){ name, photos(cache: 30s, with: [user_id id]){ url } }`)
+
+	if stl.TTL != 30*time.Second || strings.Contains(stl.Query, "cache") {
+		flux.FatalFailed(t, "Expected the shortest ttl and no cache condition: %s %s", stl.TTL, stl.Query)
+	}
+
+	if names := stl.TableNames(); len(names) != 2 || names[0] != "photos" || names[1] != "users" {
+		flux.FatalFailed(t, "Expected photos and users tables: %+v", names)
+	}
+
+	cache.Set(CacheKey(stl), [][]interface{}{{"alex", "./images/sock.jpg"}}, stl.TTL, stl.TableNames())
+
+	res, err := ad.Execute(context.Background(), stl)
+
+	if err != nil || !stl.Cached {
+		flux.FatalFailed(t, "Expected statement served from the cache: %+s", err)
+	}
+
+	users, _ := adaptors.Plain(res["users"]).([]interface{})
+
+	if len(users) != 1 || users[0].(map[string]interface{})["name"] != "alex" {
+		flux.FatalFailed(t, "Expected cached user record: %+v", res)
+	}
+
+	other := compileStatement(t, ad, `users(cache: 60s, id: 2){ name, photos(with: [user_id id]){ url } }`)
+
+	if CacheKey(other) == CacheKey(stl) {
+		flux.FatalFailed(t, "Expected statements with different args to have different keys")
+	}
+
+	now = now.Add(31 * time.Second)
+
+	if _, ok := cache.Get(CacheKey(stl)); ok || cache.Len() != 0 {
+		flux.FatalFailed(t, "Expected expired entry to be dropped")
+	}
+
+	cache.Set(CacheKey(stl), stl.Data, stl.TTL, stl.TableNames())
+	cache.Set(CacheKey(other), stl.Data, time.Minute, []string{"users"})
+	cache.Invalidate("PHOTOS")
+
+	if _, ok := cache.Get(CacheKey(stl)); ok {
+		flux.FatalFailed(t, "Expected entry reading photos to be invalidated")
+	}
+
+	if _, ok := cache.Get(CacheKey(other)); !ok {
+		flux.FatalFailed(t, "Expected entry reading only users to be kept")
+	}
+
+	flux.LogPassed(t, "Cached statements by sql and args with ttl expiry and table invalidation")
+}
+
+func TestCacheRule(t *testing.T) {
+	stl := compileStatement(t, DefaultAdaptor(nil, nil), `users(id: 1){ name }`)
+
+	if stl.TTL != 0 {
+		flux.FatalFailed(t, "Expected no ttl without a cache rule: %s", stl.TTL)
+	}
+
+	graphs, err := adaptors.Parse(parser.DefaultInspectionFactory, `users(cache: soon){ name }`)
+
+	if err != nil {
+		flux.FatalFailed(t, "Failed to parse query: %+s", err)
+	}
+
+	if _, err := DefaultAdaptor(nil, nil).Compile(graphs[0]); err == nil {
+		flux.FatalFailed(t, "Expected invalid cache duration to fail")
+	} else if _, ok := err.(*parser.PositionError); !ok {
+		flux.FatalFailed(t, "Expected positioned error: %+s", err)
+	}
+
+	flux.LogPassed(t, "Rejected invalid cache durations")
+}
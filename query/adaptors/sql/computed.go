@@ -0,0 +1,245 @@
+package sql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/influx6/data/query/adaptors"
+	"github.com/influx6/data/query/parser"
+)
+
+// literalTag is the OPFactory tag of the handler writing the literals of computed fields in the dialect
+const literalTag = "literal"
+
+// divideTag is the OPFactory tag of the handler writing the division of computed fields in the dialect, which receives the sql of
+// both operands under "params"
+const divideTag = "divide"
+
+// ComputedColumn defines the sql of a computed field of a table, where the table's own columns are written as {{table}}.column
+type ComputedColumn struct {
+	Name string
+	SQL  string
+}
+
+// FunctionTag returns the OPFactory tag of the handler of a function, handlers receive the sql of each argument under "params"
+// and the parsed arguments under "exprs"
+func FunctionTag(name string) string {
+	return "func:" + strings.ToLower(name)
+}
+
+// functionSet reports the functions of an OPFactory as an adaptors.ConditionSet
+type functionSet struct {
+	op *parser.OPFactory
+}
+
+// Has returns true if the OPFactory holds a handler for the function
+func (f functionSet) Has(name string) bool {
+	return f.op.Has(FunctionTag(name))
+}
+
+// truncFormats are the MySQL and SQLite formats of the date_trunc units, SQLite uses %M for minutes where MySQL uses %i
+var truncFormats = map[string][2]string{
+	"year":   {"%Y-01-01 00:00:00", "%Y-01-01 00:00:00"},
+	"month":  {"%Y-%m-01 00:00:00", "%Y-%m-01 00:00:00"},
+	"day":    {"%Y-%m-%d 00:00:00", "%Y-%m-%d 00:00:00"},
+	"hour":   {"%Y-%m-%d %H:00:00", "%Y-%m-%d %H:00:00"},
+	"minute": {"%Y-%m-%d %H:%i:00", "%Y-%m-%d %H:%M:00"},
+	"second": {"%Y-%m-%d %H:%i:%s", "%Y-%m-%d %H:%M:%S"},
+}
+
+// AddFunctionHandlers adds the lower, upper, concat, coalesce, round and date_trunc functions of the dialect to the OPFactory with the
+// handler writing literals, strings are inlined as quoted literals rather than bound so they keep their type within function calls
+func AddFunctionHandlers(op *parser.OPFactory, d Dialect) {
+	quote := strings.NewReplacer("'", "''")
+
+	if d == MySQL {
+		quote = strings.NewReplacer("'", "''", `\`, `\\`)
+	}
+
+	op.Add(literalTag, func(_ string, c parser.Collector) ([]string, error) {
+		switch mo := c.Get("value").(type) {
+		case nil:
+			return []string{"NULL"}, nil
+		case float64:
+			return []string{strconv.FormatFloat(mo, 'f', -1, 64)}, nil
+		case bool:
+			if d == SQLite {
+				return []string{map[bool]string{true: "1", false: "0"}[mo]}, nil
+			}
+			return []string{strings.ToUpper(strconv.FormatBool(mo))}, nil
+		case string:
+			return []string{"'" + quote.Replace(mo) + "'"}, nil
+		}
+
+		return nil, ErrNoValue
+	})
+
+	//division is by a float so integers divide as they do in memory, and by zero is NULL rather than an error
+	switch d {
+	case Postgres:
+		op.Add(divideTag, operandsHandler("(CAST(%s AS NUMERIC) / NULLIF(%s, 0))"))
+	case SQLite:
+		op.Add(divideTag, operandsHandler("(CAST(%s AS REAL) / NULLIF(%s, 0))"))
+	default:
+		op.Add(divideTag, operandsHandler("(%s / NULLIF(%s, 0))"))
+	}
+
+	op.Add(FunctionTag("lower"), callHandler("LOWER(%s)"))
+	op.Add(FunctionTag("upper"), callHandler("UPPER(%s)"))
+	op.Add(FunctionTag("coalesce"), callHandler("COALESCE(%s)"))
+
+	//concat skips null arguments in every dialect
+	switch d {
+	case Postgres:
+		op.Add(FunctionTag("concat"), callHandler("CONCAT(%s)"))
+		op.Add(FunctionTag("round"), func(_ string, c parser.Collector) ([]string, error) {
+			params, _ := c.Get("params").([]string)
+			params[0] = fmt.Sprintf("CAST(%s AS NUMERIC)", params[0])
+			return []string{"ROUND(" + strings.Join(params, ", ") + ")"}, nil
+		})
+	case SQLite:
+		op.Add(FunctionTag("concat"), func(_ string, c parser.Collector) ([]string, error) {
+			var parts []string
+
+			params, _ := c.Get("params").([]string)
+
+			for _, param := range params {
+				parts = append(parts, "COALESCE("+param+", '')")
+			}
+
+			return []string{"(" + strings.Join(parts, " || ") + ")"}, nil
+		})
+		op.Add(FunctionTag("round"), callHandler("ROUND(%s)"))
+	default:
+		op.Add(FunctionTag("concat"), callHandler("CONCAT_WS('', %s)"))
+		op.Add(FunctionTag("round"), callHandler("ROUND(%s)"))
+	}
+
+	op.Add(FunctionTag("date_trunc"), func(_ string, c parser.Collector) ([]string, error) {
+		params, _ := c.Get("params").([]string)
+		exprs, _ := c.Get("exprs").([]parser.Expr)
+
+		unit := ""
+
+		if lit, ok := exprs[0].(*parser.LiteralExpr); ok {
+			unit, _ = lit.Value.(string)
+		}
+
+		formats, ok := truncFormats[unit]
+
+		if !ok {
+			return nil, fmt.Errorf("Function 'date_trunc' does not support the unit %q", unit)
+		}
+
+		switch d {
+		case Postgres:
+			return []string{fmt.Sprintf("DATE_TRUNC(%s, %s)", params[0], params[1])}, nil
+		case SQLite:
+			return []string{"strftime('" + formats[1] + "', " + params[1] + ")"}, nil
+		}
+
+		return []string{"CAST(DATE_FORMAT(" + params[1] + ", '" + formats[0] + "') AS DATETIME)"}, nil
+	})
+}
+
+// callHandler returns a handler writing the comma separated sql of the arguments into the template
+func callHandler(template string) parser.ParseFx {
+	return func(_ string, c parser.Collector) ([]string, error) {
+		params, _ := c.Get("params").([]string)
+		return []string{fmt.Sprintf(template, strings.Join(params, ", "))}, nil
+	}
+}
+
+// operandsHandler returns a handler writing the sql of the left and right operands into the template
+func operandsHandler(template string) parser.ParseFx {
+	return func(_ string, c parser.Collector) ([]string, error) {
+		params, _ := c.Get("params").([]string)
+
+		if len(params) != 2 {
+			return nil, ErrNoValue
+		}
+
+		return []string{fmt.Sprintf(template, params[0], params[1])}, nil
+	}
+}
+
+// ExpressionSQL returns the sql of a computed field's expression through the function and literal handlers of the OPFactory
+func ExpressionSQL(op *parser.OPFactory, ex parser.Expr) (string, error) {
+	switch mo := ex.(type) {
+	case *parser.ColumnExpr:
+		return "{{table}}." + mo.Name, nil
+	case *parser.LiteralExpr:
+		return processOne(op, literalTag, parser.Collector{"type": literalTag, "value": mo.Value})
+	case *parser.BinaryExpr:
+		left, err := ExpressionSQL(op, mo.Left)
+
+		if err != nil {
+			return "", err
+		}
+
+		right, err := ExpressionSQL(op, mo.Right)
+
+		if err != nil {
+			return "", err
+		}
+
+		if mo.Op == "/" && op.Has(divideTag) {
+			return processOne(op, divideTag, parser.Collector{"type": divideTag, "params": []string{left, right}})
+		}
+
+		return fmt.Sprintf("(%s %s %s)", left, mo.Op, right), nil
+	case *parser.CallExpr:
+		if !op.Has(FunctionTag(mo.Func)) {
+			return "", fmt.Errorf(adaptors.UnsupportedFunctionMessage, "sql", mo.Func)
+		}
+
+		var params []string
+
+		for _, arg := range mo.Args {
+			param, err := ExpressionSQL(op, arg)
+
+			if err != nil {
+				return "", err
+			}
+
+			params = append(params, param)
+		}
+
+		return processOne(op, FunctionTag(mo.Func), parser.Collector{"type": FunctionTag(mo.Func), "params": params, "exprs": mo.Args})
+	}
+
+	return "", fmt.Errorf("Unknown expression %s", ex)
+}
+
+// processOne returns the single sql fragment of a handler
+func processOne(op *parser.OPFactory, tag string, c parser.Collector) (string, error) {
+	co, err := op.Process(tag, tag, c)
+
+	if err != nil {
+		return "", err
+	}
+
+	if len(co) != 1 {
+		return "", ErrNoValue
+	}
+
+	return co[0], nil
+}
+
+// computedColumns returns the sql of the node's computed fields
+func computedColumns(op *parser.OPFactory, node *parser.ParseNode) ([]ComputedColumn, error) {
+	var cols []ComputedColumn
+
+	for _, cf := range node.Computed {
+		sq, err := ExpressionSQL(op, cf.Expr)
+
+		if err != nil {
+			return nil, node.Report(cf.Name, err.Error())
+		}
+
+		cols = append(cols, ComputedColumn{Name: cf.Name, SQL: sq})
+	}
+
+	return cols, nil
+}
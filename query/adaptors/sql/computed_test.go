@@ -0,0 +1,73 @@
+package sql
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/influx6/data/query/adaptors"
+	"github.com/influx6/data/query/parser"
+	"github.com/influx6/flux"
+)
+
+func TestComputedFields(t *testing.T) {
+	stl := compileStatement(t, DefaultAdaptor(nil, nil), `users(id: 1){ name, fullName: concat(first, " ", last), ageInMonths: age * 12, quote: upper("it's") }`)
+
+	info := stl.Tables.Named("users")[0]
+	alias := info.Alias
+
+	for _, column := range []string{
+		"CONCAT_WS('', " + alias + ".first, ' ', " + alias + ".last) AS fullName",
+		"(" + alias + ".age * 12) AS ageInMonths",
+		"UPPER('it''s') AS quote",
+	} {
+		if !strings.Contains(stl.Query, column) {
+			flux.FatalFailed(t, "Expected %q to be selected: %s", column, stl.Query)
+		}
+	}
+
+	if strings.Join(info.Columns, " ") != "name fullName ageInMonths quote" || stl.Columns != 4 || len(stl.Args) != 0 {
+		flux.FatalFailed(t, "Expected computed fields after the columns without args: %+v %+v", info.Columns, stl.Args)
+	}
+
+	pg := compileStatement(t, NewAdaptor(nil, nil, PostgresQueries, RelQueries), `users(){ score: round(rating, 1), month: date_trunc("month", created) }`)
+
+	if !strings.Contains(pg.Query, "ROUND(CAST(") || !strings.Contains(pg.Query, "DATE_TRUNC('month', ") {
+		flux.FatalFailed(t, "Expected postgres functions: %s", pg.Query)
+	}
+
+	lite := compileStatement(t, NewAdaptor(nil, nil, SQLiteQueries, RelQueries), `users(){ fullName: concat(first, last), day: date_trunc("day", created) }`)
+
+	if !strings.Contains(lite.Query, "(COALESCE(") || !strings.Contains(lite.Query, "strftime('%Y-%m-%d 00:00:00', ") {
+		flux.FatalFailed(t, "Expected sqlite functions: %s", lite.Query)
+	}
+
+	flux.LogPassed(t, "Selected computed fields with dialect functions")
+}
+
+func TestDivision(t *testing.T) {
+	ex, err := parser.ParseExpr("7 / 2", nil)
+
+	if err != nil {
+		flux.FatalFailed(t, "Failed to parse expression: %+s", err)
+	}
+
+	if val, err := adaptors.DefaultScalars.Compute(ex, adaptors.Record{}); err != nil || val != 3.5 {
+		flux.FatalFailed(t, "Expected 7 / 2 to be 3.5 in memory but got %v (%+s)", val, err)
+	}
+
+	//every dialect divides by a float, so 7 / 2 is 3.5 rather than the 3 of integer division
+	for _, tc := range []struct {
+		op  *parser.OPFactory
+		sql string
+	}{
+		{TemplatesQueries, "(7 / NULLIF(2, 0))"},
+		{PostgresQueries, "(CAST(7 AS NUMERIC) / NULLIF(2, 0))"},
+		{SQLiteQueries, "(CAST(7 AS REAL) / NULLIF(2, 0))"},
+	} {
+		if sq, err := ExpressionSQL(tc.op, ex); err != nil || sq != tc.sql {
+			flux.FatalFailed(t, "Expected %q but got %q (%+s)", tc.sql, sq, err)
+		}
+	}
+
+	flux.LogPassed(t, "Divided by a float in sql as in memory")
+}
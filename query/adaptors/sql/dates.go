@@ -0,0 +1,139 @@
+package sql
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/influx6/data/query/parser"
+)
+
+// intervalUnits are the units relative dates are written in, largest first, each with its MySQL keyword and its Postgres and SQLite name
+var intervalUnits = []struct {
+	size         time.Duration
+	mysql, plain string
+}{
+	{24 * time.Hour, "DAY", "days"},
+	{time.Hour, "HOUR", "hours"},
+	{time.Minute, "MINUTE", "minutes"},
+	{time.Second, "SECOND", "seconds"},
+}
+
+// DateSQL returns the sql of the current time moved by the offset in the dialect, offsets are rounded to whole seconds
+func DateSQL(d Dialect, offset time.Duration) string {
+	sign := "+"
+
+	if offset < 0 {
+		sign = "-"
+		offset = -offset
+	}
+
+	offset = offset.Truncate(time.Second)
+
+	if offset == 0 {
+		if d == SQLite {
+			return "datetime('now')"
+		}
+		return "NOW()"
+	}
+
+	unit := intervalUnits[len(intervalUnits)-1]
+
+	for _, iu := range intervalUnits {
+		if offset%iu.size == 0 {
+			unit = iu
+			break
+		}
+	}
+
+	count := int64(offset / unit.size)
+
+	switch d {
+	case Postgres:
+		return fmt.Sprintf("(NOW() %s INTERVAL '%d %s')", sign, count, unit.plain)
+	case SQLite:
+		return fmt.Sprintf("datetime('now', '%s%d %s')", sign, count, unit.plain)
+	}
+
+	return fmt.Sprintf("(NOW() %s INTERVAL %d %s)", sign, count, unit.mysql)
+}
+
+// AddDateHandlers adds the before, after, within and between conditions of the dialect to the OPFactory, absolute dates are bound as
+// time.Time args while relative dates are computed by the database from its current time
+func AddDateHandlers(op *parser.OPFactory, d Dialect) {
+	op.Add("before", dateHandler(d, "{{table}}.%s < %s"))
+	op.Add("after", dateHandler(d, "{{table}}.%s > %s"))
+
+	op.Add("within", func(name string, c parser.Collector) ([]string, error) {
+		if !c.Has("value") {
+			return nil, ErrNoValue
+		}
+
+		date, err := parser.DateValue(c.Get("value"))
+
+		if err != nil {
+			return nil, err
+		}
+
+		now := DateSQL(d, 0)
+		at := dateValue(c, d, date)
+
+		if date.Relative && date.Offset > 0 {
+			return []string{fmt.Sprintf("{{table}}.%s BETWEEN %s AND %s", name, now, at)}, nil
+		}
+
+		return []string{fmt.Sprintf("{{table}}.%s BETWEEN %s AND %s", name, at, now)}, nil
+	})
+
+	op.Add("between", func(name string, c parser.Collector) ([]string, error) {
+		if !c.Has("min") || !c.Has("max") {
+			return nil, ErrNoValue
+		}
+
+		from, err := parser.DateValue(c.Get("min"))
+
+		if err != nil {
+			return nil, err
+		}
+
+		to, err := parser.DateValue(c.Get("max"))
+
+		if err != nil {
+			return nil, err
+		}
+
+		//args are bound in the order their placeholders appear
+		min := dateValue(c, d, from)
+		max := dateValue(c, d, to)
+
+		return []string{fmt.Sprintf("{{table}}.%s BETWEEN %s AND %s", name, min, max)}, nil
+	})
+}
+
+// dateHandler returns a handler formatting the template with the column name and the sql of the collector's date
+func dateHandler(d Dialect, template string) parser.ParseFx {
+	return func(name string, c parser.Collector) ([]string, error) {
+		if !c.Has("value") {
+			return nil, ErrNoValue
+		}
+
+		date, err := parser.DateValue(c.Get("value"))
+
+		if err != nil {
+			return nil, err
+		}
+
+		return []string{fmt.Sprintf(template, name, dateValue(c, d, date))}, nil
+	}
+}
+
+// dateValue returns the sql of a relative date or the '?' placeholder of an absolute date, recording its time within the collector's args
+func dateValue(c parser.Collector, d Dialect, date parser.DateExpr) string {
+	if date.Relative {
+		return DateSQL(d, date.Offset)
+	}
+
+	args, _ := c.Get(parser.ArgsKey).([]interface{})
+	c.Set(parser.ArgsKey, append(args, date.Time))
+
+	return "?"
+}
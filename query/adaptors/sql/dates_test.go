@@ -0,0 +1,35 @@
+package sql
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influx6/flux"
+)
+
+func TestDateConditions(t *testing.T) {
+	stl := compileStatement(t, DefaultAdaptor(nil, nil), `users(){ name, created(within: 7d), updated(after: 2016-01-02T15:04:05Z) }`)
+
+	if !strings.Contains(stl.Query, ".created BETWEEN (NOW() - INTERVAL 7 DAY) AND NOW()") || !strings.Contains(stl.Query, ".updated > ?") {
+		flux.FatalFailed(t, "Expected mysql date conditions: %s", stl.Query)
+	}
+
+	if len(stl.Args) != 1 || stl.Args[0] != time.Date(2016, 1, 2, 15, 4, 5, 0, time.UTC) {
+		flux.FatalFailed(t, "Expected the absolute date bound as a time: %+v", stl.Args)
+	}
+
+	pg := compileStatement(t, NewAdaptor(nil, nil, PostgresQueries, RelQueries), `users(){ created(before: 36h), updated(between: 2016-01-01..+90m) }`)
+
+	if !strings.Contains(pg.Query, ".created < (NOW() - INTERVAL '36 hours')") || !strings.Contains(pg.Query, ".updated BETWEEN $1 AND (NOW() + INTERVAL '90 minutes')") {
+		flux.FatalFailed(t, "Expected postgres date arithmetic: %s", pg.Query)
+	}
+
+	lite := compileStatement(t, NewAdaptor(nil, nil, SQLiteQueries, RelQueries), `users(){ created(within: 1w) }`)
+
+	if !strings.Contains(lite.Query, ".created BETWEEN datetime('now', '-7 days') AND datetime('now')") {
+		flux.FatalFailed(t, "Expected sqlite date modifiers: %s", lite.Query)
+	}
+
+	flux.LogPassed(t, "Generated date conditions with dialect date arithmetic")
+}
@@ -0,0 +1,110 @@
+package sql
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/influx6/data/query/adaptors"
+	"github.com/influx6/data/query/parser"
+)
+
+//ExistsRootMessage provides error for existence rules on records without a parent to filter
+const ExistsRootMessage = "Record '%s' has an existence rule but no parent record to filter"
+
+// existence returns the EXISTS or NOT EXISTS operator of the record's existence rule, or an empty string when it has none
+func existence(uo *parser.ParseNode, table *Table) (string, error) {
+	want, ok := adaptors.Existence(uo)
+
+	if !ok {
+		return "", nil
+	}
+
+	if table.Parent == "" {
+		return "", uo.Report("", fmt.Sprintf(ExistsRootMessage, uo.Name()))
+	}
+
+	if want {
+		return "EXISTS", nil
+	}
+
+	return "NOT EXISTS", nil
+}
+
+// foldExists removes the tables of records with an existence rule along with their children, adding a correlated EXISTS subquery of
+// them to the conditions of their parents
+func foldExists(tables Tables) Tables {
+	children := make(map[string]Tables)
+	folded := make(map[string]bool)
+
+	for _, table := range tables {
+		children[table.PKey] = append(children[table.PKey], table)
+	}
+
+	var kept Tables
+
+	for _, table := range tables {
+		//children of folded tables are part of their subquery
+		if folded[table.Key] || folded[table.PKey] {
+			folded[table.Key] = true
+			continue
+		}
+
+		kept = append(kept, table)
+
+		for _, child := range children[table.Key] {
+			if child.Exists == "" {
+				continue
+			}
+
+			clause, args := existsClause(child, children)
+			table.Conditions = append(table.Conditions, clause)
+			table.Args = append(table.Args, args...)
+			folded[child.Key] = true
+		}
+	}
+
+	return kept
+}
+
+// existsClause returns the subquery of the table joined with its children, the table is correlated to its parent through the
+// {{parentTable}} of its conditions and children with an existence rule of their own become nested subqueries
+func existsClause(table *Table, children map[string]Tables) (string, []interface{}) {
+	var names []string
+	var wheres []string
+	var args []interface{}
+
+	var add func(t *Table)
+
+	add = func(t *Table) {
+		names = append(names, fmt.Sprintf("%s %s", strings.ToUpper(t.Name), t.Key))
+		names = append(names, t.Joins...)
+
+		clos := strings.Join(t.Conditions, "\nAND ")
+		clos = strings.Replace(clos, "{{table}}", t.Key, -1)
+		clos = strings.Replace(clos, "{{parentTable}}", t.PKey, -1)
+
+		wheres = append(wheres, clos)
+		args = append(args, t.Args...)
+
+		for _, child := range children[t.Key] {
+			if child.Exists == "" {
+				add(child)
+				continue
+			}
+
+			clause, cargs := existsClause(child, children)
+			wheres = append(wheres, clause)
+			args = append(args, cargs...)
+		}
+	}
+
+	add(table)
+
+	query := fmt.Sprintf("SELECT 1 FROM %s", strings.Join(names, ", "))
+
+	if wheres = adaptors.CleanHouse(wheres); len(wheres) > 0 {
+		query = fmt.Sprintf("%s WHERE %s", query, strings.Join(wheres, "\nAND "))
+	}
+
+	return fmt.Sprintf("%s (%s)", table.Exists, query), args
+}
@@ -0,0 +1,64 @@
+package sql
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/influx6/data/query/adaptors"
+	"github.com/influx6/data/query/parser"
+	"github.com/influx6/flux"
+)
+
+func TestNullConditions(t *testing.T) {
+	stl := compileStatement(t, DefaultAdaptor(nil, nil), `users(deleted_at: null){ name(notnull: true), email(isnull: true), phone(isnot: NULL) }`)
+
+	for _, cond := range []string{".deleted_at IS NULL", ".name IS NOT NULL", ".email IS NULL", ".phone IS NOT NULL"} {
+		if !strings.Contains(stl.Query, cond) {
+			flux.FatalFailed(t, "Expected %q in statement: %s", cond, stl.Query)
+		}
+	}
+
+	if strings.Contains(stl.Query, "= null") || len(stl.Args) != 0 {
+		flux.FatalFailed(t, "Expected no null comparisons or args: %s %+v", stl.Query, stl.Args)
+	}
+
+	flux.LogPassed(t, "Generated IS NULL and IS NOT NULL conditions")
+}
+
+func TestExistsRule(t *testing.T) {
+	stl := compileStatement(t, DefaultAdaptor(nil, nil), `users(){
+		name,
+		photos(exists: true, with: [user_id id]){ url(contains: 'sock') },
+		posts(notexists: true, with: [user_id id])
+	}`)
+
+	if len(stl.Tables) != 1 || stl.Columns != 1 {
+		flux.FatalFailed(t, "Expected only users selected: %s", stl.Query)
+	}
+
+	users := stl.Tables.Named("users")[0].Alias
+
+	if !strings.Contains(stl.Query, "EXISTS (SELECT 1 FROM PHOTOS ") || !strings.Contains(stl.Query, ".user_id = "+users+".id") {
+		flux.FatalFailed(t, "Expected photos correlated to users: %s", stl.Query)
+	}
+
+	if !strings.Contains(stl.Query, "NOT EXISTS (SELECT 1 FROM POSTS ") || !strings.Contains(stl.Query, ".url LIKE ? ESCAPE '!'") {
+		flux.FatalFailed(t, "Expected posts excluded and photos conditioned: %s", stl.Query)
+	}
+
+	if len(stl.Args) != 1 || stl.Args[0] != "%sock%" {
+		flux.FatalFailed(t, "Expected the photos condition bound within the subquery: %+v", stl.Args)
+	}
+
+	graphs, err := adaptors.Parse(parser.DefaultInspectionFactory, `users(exists: true){ name }`)
+
+	if err != nil {
+		flux.FatalFailed(t, "Failed to parse query: %+s", err)
+	}
+
+	if _, err := DefaultAdaptor(nil, nil).Compile(graphs[0]); err == nil {
+		flux.FatalFailed(t, "Expected existence rule on root record to fail")
+	}
+
+	flux.LogPassed(t, "Compiled existence rules into correlated EXISTS subqueries")
+}
@@ -0,0 +1,253 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"strings"
+
+	"github.com/influx6/data/query/adaptors"
+	"github.com/influx6/data/query/parser"
+	"github.com/influx6/ds"
+	"github.com/influx6/flux"
+)
+
+// ExplainPrefix is prepended to a statement's query to ask the database for its plan
+var ExplainPrefix = "EXPLAIN "
+
+// TableRange describes the columns a table occupies within the rows of a statement
+type TableRange struct {
+	Name    string   `json:"name"`
+	Alias   string   `json:"alias"`
+	Parent  string   `json:"parent,omitempty"`
+	Kind    string   `json:"kind"`
+	Begin   int      `json:"begin"`
+	End     int      `json:"end"`
+	Columns []string `json:"columns"`
+}
+
+// Explanation describes what a query will run without executing it
+type Explanation struct {
+	Query   string                   `json:"query"`
+	Graph   *adaptors.GraphNode      `json:"graph"`
+	SQL     string                   `json:"sql"`
+	Args    []interface{}            `json:"args"`
+	Tables  []TableRange             `json:"tables"`
+	Batches []string                 `json:"batches,omitempty"`
+	Plan    []map[string]interface{} `json:"plan,omitempty"`
+}
+
+// String returns the name of the relation kind
+func (k RelationKind) String() string {
+	switch k {
+	case HasOne:
+		return "has-one"
+	case HasMany:
+		return "has-many"
+	case ManyToMany:
+		return "many-to-many"
+	}
+	return "unknown"
+}
+
+// Explain compiles the graph as Compile does into its Explanation, so its batches are those the adaptor executes, adding the database's
+// own plan when the adaptor has a database
+func (a *Adaptor) Explain(ctx context.Context, gs ds.Graphs) (*Explanation, error) {
+	return explain(ctx, a.db, gs, func() (*Statement, error) {
+		plan, err := a.Compile(gs)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return plan.(*Statement), nil
+	})
+}
+
+// ExplainGraph describes the graph and compiles it through the OPFactories into its Explanation, running the database's EXPLAIN of the statement when db is not nil
+func ExplainGraph(ctx context.Context, db *sql.DB, gs ds.Graphs, op, specs *parser.OPFactory, rs RelationResolver) (*Explanation, error) {
+	return explain(ctx, db, gs, func() (*Statement, error) {
+		tables, err := BuildTables(gs, op, specs, rs)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return BuildStatement(tables), nil
+	})
+}
+
+// explain describes the graph before compiling it, as compiling consumes its rules, into the Explanation of the compiled statement
+func explain(ctx context.Context, db *sql.DB, gs ds.Graphs, compile func() (*Statement, error)) (*Explanation, error) {
+	graph, err := adaptors.DescribeGraph(gs)
+
+	if err != nil {
+		return nil, err
+	}
+
+	query, err := parser.Canonical(gs)
+
+	if err != nil {
+		return nil, err
+	}
+
+	stl, err := compile()
+
+	if err != nil {
+		return nil, err
+	}
+
+	ex := &Explanation{
+		Query:   query,
+		Graph:   graph,
+		SQL:     stl.Query,
+		Args:    stl.Args,
+		Tables:  Ranges(stl),
+		Batches: BatchQueries(stl),
+	}
+
+	if ex.Args == nil {
+		ex.Args = []interface{}{}
+	}
+
+	if db != nil {
+		if ex.Plan, err = ExplainStatement(ctx, db, stl); err != nil {
+			return nil, err
+		}
+	}
+
+	return ex, nil
+}
+
+// Ranges returns the column ranges of the statement's tables in the order they appear in its rows
+func Ranges(stl *Statement) []TableRange {
+	var infos []*TableInfo
+
+	for _, info := range stl.Tables {
+		infos = append(infos, info)
+	}
+
+	sort.Sort(byBegin(infos))
+
+	ranges := []TableRange{}
+
+	for _, info := range infos {
+		ranges = append(ranges, TableRange{
+			Name:    info.Name,
+			Alias:   info.Alias,
+			Parent:  info.Parent,
+			Kind:    info.Kind.String(),
+			Begin:   info.Begin,
+			End:     info.End,
+			Columns: info.Columns,
+		})
+	}
+
+	return ranges
+}
+
+// BatchQueries returns the queries of the statement's batches and their own batches, the parent keys of each are shown as a single '?'
+func BatchQueries(stl *Statement) []string {
+	var queries []string
+
+	for _, batch := range stl.Batches {
+		queries = append(queries, Rebind(strings.Replace(batch.Query, keysMarker, "?", 1), batch.Placeholder))
+		queries = append(queries, BatchQueries(batch)...)
+	}
+
+	return queries
+}
+
+// ExplainStatement runs the database's EXPLAIN of the statement, returning each row of the plan keyed by its column names
+func ExplainStatement(ctx context.Context, db *sql.DB, stl *Statement) ([]map[string]interface{}, error) {
+	rows, err := db.QueryContext(ctx, ExplainPrefix+stl.Query, stl.Args...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+
+	if err != nil {
+		return nil, err
+	}
+
+	var plan []map[string]interface{}
+
+	for rows.Next() {
+		bu := adaptors.BuildInterfacePoints(len(cols))
+
+		if err := rows.Scan(bu...); err != nil {
+			return nil, err
+		}
+
+		row := make(map[string]interface{})
+
+		for index, val := range adaptors.UnbuildInterfacePoints(bu) {
+			if bs, ok := val.([]byte); ok {
+				val = string(bs)
+			}
+
+			row[cols[index]] = val
+		}
+
+		plan = append(plan, row)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return plan, nil
+}
+
+// ExplainQuery parses the query and explains each of its root records
+func ExplainQuery(ctx context.Context, db *sql.DB, query string, op, specs *parser.OPFactory, rs RelationResolver, inspect *parser.InspectionFactory) ([]*Explanation, error) {
+	graphs, err := adaptors.Parse(inspect, query)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var exs []*Explanation
+
+	for _, gs := range graphs {
+		ex, err := ExplainGraph(ctx, db, gs, op, specs, rs)
+
+		if err != nil {
+			return nil, err
+		}
+
+		exs = append(exs, ex)
+	}
+
+	return exs, nil
+}
+
+// Explainer returns a reactor that compiles each ds.Graphs into its *Explanation without executing it, asking the database for its plan when db is not nil
+func Explainer(db *sql.DB, op, specs *parser.OPFactory, rs RelationResolver) flux.Reactor {
+	return adaptors.QueryAdaptor(func(r flux.Reactor, gs ds.Graphs) {
+		ex, err := ExplainGraph(context.Background(), db, gs, op, specs, rs)
+
+		if err != nil {
+			r.ReplyError(err)
+			return
+		}
+
+		r.Reply(ex)
+	})
+}
+
+// BuildExplainQuero generates a query parser that replies with the *Explanation of each query instead of its records
+func BuildExplainQuero(db *sql.DB, rs RelationResolver, op, sp *parser.OPFactory, ds *parser.InspectionFactory) flux.Reactor {
+	co := adaptors.ChunkParser(ds)
+	co.Bind(Explainer(db, op, sp, rs), true)
+	return co
+}
+
+// ExplainQuero returns a query parser using the default query formatters that explains its queries, see BuildExplainQuero
+func ExplainQuero(db *sql.DB) flux.Reactor {
+	return BuildExplainQuero(db, nil, TemplatesQueries, RelQueries, parser.DefaultInspectionFactory)
+}
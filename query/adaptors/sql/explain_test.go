@@ -0,0 +1,69 @@
+package sql
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/influx6/data/query/adaptors"
+	"github.com/influx6/data/query/parser"
+	"github.com/influx6/flux"
+)
+
+func TestExplain(t *testing.T) {
+	exs, err := ExplainQuery(context.Background(), nil, `users(id: 1){
+		name,
+		photos(with: [user_id id]){ url, title }
+	}`, TemplatesQueries, RelQueries, nil, parser.DefaultInspectionFactory)
+
+	if err != nil || len(exs) != 1 {
+		flux.FatalFailed(t, "Failed to explain query: %+s", err)
+	}
+
+	ex := exs[0]
+
+	if ex.Query != "users(id: 1){ name, photos(with: [user_id id]){ title, url } }" {
+		flux.FatalFailed(t, "Expected canonical query but got %q", ex.Query)
+	}
+
+	if !strings.Contains(ex.SQL, "FROM USERS") || ex.Plan != nil {
+		flux.FatalFailed(t, "Expected compiled sql without a database plan: %+v", ex)
+	}
+
+	if len(ex.Tables) != 2 || ex.Tables[0].Name != "users" || ex.Tables[1].Begin != 1 || ex.Tables[1].End != 2 {
+		flux.FatalFailed(t, "Expected users at column 0 and photos at columns 1-2: %+v", ex.Tables)
+	}
+
+	if ex.Graph.Name != "users" || len(ex.Graph.Children) != 1 || ex.Graph.Children[0].Rules["with"] == nil {
+		flux.FatalFailed(t, "Expected graph of users with photos joined by 'with': %+v", ex.Graph)
+	}
+
+	if _, err := json.Marshal(ex); err != nil {
+		flux.FatalFailed(t, "Failed to marshal explanation: %+s", err)
+	}
+
+	flux.LogPassed(t, "Explained query with its graph, sql and table column ranges")
+
+	graphs, err := adaptors.Parse(parser.DefaultInspectionFactory, `users(id: 1){
+		name,
+		photos(with: [user_id id]){ url },
+		posts(with: [user_id id]){ title }
+	}`)
+
+	if err != nil {
+		flux.FatalFailed(t, "Failed to parse query: %+s", err)
+	}
+
+	ex, err = DefaultAdaptor(nil, nil).BatchAbove(1).Explain(context.Background(), graphs[0])
+
+	if err != nil {
+		flux.FatalFailed(t, "Failed to explain query: %+s", err)
+	}
+
+	if len(ex.Batches) != 1 || !strings.Contains(ex.Batches[0], "FROM POSTS") || strings.Contains(ex.SQL, "POSTS") {
+		flux.FatalFailed(t, "Expected posts explained as the batch the adaptor runs: %+v", ex)
+	}
+
+	flux.LogPassed(t, "Explained the batches of the adaptor's fanout")
+}
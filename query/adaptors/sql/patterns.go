@@ -0,0 +1,171 @@
+package sql
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/influx6/data/query/adaptors"
+	"github.com/influx6/data/query/parser"
+)
+
+// Dialect defines the sql flavour the pattern matching and date conditions are generated in
+type Dialect int
+
+const (
+	//MySQL generates REGEXP and MATCH ... AGAINST conditions, it is the dialect of TemplatesQueries
+	MySQL Dialect = iota
+	//Postgres generates ILIKE, ~ and to_tsvector conditions
+	Postgres
+	//SQLite generates REGEXP and FTS5 MATCH conditions, REGEXP needs a regexp function registered with the driver
+	SQLite
+)
+
+// LikeEscape is the escape character of the LIKE patterns generated for startswith, endswith and contains, chosen over a backslash
+// as it reads the same in the string literals of every dialect
+const LikeEscape = "!"
+
+// likeEscaper escapes the wildcards and escape character of a LIKE pattern
+var likeEscaper = strings.NewReplacer(LikeEscape, LikeEscape+LikeEscape, "%", LikeEscape+"%", "_", LikeEscape+"_")
+
+// EscapeLike returns the value with its LIKE wildcards escaped by LikeEscape so it is matched literally
+func EscapeLike(val string) string {
+	return likeEscaper.Replace(val)
+}
+
+// PostgresQueries provides the sql query templates with the pattern matching and date conditions of Postgres
+var PostgresQueries = DialectQueries(Postgres)
+
+// SQLiteQueries provides the sql query templates with the pattern matching and date conditions of SQLite
+var SQLiteQueries = DialectQueries(SQLite)
+
+// DialectQueries returns a new OPFactory of the default sql query templates with the pattern matching and date conditions and the
+// computed field functions of the dialect
+func DialectQueries(d Dialect) *parser.OPFactory {
+	op := parser.NewOPFactory()
+	AddSQLQueryHandlers(op)
+	AddPatternHandlers(op, d)
+	AddDateHandlers(op, d)
+	AddFunctionHandlers(op, d)
+	AddPlaceholderHandler(op, d)
+	return op
+}
+
+// placeholderTag is the OPFactory tag of the handler writing the placeholder of the nth arg of a statement, statements of factories
+// without one keep the '?' placeholders of MySQL and SQLite
+const placeholderTag = "placeholder"
+
+// Placeholder defines a function returning the placeholder of the nth arg of a statement, counted from 1
+type Placeholder func(n int) string
+
+// AddPlaceholderHandler adds the handler numbering the placeholders of Postgres as $1..$n to the OPFactory, the handler receives the
+// number of the arg under "index". Other dialects use '?' and need none
+func AddPlaceholderHandler(op *parser.OPFactory, d Dialect) {
+	if d != Postgres {
+		return
+	}
+
+	op.Add(placeholderTag, func(_ string, c parser.Collector) ([]string, error) {
+		return []string{fmt.Sprintf("$%v", c.Get("index"))}, nil
+	})
+}
+
+// Placeholders returns the Placeholder of the OPFactory's dialect or nil when its statements keep their '?' placeholders
+func Placeholders(op *parser.OPFactory) Placeholder {
+	if op == nil || !op.Has(placeholderTag) {
+		return nil
+	}
+
+	return func(n int) string {
+		marks, err := op.Process(placeholderTag, "", parser.Collector{"index": n})
+
+		if err != nil || len(marks) == 0 {
+			return "?"
+		}
+
+		return marks[0]
+	}
+}
+
+// Rebind returns the query with each '?' placeholder outside its string literals written by the Placeholder in order, a nil
+// Placeholder leaves the query as it is
+func Rebind(query string, mark Placeholder) string {
+	if mark == nil {
+		return query
+	}
+
+	var buf bytes.Buffer
+	var quoted bool
+	var count int
+
+	for _, ch := range query {
+		switch {
+		case ch == '\'':
+			quoted = !quoted
+		case ch == '?' && !quoted:
+			count++
+			buf.WriteString(mark(count))
+			continue
+		}
+
+		buf.WriteRune(ch)
+	}
+
+	return buf.String()
+}
+
+// AddPatternHandlers adds the like, ilike, startswith, endswith, contains, match and search conditions of the dialect to the OPFactory,
+// their values are always bound as args
+func AddPatternHandlers(op *parser.OPFactory, d Dialect) {
+	like := "{{table}}.%s LIKE ?"
+	ilike := "LOWER({{table}}.%s) LIKE LOWER(?)"
+	literal := "{{table}}.%s LIKE ? ESCAPE '" + LikeEscape + "'"
+	match := "{{table}}.%s REGEXP ?"
+	search := "MATCH({{table}}.%s) AGAINST (? IN NATURAL LANGUAGE MODE)"
+
+	switch d {
+	case Postgres:
+		ilike = "{{table}}.%s ILIKE ?"
+		match = "{{table}}.%s ~ ?"
+		search = "to_tsvector({{table}}.%s) @@ plainto_tsquery(?)"
+	case SQLite:
+		search = "{{table}}.%s MATCH ?"
+	}
+
+	op.Add("like", patternHandler(like, nil))
+	op.Add("ilike", patternHandler(ilike, nil))
+	op.Add("match", patternHandler(match, nil))
+	op.Add("search", patternHandler(search, nil))
+
+	op.Add("startswith", patternHandler(literal, func(val string) string {
+		return EscapeLike(val) + "%"
+	}))
+
+	op.Add("endswith", patternHandler(literal, func(val string) string {
+		return "%" + EscapeLike(val)
+	}))
+
+	op.Add("contains", patternHandler(literal, func(val string) string {
+		return "%" + EscapeLike(val) + "%"
+	}))
+}
+
+// patternHandler returns a handler formatting the template with the column name and binding the unquoted value, turned into a pattern when given
+func patternHandler(template string, pattern func(string) string) parser.ParseFx {
+	return func(name string, c parser.Collector) ([]string, error) {
+		if !c.Has("value") {
+			return nil, ErrNoValue
+		}
+
+		val := adaptors.ToString(c.Get("value"))
+
+		if pattern != nil {
+			val = pattern(val)
+		}
+
+		args, _ := c.Get(parser.ArgsKey).([]interface{})
+		c.Set(parser.ArgsKey, append(args, val))
+
+		return []string{fmt.Sprintf(template, name)}, nil
+	}
+}
@@ -0,0 +1,65 @@
+package sql
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/influx6/flux"
+)
+
+func TestPatternConditions(t *testing.T) {
+	stl := compileStatement(t, DefaultAdaptor(nil, nil), `users(){ name(startswith: 'a_%'), bio(search: 'go lang'), email(ilike: '%@GMAIL.com') }`)
+
+	if !strings.Contains(stl.Query, ".name LIKE ? ESCAPE '!'") || !strings.Contains(stl.Query, "MATCH(") {
+		flux.FatalFailed(t, "Expected escaped LIKE and full text conditions: %s", stl.Query)
+	}
+
+	if !strings.Contains(stl.Query, "LOWER(") || strings.Contains(stl.Query, "a_%") {
+		flux.FatalFailed(t, "Expected case folded LIKE with bound values: %s", stl.Query)
+	}
+
+	//field conditions are not ordered, so only the bound values are checked
+	args := make(map[interface{}]bool)
+
+	for _, arg := range stl.Args {
+		args[arg] = true
+	}
+
+	if len(stl.Args) != 3 || !args["a!_!%%"] || !args["go lang"] || !args["%@GMAIL.com"] {
+		flux.FatalFailed(t, "Expected escaped pattern args: %+v", stl.Args)
+	}
+
+	pg := compileStatement(t, NewAdaptor(nil, nil, PostgresQueries, RelQueries), `users(){ name(ilike: 'al%'), bio(match: '^go') }`)
+
+	if !strings.Contains(pg.Query, ".name ILIKE $") || !strings.Contains(pg.Query, ".bio ~ $") {
+		flux.FatalFailed(t, "Expected postgres ILIKE and regex conditions: %s", pg.Query)
+	}
+
+	flux.LogPassed(t, "Generated pattern matching conditions for each dialect")
+}
+
+func TestPostgresPlaceholders(t *testing.T) {
+	ad := NewAdaptor(nil, nil, PostgresQueries, RelQueries)
+
+	stl := compileStatement(t, ad, `users(){ name(ilike: 'al%'), tag: concat(name, '?'), photos(with: [user_id id], strategy: batch){ url(contains: 'jpg') } }`)
+
+	if !strings.Contains(stl.Query, ".name ILIKE $1") || strings.Contains(stl.Query, "$2") || !strings.Contains(stl.Query, "'?'") {
+		flux.FatalFailed(t, "Expected numbered placeholders outside string literals: %s", stl.Query)
+	}
+
+	batches := BatchQueries(stl)
+
+	if len(batches) != 1 || !strings.Contains(batches[0], "IN ($1)") || !strings.Contains(batches[0], ".url LIKE $2 ESCAPE") {
+		flux.FatalFailed(t, "Expected the batch's parent keys numbered before its args: %+v", batches)
+	}
+
+	if got := Rebind("a = ? AND b = 'x?' AND c IN (?, ?)", Placeholders(PostgresQueries)); got != "a = $1 AND b = 'x?' AND c IN ($2, $3)" {
+		flux.FatalFailed(t, "Expected rebound placeholders but got %q", got)
+	}
+
+	if mysql := compileStatement(t, DefaultAdaptor(nil, nil), `users(){ name(ilike: 'al%') }`); strings.Contains(mysql.Query, "$1") {
+		flux.FatalFailed(t, "Expected mysql to keep its '?' placeholders: %s", mysql.Query)
+	}
+
+	flux.LogPassed(t, "Numbered the placeholders of postgres statements and batches")
+}
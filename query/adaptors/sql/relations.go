@@ -0,0 +1,266 @@
+package sql
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/influx6/data/query/adaptors"
+)
+
+// RelationResolver resolves the [childkey parentkey] pair joining a child record to its parent when a query omits the 'with' rule
+type RelationResolver interface {
+	Resolve(child, parent string) ([]string, error)
+}
+
+const (
+	//NoRelation is the message when no relation can be found between two records
+	NoRelation = "Unable to infer a relation between '%s' and its parent '%s', add a '%s(with: [childkey parentkey])' rule"
+	//AmbiguousRelation is the message when more than one relation exists between two records
+	AmbiguousRelation = "Ambiguous relation between '%s' and its parent '%s' (candidates: %s), add a '%s(with: [childkey parentkey])' rule"
+)
+
+// NamingResolver resolves relations purely by naming convention ie 'photos' under 'users' joins on photos.user_id = users.id
+type NamingResolver struct{}
+
+// Resolve returns the conventional keys for the child and parent
+func (NamingResolver) Resolve(child, parent string) ([]string, error) {
+	return []string{ForeignKeyName(parent), "id"}, nil
+}
+
+// ForeignKeyName returns the conventional foreign key column for a table eg 'users' => 'user_id'
+func ForeignKeyName(table string) string {
+	return fmt.Sprintf("%s_id", Singular(strings.ToLower(table)))
+}
+
+// Singular returns a naive singular form of a table name eg 'users' => 'user', 'categories' => 'category'
+func Singular(name string) string {
+	switch {
+	case strings.HasSuffix(name, "ies"):
+		return strings.TrimSuffix(name, "ies") + "y"
+	case strings.HasSuffix(name, "ses"), strings.HasSuffix(name, "xes"):
+		return strings.TrimSuffix(name, "es")
+	case strings.HasSuffix(name, "ss"):
+		return name
+	case strings.HasSuffix(name, "s"):
+		return strings.TrimSuffix(name, "s")
+	}
+	return name
+}
+
+// Resolve infers the relation between the child and parent tables from their foreign keys, falling back to the naming convention of NamingResolver checked against the known columns
+func (c *Catalog) Resolve(child, parent string) ([]string, error) {
+	cts, pts := c.Table(child), c.Table(parent)
+
+	if cts == nil {
+		return nil, fmt.Errorf(UnknownTable, child)
+	}
+
+	if pts == nil {
+		return nil, fmt.Errorf(UnknownTable, parent)
+	}
+
+	var found [][]string
+
+	//foreign keys held by the child referencing the parent ie has-many/has-one
+	for _, fk := range cts.ForeignKeys {
+		if strings.EqualFold(fk.RefTable, pts.Name) {
+			found = append(found, []string{fk.Column, fk.RefColumn})
+		}
+	}
+
+	//foreign keys held by the parent referencing the child ie belongs-to
+	for _, fk := range pts.ForeignKeys {
+		if strings.EqualFold(fk.RefTable, cts.Name) {
+			found = append(found, []string{fk.RefColumn, fk.Column})
+		}
+	}
+
+	if len(found) == 0 {
+		if key := ForeignKeyName(pts.Name); cts.Column(key) != nil {
+			found = append(found, []string{key, primaryOrID(pts)})
+		}
+
+		if key := ForeignKeyName(cts.Name); pts.Column(key) != nil {
+			found = append(found, []string{primaryOrID(cts), key})
+		}
+	}
+
+	switch len(found) {
+	case 0:
+		return nil, fmt.Errorf(NoRelation, child, parent, child)
+	case 1:
+		return found[0], nil
+	}
+
+	var candidates []string
+
+	for _, keys := range found {
+		candidates = append(candidates, fmt.Sprintf("[%s]", strings.Join(keys, " ")))
+	}
+
+	return nil, fmt.Errorf(AmbiguousRelation, child, parent, strings.Join(candidates, ", "), child)
+}
+
+// primaryOrID returns the primary key of the table or 'id' when none is known
+func primaryOrID(t *TableSchema) string {
+	if pk := t.PrimaryKey(); pk != "" {
+		return pk
+	}
+	return "id"
+}
+
+// RelationKind defines the cardinality of a relation between a parent and child record
+type RelationKind int
+
+const (
+	//UnknownRelation represents a relation given only by its keys eg an explicit 'with' rule
+	UnknownRelation RelationKind = iota
+	//HasOne represents a parent owning a single child record
+	HasOne
+	//HasMany represents a parent owning a list of child records
+	HasMany
+	//ManyToMany represents parents and children linked through a join table
+	ManyToMany
+)
+
+// Many returns true if the relation shapes its child records as a list
+func (k RelationKind) Many() bool {
+	return k == HasMany || k == ManyToMany
+}
+
+// Relation defines a declared relation between a parent and a child table, Via and its keys are only used by ManyToMany relations
+type Relation struct {
+	Kind         RelationKind
+	Parent       string
+	Child        string
+	ChildKey     string
+	ParentKey    string
+	Via          string
+	ViaParentKey string
+	ViaChildKey  string
+}
+
+// RelationFinder provides the declared relation between a parent and child table if one exists
+type RelationFinder interface {
+	Find(parent, child string) (*Relation, bool)
+}
+
+// Relations provides a thread-safe registry of declared relations between tables
+type Relations struct {
+	rels map[string]*Relation
+	rw   sync.RWMutex
+}
+
+// NewRelations returns a new empty Relations registry
+func NewRelations() *Relations {
+	return &Relations{rels: make(map[string]*Relation)}
+}
+
+// relationID returns the registry key of a parent and child pair
+func relationID(parent, child string) string {
+	return fmt.Sprintf("%s.%s", strings.ToLower(parent), strings.ToLower(child))
+}
+
+// Add adds a relation into the registry replacing any existing one for the same parent and child
+func (r *Relations) Add(rel *Relation) {
+	r.rw.Lock()
+	r.rels[relationID(rel.Parent, rel.Child)] = rel
+	r.rw.Unlock()
+}
+
+// HasOne declares that each parent record owns a single child record where child.childKey = parent.parentKey
+func (r *Relations) HasOne(parent, child, childKey, parentKey string) {
+	r.Add(&Relation{
+		Kind:      HasOne,
+		Parent:    parent,
+		Child:     child,
+		ChildKey:  childKey,
+		ParentKey: parentKey,
+	})
+}
+
+// HasMany declares that each parent record owns a list of child records where child.childKey = parent.parentKey
+func (r *Relations) HasMany(parent, child, childKey, parentKey string) {
+	r.Add(&Relation{
+		Kind:      HasMany,
+		Parent:    parent,
+		Child:     child,
+		ChildKey:  childKey,
+		ParentKey: parentKey,
+	})
+}
+
+// ManyToMany declares that parent and child records are linked through the via table using the conventional keys ie ManyToMany("users","groups","user_groups") joins on user_groups.user_id = users.id and user_groups.group_id = groups.id
+func (r *Relations) ManyToMany(parent, child, via string) {
+	r.Add(&Relation{
+		Kind:         ManyToMany,
+		Parent:       parent,
+		Child:        child,
+		ChildKey:     "id",
+		ParentKey:    "id",
+		Via:          via,
+		ViaParentKey: ForeignKeyName(parent),
+		ViaChildKey:  ForeignKeyName(child),
+	})
+}
+
+// Find returns the relation declared between the parent and child
+func (r *Relations) Find(parent, child string) (*Relation, bool) {
+	r.rw.RLock()
+	rel, ok := r.rels[relationID(parent, child)]
+	r.rw.RUnlock()
+	return rel, ok
+}
+
+// Resolve returns the keys of a declared has-one or has-many relation between the child and parent
+func (r *Relations) Resolve(child, parent string) ([]string, error) {
+	rel, ok := r.Find(parent, child)
+
+	if !ok || rel.Kind == ManyToMany {
+		return nil, fmt.Errorf(NoRelation, child, parent, child)
+	}
+
+	return []string{rel.ChildKey, rel.ParentKey}, nil
+}
+
+// Joins returns the extra table and the conditions linking a child to its parent through the relation's join table, using the giving alias for the join table
+func (r *Relation) Joins(alias string) (string, []string) {
+	table := fmt.Sprintf("%s %s", strings.ToUpper(r.Via), alias)
+	return table, []string{
+		fmt.Sprintf("%s.%s = {{parentTable}}.%s", alias, r.ViaParentKey, r.ParentKey),
+		fmt.Sprintf("%s.%s = {{table}}.%s", alias, r.ViaChildKey, r.ChildKey),
+	}
+}
+
+// linker provides the adaptors.Linker of a RelationResolver
+type linker struct {
+	rs RelationResolver
+}
+
+// Linker returns an adaptors.Linker resolving relations as BuildTables does through the RelationResolver, for a Policy to check the
+// keys and join tables of child records queried without a 'with' rule
+func Linker(rs RelationResolver) adaptors.Linker {
+	return linker{rs}
+}
+
+// Link returns the link of the child to its parent, through the join table of a many-to-many relation
+func (l linker) Link(child, parent string) (*adaptors.Link, error) {
+	if finder, ok := l.rs.(RelationFinder); ok {
+		if rel, ok := finder.Find(parent, child); ok && rel.Kind == ManyToMany {
+			return &adaptors.Link{
+				Keys:    []string{rel.ChildKey, rel.ParentKey},
+				Via:     rel.Via,
+				ViaKeys: []string{rel.ViaChildKey, rel.ViaParentKey},
+			}, nil
+		}
+	}
+
+	keys, err := l.rs.Resolve(child, parent)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &adaptors.Link{Keys: keys}, nil
+}
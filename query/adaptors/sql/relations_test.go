@@ -0,0 +1,141 @@
+package sql
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/influx6/data/query/adaptors"
+	"github.com/influx6/data/query/parser"
+	"github.com/influx6/flux"
+)
+
+func TestManyToManyRelation(t *testing.T) {
+	var ws sync.WaitGroup
+	ws.Add(1)
+
+	rels := NewRelations()
+	rels.HasMany("users", "photos", "user_id", "id")
+	rels.ManyToMany("users", "groups", "user_groups")
+
+	qo := BuildRelationPreQuero(rels, TemplatesQueries, RelQueries, parser.DefaultInspectionFactory)
+
+	qo.React(func(r flux.Reactor, err error, d interface{}) {
+		defer ws.Done()
+
+		if err != nil {
+			flux.FatalFailed(t, "Failed in Building sql.Statement, Error Received: %+s", err)
+		}
+
+		stl := d.(*Statement)
+
+		if !strings.Contains(stl.Query, "USER_GROUPS") || !strings.Contains(stl.Query, ".group_id = ") || !strings.Contains(stl.Query, ".user_id = ") {
+			flux.FatalFailed(t, "Expected two-hop join through user_groups: %s", stl.Query)
+		}
+
+		groups, photos := stl.Tables.Named("groups"), stl.Tables.Named("photos")
+
+		if len(groups) != 1 || len(photos) != 1 || !groups[0].Kind.Many() || !photos[0].Kind.Many() {
+			flux.FatalFailed(t, "Expected groups and photos to be list relations")
+		}
+
+		flux.LogPassed(t, "Successfully created many-to-many sql.Statement")
+	}, true)
+
+	qo.Send(`users(){ name, groups{ name }, photos{ url } }`)
+
+	ws.Wait()
+	qo.Close()
+}
+
+func TestRelationPolicy(t *testing.T) {
+	rels := NewRelations()
+	rels.HasMany("users", "photos", "user_id", "id")
+	rels.ManyToMany("users", "groups", "user_groups")
+
+	authorize := func(po *adaptors.Policy, query string) error {
+		var ws sync.WaitGroup
+		ws.Add(1)
+
+		var res error
+
+		qo := adaptors.PolicyParser(parser.DefaultInspectionFactory, po, nil)
+
+		qo.React(func(r flux.Reactor, err error, d interface{}) {
+			defer ws.Done()
+			res = err
+		}, true)
+
+		qo.Send(query)
+
+		ws.Wait()
+		qo.Close()
+
+		return res
+	}
+
+	po := adaptors.NewPolicy(true)
+	po.UseLinker(Linker(rels))
+
+	if err := authorize(po, `users(){ name, groups{ name }, photos{ url } }`); err != nil {
+		flux.FatalFailed(t, "Expected query to be authorized: %+s", err)
+	}
+
+	po.DenyTable("user_groups")
+
+	if err := authorize(po, `users(){ name, groups{ name } }`); err == nil {
+		flux.FatalFailed(t, "Expected denied join table to fail authorization")
+	}
+
+	flux.LogPassed(t, "Denied join table of a many-to-many relation failed properly")
+
+	po = adaptors.NewPolicy(true)
+	po.UseLinker(Linker(rels))
+	po.DenyColumns("user_groups", "group_id")
+
+	if err := authorize(po, `users(){ name, groups{ name } }`); err == nil {
+		flux.FatalFailed(t, "Expected denied join table key to fail authorization")
+	}
+
+	po = adaptors.NewPolicy(true)
+	po.UseLinker(Linker(rels))
+	po.DenyColumns("photos", "user_id")
+
+	if err := authorize(po, `users(){ name, photos{ url } }`); err == nil {
+		flux.FatalFailed(t, "Expected denied inferred foreign key to fail authorization")
+	}
+
+	flux.LogPassed(t, "Denied keys of inferred relations failed properly")
+}
+
+func TestNestStatement(t *testing.T) {
+	stl := &Statement{
+		Tables: TableMeta{
+			"u": {Alias: "u", Name: "users", Columns: []string{"id", "name"}, Begin: 0, End: 1},
+			"p": {Alias: "p", ParentAlias: "u", Name: "photos", Columns: []string{"url"}, Begin: 2, End: 2, Kind: HasMany},
+			"f": {Alias: "f", ParentAlias: "u", Name: "profile", Columns: []string{"bio"}, Begin: 3, End: 3, Kind: HasOne},
+		},
+		Data: [][]interface{}{
+			{1, "alex", "a.jpg", "hi"},
+			{1, "alex", "b.jpg", "hi"},
+			{2, "josh", "c.jpg", "yo"},
+		},
+	}
+
+	tree := nestStatement(stl)
+	users := tree["users"].([]TableSection)
+
+	if len(users) != 2 {
+		flux.FatalFailed(t, "Expected 2 merged user records but got %d", len(users))
+	}
+
+	if photos := users[0]["photos"].([]TableSection); len(photos) != 2 {
+		flux.FatalFailed(t, "Expected 2 photos for the first user but got %d", len(photos))
+	}
+
+	if _, ok := users[0]["profile"].(TableSection); !ok {
+		flux.FatalFailed(t, "Expected has-one profile to be an object: %+v", users[0]["profile"])
+	}
+
+	flux.LogPassed(t, "Nested has-one as object and has-many as list")
+}
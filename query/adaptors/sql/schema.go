@@ -0,0 +1,508 @@
+package sql
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/influx6/data/query/adaptors"
+	"github.com/influx6/data/query/parser"
+	"github.com/influx6/flux"
+)
+
+// ColumnKind defines the broad family of a column type used when checking conditions against it
+type ColumnKind int
+
+const (
+	//TextColumn represents character and unknown column types
+	TextColumn ColumnKind = iota
+	//NumericColumn represents integer,decimal and floating point column types
+	NumericColumn
+	//TimeColumn represents date and time column types
+	TimeColumn
+)
+
+// Column defines a single column of a table
+type Column struct {
+	Name     string
+	Type     string
+	Kind     ColumnKind
+	Nullable bool
+	Primary  bool
+}
+
+// ForeignKey defines a column of a table referencing a column of another table
+type ForeignKey struct {
+	Column    string
+	RefTable  string
+	RefColumn string
+}
+
+// TableSchema defines the columns and foreign keys of a single table, Order holds the column names in the order they were added
+type TableSchema struct {
+	Name        string
+	Columns     map[string]*Column
+	Order       []string
+	ForeignKeys []ForeignKey
+}
+
+// NewTableSchema returns a new TableSchema instance
+func NewTableSchema(name string) *TableSchema {
+	return &TableSchema{
+		Name:    name,
+		Columns: make(map[string]*Column),
+	}
+}
+
+// AddColumn adds a column to the table, deriving its kind from the database type name
+func (t *TableSchema) AddColumn(name, ctype string, nullable, primary bool) *Column {
+	col := &Column{
+		Name:     name,
+		Type:     ctype,
+		Kind:     KindOf(ctype),
+		Nullable: nullable,
+		Primary:  primary,
+	}
+
+	if _, ok := t.Columns[strings.ToLower(name)]; !ok {
+		t.Order = append(t.Order, strings.ToLower(name))
+	}
+
+	t.Columns[strings.ToLower(name)] = col
+	return col
+}
+
+// AddForeignKey adds a foreign key from a column of this table to another table's column
+func (t *TableSchema) AddForeignKey(column, reftable, refcolumn string) {
+	t.ForeignKeys = append(t.ForeignKeys, ForeignKey{
+		Column:    column,
+		RefTable:  reftable,
+		RefColumn: refcolumn,
+	})
+}
+
+// Column returns the column by the giving name or nil if it does not exist
+func (t *TableSchema) Column(name string) *Column {
+	return t.Columns[strings.ToLower(name)]
+}
+
+// PrimaryKey returns the name of the first primary key column in ordinal order or an empty string if none is known
+func (t *TableSchema) PrimaryKey() string {
+	if keys := t.PrimaryKeys(); len(keys) > 0 {
+		return keys[0]
+	}
+	return ""
+}
+
+// PrimaryKeys returns the names of the primary key columns in ordinal order, composite keys have more than one
+func (t *TableSchema) PrimaryKeys() []string {
+	var keys []string
+
+	for _, name := range t.Order {
+		if col := t.Columns[name]; col.Primary {
+			keys = append(keys, col.Name)
+		}
+	}
+
+	return keys
+}
+
+// KindOf returns the ColumnKind of a database type name eg 'varchar(50)',' int unsigned'
+func KindOf(ctype string) ColumnKind {
+	ctype = strings.ToLower(strings.TrimSpace(ctype))
+
+	if ind := strings.IndexAny(ctype, "( "); ind != -1 {
+		ctype = ctype[:ind]
+	}
+
+	switch ctype {
+	case "int", "integer", "tinyint", "smallint", "mediumint", "bigint", "decimal", "numeric", "float", "double", "real", "serial", "bigserial", "smallserial":
+		return NumericColumn
+	case "date", "datetime", "timestamp", "time", "year", "timestamptz":
+		return TimeColumn
+	}
+
+	return TextColumn
+}
+
+// Catalog provides a thread-safe collection of table schemas loaded from a database
+type Catalog struct {
+	tables map[string]*TableSchema
+	rw     sync.RWMutex
+}
+
+// NewCatalog returns a new empty Catalog
+func NewCatalog() *Catalog {
+	return &Catalog{tables: make(map[string]*TableSchema)}
+}
+
+// Add adds a table schema into the catalog,replacing any with the same name
+func (c *Catalog) Add(t *TableSchema) {
+	c.rw.Lock()
+	c.tables[strings.ToLower(t.Name)] = t
+	c.rw.Unlock()
+}
+
+// Has returns true/false if the table exists in the catalog
+func (c *Catalog) Has(name string) bool {
+	c.rw.RLock()
+	_, ok := c.tables[strings.ToLower(name)]
+	c.rw.RUnlock()
+	return ok
+}
+
+// Table returns the table schema for the giving name or nil if it does not exist
+func (c *Catalog) Table(name string) *TableSchema {
+	c.rw.RLock()
+	defer c.rw.RUnlock()
+	return c.tables[strings.ToLower(name)]
+}
+
+// mysqlColumns retrieves the columns of all tables in a schema through information_schema
+const mysqlColumns = `SELECT TABLE_NAME, COLUMN_NAME, DATA_TYPE, IS_NULLABLE, COLUMN_KEY FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = %s ORDER BY TABLE_NAME, ORDINAL_POSITION`
+
+// mysqlForeignKeys retrieves the foreign keys of all tables in a schema through information_schema
+const mysqlForeignKeys = `SELECT TABLE_NAME, COLUMN_NAME, REFERENCED_TABLE_NAME, REFERENCED_COLUMN_NAME FROM information_schema.KEY_COLUMN_USAGE WHERE TABLE_SCHEMA = %s AND REFERENCED_TABLE_NAME IS NOT NULL`
+
+// postgresColumns retrieves the columns of all tables in a schema through information_schema, marking primary key columns as 'PRI' like MySQL
+const postgresColumns = `SELECT c.table_name, c.column_name, c.data_type, c.is_nullable, CASE WHEN k.column_name IS NULL THEN '' ELSE 'PRI' END
+FROM information_schema.columns c
+LEFT JOIN information_schema.table_constraints t ON t.table_schema = c.table_schema AND t.table_name = c.table_name AND t.constraint_type = 'PRIMARY KEY'
+LEFT JOIN information_schema.key_column_usage k ON k.constraint_name = t.constraint_name AND k.table_schema = t.table_schema AND k.table_name = c.table_name AND k.column_name = c.column_name
+WHERE c.table_schema = %s ORDER BY c.table_name, c.ordinal_position`
+
+// postgresForeignKeys retrieves the foreign keys of all tables in a schema through information_schema
+const postgresForeignKeys = `SELECT k.table_name, k.column_name, u.table_name, u.column_name
+FROM information_schema.table_constraints t
+JOIN information_schema.key_column_usage k ON k.constraint_name = t.constraint_name AND k.table_schema = t.table_schema
+JOIN information_schema.constraint_column_usage u ON u.constraint_name = t.constraint_name AND u.constraint_schema = t.table_schema
+WHERE t.constraint_type = 'FOREIGN KEY' AND t.table_schema = %s`
+
+// LoadCatalog loads the tables,columns and foreign keys of a MySQL schema from information_schema, when the schema is an empty string the
+// connection's current database is used, see LoadDialectCatalog for other databases
+func LoadCatalog(db *sql.DB, schema string) (*Catalog, error) {
+	return LoadDialectCatalog(db, MySQL, schema)
+}
+
+// LoadDialectCatalog loads the catalog of a schema in the dialect's database, when the schema is an empty string the connection's current
+// database or schema is used, sqlite databases have a single schema and ignore it
+func LoadDialectCatalog(db *sql.DB, d Dialect, schema string) (*Catalog, error) {
+	var args []interface{}
+
+	columns, foreign, where, placeholder := mysqlColumns, mysqlForeignKeys, "DATABASE()", "?"
+
+	switch d {
+	case SQLite:
+		return LoadSQLiteCatalog(db)
+	case Postgres:
+		columns, foreign, where, placeholder = postgresColumns, postgresForeignKeys, "current_schema()", "$1"
+	}
+
+	if schema != "" {
+		where = placeholder
+		args = append(args, schema)
+	}
+
+	cat := NewCatalog()
+
+	rows, err := db.Query(fmt.Sprintf(columns, where), args...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var table, column, ctype, nullable, key string
+
+		if err := rows.Scan(&table, &column, &ctype, &nullable, &key); err != nil {
+			return nil, err
+		}
+
+		ts := cat.Table(table)
+
+		if ts == nil {
+			ts = NewTableSchema(table)
+			cat.Add(ts)
+		}
+
+		ts.AddColumn(column, ctype, nullable == "YES", key == "PRI")
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	fks, err := db.Query(fmt.Sprintf(foreign, where), args...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer fks.Close()
+
+	for fks.Next() {
+		var table, column, reftable, refcolumn string
+
+		if err := fks.Scan(&table, &column, &reftable, &refcolumn); err != nil {
+			return nil, err
+		}
+
+		if ts := cat.Table(table); ts != nil {
+			ts.AddForeignKey(column, reftable, refcolumn)
+		}
+	}
+
+	return cat, fks.Err()
+}
+
+// LoadSQLiteCatalog loads the tables,columns and foreign keys of a sqlite database from sqlite_master and its PRAGMA statements
+func LoadSQLiteCatalog(db *sql.DB) (*Catalog, error) {
+	rows, err := db.Query("SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%'")
+
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+
+	for rows.Next() {
+		var name string
+
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return nil, err
+		}
+
+		names = append(names, name)
+	}
+
+	rows.Close()
+
+	cat := NewCatalog()
+
+	for _, name := range names {
+		ts := NewTableSchema(name)
+
+		cols, err := db.Query(fmt.Sprintf("PRAGMA table_info(%q)", name))
+
+		if err != nil {
+			return nil, err
+		}
+
+		for cols.Next() {
+			var cid, notnull, pk int
+			var column, ctype string
+			var dflt interface{}
+
+			if err := cols.Scan(&cid, &column, &ctype, &notnull, &dflt, &pk); err != nil {
+				cols.Close()
+				return nil, err
+			}
+
+			ts.AddColumn(column, ctype, notnull == 0, pk > 0)
+		}
+
+		cols.Close()
+
+		fks, err := db.Query(fmt.Sprintf("PRAGMA foreign_key_list(%q)", name))
+
+		if err != nil {
+			return nil, err
+		}
+
+		for fks.Next() {
+			var id, seq int
+			var reftable, from, to, onupdate, ondelete, match string
+
+			if err := fks.Scan(&id, &seq, &reftable, &from, &to, &onupdate, &ondelete, &match); err != nil {
+				fks.Close()
+				return nil, err
+			}
+
+			ts.AddForeignKey(from, reftable, to)
+		}
+
+		fks.Close()
+
+		cat.Add(ts)
+	}
+
+	return cat, nil
+}
+
+const (
+	//UnknownTable is the message for a record that does not exist in the database
+	UnknownTable = "Unknown table '%s' in database schema"
+	//UnknownColumn is the message for a field that does not exist on its table
+	UnknownColumn = "Unknown column '%s' for table '%s'"
+	//IncompatibleCondition is the message for a condition that does not suit its column's type
+	IncompatibleCondition = "Condition '%s' cannot be applied to column '%s' of type '%s'"
+)
+
+// numericConditions are condition types that only make sense against numeric columns
+var numericConditions = []string{"gt", "gte", "lt", "lte", "range"}
+
+// textConditions are condition types that only make sense against text columns
+var textConditions = []string{"like", "ilike", "startswith", "endswith", "contains", "match", "search"}
+
+// dateConditions are condition types that only make sense against date and time columns
+var dateConditions = []string{"before", "after", "within", "between"}
+
+// Validate checks each table and its columns,relation keys and conditions against the catalog, returning a positioned error for the first mismatch
+func (c *Catalog) Validate(tables Tables) error {
+	schemas := make(map[string]*TableSchema)
+
+	for _, table := range tables {
+		ts := c.Table(table.Name)
+
+		if ts == nil {
+			return table.Node.Report("", fmt.Sprintf(UnknownTable, table.Name))
+		}
+
+		schemas[table.Key] = ts
+
+		for _, col := range table.Columns {
+			if ts.Column(col) == nil {
+				return table.Node.Report(col, fmt.Sprintf(UnknownColumn, col, table.Name))
+			}
+		}
+
+		for _, cf := range table.Node.Computed {
+			for _, col := range parser.ExprColumns(cf.Expr) {
+				if ts.Column(col) == nil {
+					return table.Node.Report(cf.Name, fmt.Sprintf(UnknownColumn, col, table.Name))
+				}
+			}
+		}
+
+		if len(table.With) == 2 {
+			if ts.Column(table.With[0]) == nil {
+				return table.Node.Report(relationKey, fmt.Sprintf(UnknownColumn, table.With[0], table.Name))
+			}
+
+			if pts, ok := schemas[table.PKey]; ok && pts.Column(table.With[1]) == nil {
+				return table.Node.Report(relationKey, fmt.Sprintf(UnknownColumn, table.With[1], pts.Name))
+			}
+		}
+
+		var err error
+
+		check := func(name string, co parser.Collector, stop func()) {
+			if adaptors.IsControlKey(name) {
+				return
+			}
+
+			col := ts.Column(name)
+
+			if col == nil {
+				err = table.Node.Report(name, fmt.Sprintf(UnknownColumn, name, table.Name))
+				stop()
+				return
+			}
+
+			if !compatible(col, co) {
+				err = table.Node.Report(name, fmt.Sprintf(IncompatibleCondition, co.Get("type"), col.Name, col.Type))
+				stop()
+			}
+		}
+
+		table.Node.Rules.EachCondition(check)
+
+		if err != nil {
+			return err
+		}
+
+		table.Node.Records.EachCondition(check)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// compatible returns false when a condition's type or value cannot match the column's kind
+func compatible(col *Column, co parser.Collector) bool {
+	ctype, _ := co.Get("type").(string)
+
+	if _, found := adaptors.FindMatch(dateConditions, ctype); found {
+		return col.Kind == TimeColumn
+	}
+
+	if col.Kind == NumericColumn {
+		if _, found := adaptors.FindMatch(textConditions, ctype); found {
+			return false
+		}
+
+		switch ctype {
+		case "is", "isnot":
+			return isNull(co.Get("value")) || isNumeric(co.Get("value"))
+		case "in":
+			items, _ := adaptors.InValues(co)
+			for _, val := range items {
+				if !isNumeric(val) {
+					return false
+				}
+			}
+		}
+		return true
+	}
+
+	if _, found := adaptors.FindMatch(numericConditions, ctype); found {
+		return false
+	}
+
+	return true
+}
+
+// isNumeric returns true if the value is a number or a string holding one
+func isNumeric(val interface{}) bool {
+	switch mo := val.(type) {
+	case parser.Param:
+		return isNumeric(mo.Value)
+	case int, int32, int64, uint, uint32, uint64, float32, float64:
+		return true
+	case string:
+		_, err := strconv.ParseFloat(strings.TrimSpace(mo), 64)
+		return err == nil
+	}
+	return false
+}
+
+//ErrNoCatalog is returned when a schema validator is created without a catalog
+var ErrNoCatalog = errors.New("Schema Catalog is nil")
+
+// SchemaValidator returns a reactor placed between the TableBuilder and TableParser that rejects tables which do not match the catalog
+func SchemaValidator(cat *Catalog) flux.Reactor {
+	return flux.Reactive(func(r flux.Reactor, err error, data interface{}) {
+		if err != nil {
+			r.ReplyError(err)
+			return
+		}
+
+		if cat == nil {
+			r.ReplyError(ErrNoCatalog)
+			return
+		}
+
+		tables, ok := data.(Tables)
+
+		if !ok {
+			r.ReplyError(ErrInvalidTableData)
+			return
+		}
+
+		if err := cat.Validate(tables); err != nil {
+			r.ReplyError(err)
+			return
+		}
+
+		r.Reply(tables)
+	})
+}
@@ -0,0 +1,167 @@
+package sql
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/influx6/data/query/adaptors"
+	"github.com/influx6/data/query/parser"
+	"github.com/influx6/flux"
+)
+
+func testCatalog() *Catalog {
+	cat := NewCatalog()
+
+	users := NewTableSchema("users")
+	users.AddColumn("id", "int", false, true)
+	users.AddColumn("name", "varchar(50)", true, false)
+	users.AddColumn("age", "int", true, false)
+	users.AddColumn("joined", "timestamp", true, false)
+	cat.Add(users)
+
+	photos := NewTableSchema("photos")
+	photos.AddColumn("id", "int", false, true)
+	photos.AddColumn("url", "varchar(50)", true, false)
+	photos.AddColumn("user_id", "int", true, false)
+	photos.AddForeignKey("user_id", "users", "id")
+	cat.Add(photos)
+
+	return cat
+}
+
+func validate(t *testing.T, query string) error {
+	var ws sync.WaitGroup
+	ws.Add(1)
+
+	var res error
+
+	cat := testCatalog()
+
+	com := adaptors.ChunkParser(parser.DefaultInspectionFactory)
+	com.Bind(RelationTableBuilder(TemplatesQueries, RelQueries, cat), true)
+
+	vs := SchemaValidator(cat)
+	com.Bind(vs, true)
+
+	vs.React(func(r flux.Reactor, err error, d interface{}) {
+		res = err
+		ws.Done()
+	}, true)
+
+	com.Send(query)
+
+	ws.Wait()
+	com.Close()
+
+	return res
+}
+
+func TestSchemaValidator(t *testing.T) {
+	if err := validate(t, `users(){ name, age(gt: 20), photos(with: [user_id id]){ url } }`); err != nil {
+		flux.FatalFailed(t, "Expected valid query to pass schema validation: %+s", err)
+	}
+
+	flux.LogPassed(t, "Valid query passed schema validation")
+
+	if err := validate(t, `users(cache: 60s){ name, photos(with: [user_id id], strategy: batch){ url } }`); err != nil {
+		flux.FatalFailed(t, "Expected cache and strategy rules to pass schema validation: %+s", err)
+	}
+
+	flux.LogPassed(t, "Control rules passed schema validation")
+
+	if err := validate(t, `users(){ name, age(is: null), photos(exists: true, with: [user_id id]) }`); err != nil {
+		flux.FatalFailed(t, "Expected null checks and existence rules to pass schema validation: %+s", err)
+	}
+
+	flux.LogPassed(t, "Null checks and existence rules passed schema validation")
+
+	err := validate(t, `users(){ nmae }`)
+
+	if err == nil {
+		flux.FatalFailed(t, "Expected unknown column 'nmae' to fail validation")
+	}
+
+	if _, ok := err.(*parser.PositionError); !ok {
+		flux.FatalFailed(t, "Expected a positioned error but got: %+s", err)
+	}
+
+	flux.LogPassed(t, "Unknown column failed properly: %+s", err)
+
+	if err := validate(t, `users(){ name(gt: 20) }`); err == nil {
+		flux.FatalFailed(t, "Expected numeric condition on text column to fail validation")
+	}
+
+	flux.LogPassed(t, "Incompatible condition failed properly")
+
+	if err := validate(t, `users(){ name, joined(within: 7d) }`); err != nil {
+		flux.FatalFailed(t, "Expected date condition on time column to pass schema validation: %+s", err)
+	}
+
+	for _, query := range []string{`users(){ name(before: 7d) }`, `users(){ age(after: 7d) }`} {
+		if err := validate(t, query); err == nil {
+			flux.FatalFailed(t, "Expected date condition on a column that is not a time column to fail validation: %s", query)
+		}
+	}
+
+	flux.LogPassed(t, "Date conditions on columns that are not time columns failed properly")
+
+	if err := validate(t, `users(){ name, label: upper(nmae) }`); err == nil {
+		flux.FatalFailed(t, "Expected computed field over unknown column 'nmae' to fail validation")
+	}
+
+	flux.LogPassed(t, "Computed field over unknown column failed properly")
+
+	if err := validate(t, `accounts(){ name }`); err == nil {
+		flux.FatalFailed(t, "Expected unknown table to fail validation")
+	}
+
+	flux.LogPassed(t, "Unknown table failed properly")
+}
+
+func TestPrimaryKeys(t *testing.T) {
+	ts := NewTableSchema("memberships")
+	ts.AddColumn("user_id", "int", false, true)
+	ts.AddColumn("role", "varchar(20)", true, false)
+	ts.AddColumn("group_id", "int", false, true)
+	ts.AddColumn("account_id", "int", false, true)
+
+	for i := 0; i < 10; i++ {
+		if keys := ts.PrimaryKeys(); strings.Join(keys, " ") != "user_id group_id account_id" || ts.PrimaryKey() != "user_id" {
+			flux.FatalFailed(t, "Expected composite key in ordinal order but got %+s", keys)
+		}
+	}
+
+	flux.LogPassed(t, "Returned composite primary keys in ordinal order")
+}
+
+func TestCatalogResolve(t *testing.T) {
+	cat := testCatalog()
+
+	keys, err := cat.Resolve("photos", "users")
+
+	if err != nil {
+		flux.FatalFailed(t, "Failed to resolve relation from foreign keys: %+s", err)
+	}
+
+	if len(keys) != 2 || keys[0] != "user_id" || keys[1] != "id" {
+		flux.FatalFailed(t, "Expected [user_id id] but got %+s", keys)
+	}
+
+	flux.LogPassed(t, "Resolved relation from foreign keys: %+s", keys)
+
+	cat.Table("photos").AddColumn("owner_id", "int", true, false)
+	cat.Table("photos").AddForeignKey("owner_id", "users", "id")
+
+	if _, err := cat.Resolve("photos", "users"); err == nil {
+		flux.FatalFailed(t, "Expected ambiguous foreign keys to fail resolution")
+	}
+
+	flux.LogPassed(t, "Ambiguous relation failed properly")
+
+	if err := validate(t, `users(){ name, photos{ url } }`); err != nil {
+		flux.FatalFailed(t, "Expected child without 'with' to be inferred: %+s", err)
+	}
+
+	flux.LogPassed(t, "Inferred relation for child record without 'with' rule")
+}
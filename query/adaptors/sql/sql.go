@@ -1,13 +1,18 @@
 package sql
 
 import (
+	"bytes"
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/influx6/data/query/adaptors"
 	"github.com/influx6/data/query/parser"
+	"github.com/influx6/data/query/utils"
 	"github.com/influx6/ds"
 	"github.com/influx6/flux"
 )
@@ -24,107 +29,230 @@ type Table struct {
 	PKey       string
 	Attrs      []string
 	Columns    []string
+	Computed   []ComputedColumn
 	Conditions []string
 	Orders     []string
+	With       []string
+	Joins      []string
+	Kind       RelationKind
+	Args       []interface{}
+	TTL        time.Duration
+	Strategy   string
+	Hidden     []string
+	Exists     string
 	Node       *parser.ParseNode
 	Graph      ds.Graphs
+
+	Placeholder Placeholder
 }
 
 // Tables represent an array of SQLTable
 type Tables []*Table
 
+// AddCondition generates the sql conditions of a collector through the OPFactory, adding them and any values they bound to the table
+func (t *Table) AddCondition(op *parser.OPFactory, name string, c parser.Collector) error {
+	c.Remove(parser.ArgsKey)
+
+	co, err := op.Process(c.Get("type").(string), name, c)
+
+	if err != nil {
+		return err
+	}
+
+	t.Conditions = append(t.Conditions, co...)
+
+	if args, ok := c.Get(parser.ArgsKey).([]interface{}); ok {
+		t.Args = append(t.Args, args...)
+	}
+
+	return nil
+}
+
 // TableBuilder provides a simple sql parser
 func TableBuilder(op, specs *parser.OPFactory) flux.Reactor {
+	return RelationTableBuilder(op, specs, nil)
+}
+
+// RelationTableBuilder provides a sql parser that uses the RelationResolver to supply the 'with' rule of child records that lack one, an explicit 'with' rule always takes precedence
+func RelationTableBuilder(op, specs *parser.OPFactory, rs RelationResolver) flux.Reactor {
 	return adaptors.QueryAdaptor(func(r flux.Reactor, gs ds.Graphs) {
-		mo, err := adaptors.DFGraph(gs)
+		tables, err := BuildTables(gs, op, specs, rs)
 
 		if err != nil {
 			r.ReplyError(err)
 			return
 		}
 
-		recordSize := gs.Length()
-		_ = recordSize
+		//deliver the table for building
+		r.Reply(tables)
+	})
+}
+
+// BuildTables generates the Tables of the graph's records through the OPFactories, using the RelationResolver when not nil to supply missing 'with' rules.
+// Records with an existence rule become EXISTS subqueries of their parents rather than tables of their own
+func BuildTables(gs ds.Graphs, op, specs *parser.OPFactory, rs RelationResolver) (Tables, error) {
+	mo, err := adaptors.DFGraph(gs)
+
+	if err != nil {
+		return nil, err
+	}
 
-		var tables Tables
+	recordSize := gs.Length()
+	_ = recordSize
 
-		for mo.Next() == nil {
-			uo := mo.Node().(*parser.ParseNode)
-			//create a table for this record
-			table := &Table{
-				Key:    uo.Key,
-				Name:   uo.Name(),
-				Parent: uo.Parent,
-				PKey:   uo.PKey,
-				Attrs:  uo.Attr.All(),
-				Node:   uo,
-				Graph:  gs,
+	var tables Tables
+
+	placeholder := Placeholders(op)
+
+	for mo.Next() == nil {
+		uo := mo.Node().(*parser.ParseNode)
+		//create a table for this record
+		table := &Table{
+			Key:    uo.Key,
+			Name:   uo.Name(),
+			Parent: uo.Parent,
+			PKey:   uo.PKey,
+			Attrs:  uo.Attr.All(),
+			Node:   uo,
+			Graph:  gs,
+
+			Placeholder: placeholder,
+		}
+
+		tables = append(tables, table)
+
+		rules := uo.Rules
+
+		var rel *Relation
+
+		if finder, ok := rs.(RelationFinder); ok && table.Parent != "" {
+			rel, _ = finder.Find(table.Parent, table.Name)
+		}
+
+		//many-to-many relations are linked through their join table rather than a 'with' rule
+		if rel != nil && rel.Kind == ManyToMany && !rules.Has(relationKey) {
+			join, conds := rel.Joins(strings.ToLower(utils.RandomAlias()))
+			table.Kind = rel.Kind
+			table.Joins = append(table.Joins, join)
+			table.Conditions = append(table.Conditions, conds...)
+		} else if table.Parent != "" {
+			if rel != nil && rel.Kind != ManyToMany {
+				table.Kind = rel.Kind
 			}
 
-			tables = append(tables, table)
+			if !rules.Has(relationKey) && rs == nil {
+				return nil, fmt.Errorf("Query for '%s' is a subroot/child of '%s' and needs a '%s(with: [childkey parentkey])' in its conditions for proper evaluation e.g '%s(with: [user_id id])'", table.Name, table.Parent, table.Name, table.Name)
+			}
 
-			rules := uo.Rules
+			if !rules.Has(relationKey) {
+				keys, err := rs.Resolve(table.Name, table.Parent)
 
-			if table.Parent != "" {
-				if !rules.Has(relationKey) {
-					r.ReplyError(fmt.Errorf("Query for '%s' is a subroot/child of '%s' and needs a '%s(with: [childkey parentkey])' in its conditions for proper evaluation e.g '%s(with: [user_id id])'", table.Name, table.Parent, table.Name, table.Name))
-					// col := parser.NewCondition("with")
-					// col.Set("value", []string{fmt.Sprintf("%s_id", strings.ToLower(table.Parent)), "id"})
-					// rules.Set(relationKey, []parser.Collector{col})
-					return
+				if err != nil {
+					return nil, uo.Report("", err.Error())
 				}
+
+				col := parser.NewCondition("with")
+				col.Set("value", keys)
+				rules.Set(relationKey, []parser.Collector{col})
 			}
+		}
 
-			for _, val := range specialKeys {
-				if !rules.Has(val) {
-					continue
-				}
+		for _, val := range specialKeys {
+			if !rules.Has(val) {
+				continue
+			}
 
-				co, err := rules.Get(val)
+			co, err := rules.Get(val)
 
-				if err != nil && len(co) <= 0 {
-					return
-				}
+			if err != nil && len(co) <= 0 {
+				return nil, err
+			}
 
-				cod := co[0]
+			cod := co[0]
 
-				// table.Keys[val] = co[0].Get("value")
-				if kos, err := specs.Process(val, val, cod); err == nil {
-					table.Conditions = append(table.Conditions, kos...)
+			if val == relationKey {
+				if keys, ok := cod.Get("value").([]string); ok {
+					table.With = keys
 				}
-				rules.Remove(val)
+			}
 
+			// table.Keys[val] = co[0].Get("value")
+			if kos, err := specs.Process(val, val, cod); err == nil {
+				table.Conditions = append(table.Conditions, kos...)
 			}
+			rules.Remove(val)
 
-			rules.EachCondition(func(name string, c parser.Collector, stop func()) {
-				co, err := op.Process(c.Get("type").(string), name, c)
+		}
 
-				if err != nil {
-					r.ReplyError(err)
-					stop()
-				}
+		if rules.Has(cacheKey) {
+			if table.TTL, err = cacheTTL(uo); err != nil {
+				return nil, err
+			}
+		}
 
-				table.Conditions = append(table.Conditions, co...)
-			})
+		if rules.Has(strategyKey) {
+			if table.Strategy, err = strategy(uo, table); err != nil {
+				return nil, err
+			}
+		}
 
-			records := uo.Records
-			//add the record to the column list
-			table.Columns = append(table.Columns, records.Keys()...)
+		if table.Exists, err = existence(uo, table); err != nil {
+			return nil, err
+		}
+
+		rules.EachCondition(func(name string, c parser.Collector, stop func()) {
+			if adaptors.IsControlKey(name) {
+				return
+			}
 
-			//process the records constraints
-			records.EachCondition(func(name string, c parser.Collector, stop func()) {
-				co, err := op.Process(c.Get("type").(string), name, c)
+			if err = table.AddCondition(op, name, c); err != nil {
+				stop()
+			}
+		})
 
-				if err != nil {
-					r.ReplyError(err)
-					stop()
-				}
-				table.Conditions = append(table.Conditions, co...)
-			})
+		if err != nil {
+			return nil, err
 		}
-		//deliver the table for building
-		r.Reply(tables)
-	})
+
+		records := uo.Records
+		//add the record to the column list
+		table.Columns = append(table.Columns, records.Keys()...)
+
+		//process the records constraints
+		records.EachCondition(func(name string, c parser.Collector, stop func()) {
+			if err = table.AddCondition(op, name, c); err != nil {
+				stop()
+			}
+		})
+
+		if err != nil {
+			return nil, err
+		}
+
+		if table.Computed, err = computedColumns(op, uo); err != nil {
+			return nil, err
+		}
+	}
+
+	return foldExists(tables), nil
+}
+
+// cacheTTL returns the duration of the record's cache rule
+func cacheTTL(uo *parser.ParseNode) (time.Duration, error) {
+	co, err := uo.Rules.Get(cacheKey)
+
+	if err != nil || len(co) == 0 {
+		return 0, err
+	}
+
+	val := co[0].Get("value")
+	ttl, err := time.ParseDuration(fmt.Sprintf("%v", val))
+
+	if err != nil || ttl <= 0 {
+		return 0, uo.Report(cacheKey, fmt.Sprintf(InvalidCacheMessage, uo.Name(), val))
+	}
+
+	return ttl, nil
 }
 
 //ErrInvalidTableData represent the error when the data type does not match the Tables type
@@ -137,21 +265,47 @@ type TableInfo struct {
 	Name        string
 	Parent      string
 	Begin, End  int
+	Kind        RelationKind
 	Columns     []string
+	Hidden      []string
 	Node        *parser.ParseNode
 	Graph       ds.Graphs
 }
 
-// TableMeta defines a map of TableInfo
+// TableMeta defines a map of TableInfo keyed by the alias of each table, as a record may be queried more than once within a statement
 type TableMeta map[string]*TableInfo
 
-// Statement represent a properly passed sql SqlStatement
+// Named returns the infos of the tables of the named record in the order their columns appear in the statement's rows
+func (t TableMeta) Named(name string) []*TableInfo {
+	var infos []*TableInfo
+
+	for _, info := range t {
+		if info.Name == name {
+			infos = append(infos, info)
+		}
+	}
+
+	sort.Sort(byBegin(infos))
+	return infos
+}
+
+// Statement represent a properly passed sql SqlStatement, a TTL lets its rows be served from a Cache and Cached marks rows that were.
+// Batches are the statements of batched child records, each looked up by the keys of the rows of the table aliased Parent through its With keys
 type Statement struct {
 	Query   string
+	Args    []interface{}
 	Tables  TableMeta
 	Columns int
 	Data    [][]interface{}
 	Graph   ds.Graphs
+	TTL     time.Duration
+	Cached  bool
+	Batches []*Statement
+	Parent  string
+	With    []string
+
+	//Placeholder writes the placeholders of the query's dialect, batch queries are only rebound once their parent keys are known
+	Placeholder Placeholder
 }
 
 //TableParser is a reactor that takes a array of *Tables and generates the corresponding sql statement
@@ -162,95 +316,190 @@ func TableParser() flux.Reactor {
 			return
 		}
 
-		var tables Tables
-		var ok bool
-
-		tables, ok = data.(Tables)
+		tables, ok := data.(Tables)
 
 		if !ok {
 			r.ReplyError(ErrInvalidTableData)
 			return
 		}
 
-		var tableNames []string
-		var tableColumns []string
-		var tableWheres []string
-		var tableMeta = make(TableMeta)
-		var lastColumSize = 0
-		var graph ds.Graphs
+		r.Reply(BuildStatement(tables))
+	})
+}
 
-		for _, table := range tables {
+// BuildStatement generates the sql statement selecting the columns of the tables with their joined conditions, tables with the batch
+// strategy and their children are split into batch statements of their own
+func BuildStatement(tables Tables) *Statement {
+	var groups []Tables
+	var ttl time.Duration
 
-			if graph == nil {
-				graph = table.Graph
-			}
+	owner := make(map[string]int)
+	keyed := make(map[string]*Table)
 
-			//add the tables names into the array and ensure to use aliases format "TALBENAME tablename"
-			tableNames = append(tableNames, fmt.Sprintf("%s %s", strings.ToUpper(table.Name), table.Key))
+	for _, table := range tables {
+		keyed[table.Key] = table
 
-			//loop through each column name and append talbe alias,add the column names for the 'from' clause
-			for _, coname := range table.Columns {
-				tableColumns = append(tableColumns, fmt.Sprintf("%s.%s", table.Key, coname))
-			}
+		//a statement is only cached as long as its shortest lived record allows
+		if table.TTL > 0 && (ttl == 0 || table.TTL < ttl) {
+			ttl = table.TTL
+		}
 
-			//collect table info for particular table
-			tableMeta[table.Name] = &TableInfo{
-				Alias:       table.Key,
-				ParentAlias: table.PKey,
-				Name:        table.Name,
-				Parent:      table.Parent,
-				Columns:     table.Columns,
-				Begin:       lastColumSize,
-				End:         (lastColumSize + (len(table.Columns) - 1)),
-				Node:        table.Node,
-				Graph:       table.Graph,
-			}
+		if id, ok := owner[table.PKey]; ok && table.Strategy != BatchStrategy {
+			owner[table.Key] = id
+			groups[id] = append(groups[id], table)
+			continue
+		}
+
+		owner[table.Key] = len(groups)
+		groups = append(groups, Tables{table})
+	}
+
+	if len(groups) == 0 {
+		return selectStatement(tables)
+	}
+
+	for _, group := range groups[1:] {
+		if parent, ok := keyed[group[0].PKey]; ok {
+			prepareBatch(group[0], parent)
+		}
+	}
+
+	stls := make([]*Statement, len(groups))
+
+	for id, group := range groups {
+		stls[id] = selectStatement(group)
 
-			lastColumSize = len(tableColumns)
+		if id == 0 {
+			continue
+		}
+
+		head := group[0]
+		parent, ok := keyed[head.PKey]
+
+		if !ok {
+			continue
+		}
+
+		stls[id].With = head.With
+		stls[id].Parent = parent.Key
 
-			// for _,clo := range table.Conditions {
-			// }
-			//join the conditions of this table with a AND
-			clos := strings.Join(table.Conditions, "\nAND ")
+		owned := stls[owner[head.PKey]]
+		owned.Batches = append(owned.Batches, stls[id])
+	}
 
-			//replace both {{table}} and {{parentTable}} with the appropriate names/tags
-			// log.Printf("setting alias:", table.Key, table.PKey, table.Name)
-			clos = strings.Replace(clos, "{{table}}", table.Key, -1)
-			clos = strings.Replace(clos, "{{parentTable}}", table.PKey, -1)
+	stls[0].TTL = ttl
+	return stls[0]
+}
 
-			//add this condition to the global where list
-			tableWheres = append(tableWheres, clos)
+// selectStatement generates the sql statement joining the tables into a single select
+func selectStatement(tables Tables) *Statement {
+	var tableNames []string
+	var tableColumns []string
+	var tableWheres []string
+	var tableArgs []interface{}
+	var tableMeta = make(TableMeta)
+	var lastColumSize = 0
+	var graph ds.Graphs
+	var placeholder Placeholder
+
+	for _, table := range tables {
+
+		if graph == nil {
+			graph = table.Graph
+			placeholder = table.Placeholder
 		}
 
-		var sqlst = SQLSimpleSelect
+		//add the tables names into the array and ensure to use aliases format "TALBENAME tablename"
+		tableNames = append(tableNames, fmt.Sprintf("%s %s", strings.ToUpper(table.Name), table.Key))
 
-		sqlst = strings.Replace(sqlst, "{{columns}}", strings.Join(tableColumns, ", "), -1)
-		sqlst = strings.Replace(sqlst, "{{tables}}", strings.Join(tableNames, ", "), -1)
+		//add any join tables needed to link this table to its parent
+		tableNames = append(tableNames, table.Joins...)
 
-		//clean where clauses of an empty strings or only spaces
-		tableWheres = adaptors.CleanHouse(tableWheres)
+		//loop through each column name and append talbe alias,add the column names for the 'from' clause
+		for _, coname := range table.Columns {
+			tableColumns = append(tableColumns, fmt.Sprintf("%s.%s", table.Key, coname))
+		}
 
-		if len(tableWheres) < 2 {
-			sqlst = strings.Replace(sqlst, "{{clauses}}", strings.Join(tableWheres, " "), -1)
-		} else {
-			sqlst = strings.Replace(sqlst, "{{clauses}}", strings.Join(tableWheres, "\nAND "), -1)
+		//computed fields are selected after the columns of the table, keeping their names in its info
+		columns := append([]string(nil), table.Columns...)
+
+		for _, cc := range table.Computed {
+			tableColumns = append(tableColumns, fmt.Sprintf("%s AS %s", strings.Replace(cc.SQL, "{{table}}", table.Key, -1), cc.Name))
+			columns = append(columns, cc.Name)
 		}
 
-		// log.Printf("SQL: %s", sqlst)
-		r.Reply(&Statement{
-			Query:   sqlst,
-			Tables:  tableMeta,
-			Columns: len(tableColumns),
-			Graph:   graph,
-		})
-	})
+		//collect table info for particular table
+		tableMeta[table.Key] = &TableInfo{
+			Alias:       table.Key,
+			ParentAlias: table.PKey,
+			Name:        table.Name,
+			Parent:      table.Parent,
+			Columns:     columns,
+			Hidden:      table.Hidden,
+			Begin:       lastColumSize,
+			End:         (lastColumSize + (len(columns) - 1)),
+			Kind:        table.Kind,
+			Node:        table.Node,
+			Graph:       table.Graph,
+		}
+
+		lastColumSize = len(tableColumns)
+
+		// for _,clo := range table.Conditions {
+		// }
+		//join the conditions of this table with a AND
+		clos := strings.Join(table.Conditions, "\nAND ")
+
+		//replace both {{table}} and {{parentTable}} with the appropriate names/tags
+		// log.Printf("setting alias:", table.Key, table.PKey, table.Name)
+		clos = strings.Replace(clos, "{{table}}", table.Key, -1)
+		clos = strings.Replace(clos, "{{parentTable}}", table.PKey, -1)
+
+		//add this condition to the global where list
+		tableWheres = append(tableWheres, clos)
+		tableArgs = append(tableArgs, table.Args...)
+	}
+
+	var sqlst = SQLSimpleSelect
+
+	sqlst = strings.Replace(sqlst, "{{columns}}", strings.Join(tableColumns, ", "), -1)
+	sqlst = strings.Replace(sqlst, "{{tables}}", strings.Join(tableNames, ", "), -1)
+
+	//clean where clauses of an empty strings or only spaces
+	tableWheres = adaptors.CleanHouse(tableWheres)
+
+	if len(tableWheres) < 2 {
+		sqlst = strings.Replace(sqlst, "{{clauses}}", strings.Join(tableWheres, " "), -1)
+	} else {
+		sqlst = strings.Replace(sqlst, "{{clauses}}", strings.Join(tableWheres, "\nAND "), -1)
+	}
+
+	//the parent keys of a batch come before its args, so it is rebound once they replace its marker
+	if !strings.Contains(sqlst, keysMarker) {
+		sqlst = Rebind(sqlst, placeholder)
+	}
+
+	return &Statement{
+		Query:   sqlst,
+		Args:    tableArgs,
+		Tables:  tableMeta,
+		Columns: len(tableColumns),
+		Graph:   graph,
+
+		Placeholder: placeholder,
+	}
 }
 
 //ErrInvalidTableData represent the error when the data type does not match the Tables type
 var ErrInvalidStatementType = errors.New("Data type not *Statement")
 
-//DbExecutor returns a reactor that takes a sql.Db for execution of queries
+//DbExecutor returns a reactor that takes a sql.Db for execution of queries, statements already served from a Cache are passed on as they are
 func DbExecutor(db *sql.DB) flux.Reactor {
+	return ConcurrentDbExecutor(db, 1)
+}
+
+//ConcurrentDbExecutor returns a DbExecutor running the sibling batches of each statement with at most limit queries at once, see ExecuteConcurrent
+func ConcurrentDbExecutor(db *sql.DB, limit int) flux.Reactor {
 	return flux.Reactive(func(r flux.Reactor, err error, d interface{}) {
 		if err != nil {
 			r.ReplyError(err)
@@ -265,33 +514,72 @@ func DbExecutor(db *sql.DB) flux.Reactor {
 			return
 		}
 
-		rows, err := db.Query(stl.Query)
+		if stl.Cached {
+			r.Reply(stl)
+			return
+		}
 
-		if err != nil {
+		if err := ExecuteConcurrent(context.Background(), db, stl, limit); err != nil {
 			r.ReplyError(err)
 			return
 		}
 
-		var datarows [][]interface{}
+		r.Reply(stl)
+	})
+}
 
-		defer rows.Close()
+// ExecuteStatement runs the statement's query on the database, storing the scanned rows in its Data, then runs its batches in order for the keys of those rows
+func ExecuteStatement(ctx context.Context, db *sql.DB, stl *Statement) error {
+	return ExecuteConcurrent(ctx, db, stl, 1)
+}
 
-		for rows.Next() {
-			bu := adaptors.BuildInterfacePoints(stl.Columns)
+// ExecuteConcurrent runs the statement like ExecuteStatement but runs sibling batches at once with at most limit queries in flight on the database,
+// the first failed query cancels the others. Each batch keeps its own rows so results do not depend on the order queries finish in
+func ExecuteConcurrent(ctx context.Context, db *sql.DB, stl *Statement, limit int) error {
+	rows, err := queryRows(ctx, db, stl.Query, stl.Args, stl.Columns)
 
-			err := rows.Scan(bu...)
+	if err != nil {
+		return err
+	}
 
-			if err != nil {
-				r.ReplyError(err)
-				return
-			}
+	stl.Data = rows
+
+	var slots chan struct{}
+
+	if limit > 1 {
+		slots = make(chan struct{}, limit)
+	}
+
+	return executeBatches(ctx, db, stl, slots)
+}
 
-			datarows = append(datarows, bu)
+// queryRows runs the query on the database and returns its scanned rows of the giving number of columns
+func queryRows(ctx context.Context, db *sql.DB, query string, args []interface{}, columns int) ([][]interface{}, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var datarows [][]interface{}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		bu := adaptors.BuildInterfacePoints(columns)
+
+		if err := rows.Scan(bu...); err != nil {
+			return nil, err
 		}
 
-		stl.Data = adaptors.UnbuildInterfaceList(datarows)
-		r.Reply(stl)
-	})
+		datarows = append(datarows, bu)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return adaptors.UnbuildInterfaceList(datarows), nil
 }
 
 //TableSection represents a single data composition tree per sql record row representing the retrieved data
@@ -319,74 +607,231 @@ func JSONBuilder() flux.Reactor {
 			return
 		}
 
-		for _, blck := range stl.Data {
-			func(block []interface{}) {
-				// orecord := make(RecordBlock)
-				// records = append(records, orecord)
-				for _, ifo := range stl.Tables {
-					func(info *TableInfo) {
-						section := make(TableSection)
-						max := info.End - info.Begin
-
-						for j := 0; j <= max; j++ {
-							func(ind int) {
-								col := info.Columns[ind]
-								section[col] = block[info.Begin+ind]
-							}(j)
-						}
-
-						// info.Node.Result = section
-						info.Node.Result = append(info.Node.Result, section)
-						// orecord[info.Alias] = section
-					}(ifo)
-				}
+		tree, err := BuildJSON(stl)
 
-			}(blck)
+		if err != nil {
+			r.ReplyError(err)
+			return
 		}
 
-		mo, err := adaptors.BFGraph(stl.Graph)
+		r.Reply(tree)
+	})
+}
+
+// BuildJSON builds the record tree of the statement's rows, keyed by the name of its root record, with the records of its batches nested under their parents
+func BuildJSON(stl *Statement) (map[string]interface{}, error) {
+	tree, err := buildTree(stl)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, batch := range stl.Batches {
+		sub, err := BuildJSON(batch)
 
 		if err != nil {
-			r.ReplyError(err)
-			return
+			return nil, err
 		}
 
-		var roots = make(map[string]*parser.ParseNode)
-		var tree = make(map[string]interface{})
-		var root *parser.ParseNode
+		stitchBatch(stl, tree, batch, sub)
+	}
 
-		for mo.Next() == nil {
-			no := mo.Node().(*parser.ParseNode)
+	hideColumns(stl, tree)
+	return tree, nil
+}
 
-			if root == nil {
-				root = no
+// buildTree builds the record tree of the statement's own rows
+func buildTree(stl *Statement) (map[string]interface{}, error) {
+	//clear the results of any earlier execution of the same statement
+	for _, info := range stl.Tables {
+		if info.Node != nil {
+			info.Node.Result = nil
+		}
+	}
+
+	//declared has-many relations need their parent rows merged and their children collected as lists
+	if stl.Tables.HasMany() {
+		return nestStatement(stl), nil
+	}
+
+	for _, blck := range stl.Data {
+		func(block []interface{}) {
+			// orecord := make(RecordBlock)
+			// records = append(records, orecord)
+			for _, ifo := range stl.Tables {
+				func(info *TableInfo) {
+					section := make(TableSection)
+					max := info.End - info.Begin
+
+					for j := 0; j <= max; j++ {
+						func(ind int) {
+							col := info.Columns[ind]
+							section[col] = block[info.Begin+ind]
+						}(j)
+					}
+
+					// info.Node.Result = section
+					info.Node.Result = append(info.Node.Result, section)
+					// orecord[info.Alias] = section
+				}(ifo)
 			}
 
-			if _, ok := roots[no.Key]; !ok {
-				roots[no.Key] = no
-			}
+		}(blck)
+	}
+
+	mo, err := adaptors.BFGraph(stl.Graph)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var roots = make(map[string]*parser.ParseNode)
+	var tree = make(map[string]interface{})
+	var root *parser.ParseNode
+
+	for mo.Next() == nil {
+		no := mo.Node().(*parser.ParseNode)
+
+		//records of batches are built by their own statements
+		if _, ok := stl.Tables[no.Key]; !ok {
+			continue
+		}
+
+		if root == nil {
+			root = no
+		}
+
+		if _, ok := roots[no.Key]; !ok {
+			roots[no.Key] = no
+		}
+
+		rod, ok := roots[no.PKey]
+
+		if !ok {
+			continue
+		}
+
+		for n, rorec := range rod.Result {
+			rorec[no.Name()] = no.Result[n]
+		}
+	}
+
+	// res := root.Result
+	tree[root.Name()] = root.Result
+	return tree, nil
+}
+
+// HasMany returns true if any table in the meta is the child of a has-many or many-to-many relation
+func (t TableMeta) HasMany() bool {
+	for _, info := range t {
+		if info.Kind.Many() {
+			return true
+		}
+	}
+	return false
+}
+
+// nestStatement builds the json structure of a statement by merging the rows of each record and nesting children as objects for has-one relations or lists for has-many relations
+func nestStatement(stl *Statement) map[string]interface{} {
+	var root *TableInfo
+	children := make(map[string][]*TableInfo)
+
+	aliases := make(map[string]bool)
+
+	for _, info := range stl.Tables {
+		aliases[info.Alias] = true
+	}
+
+	for _, info := range stl.Tables {
+		if info.ParentAlias == "" || !aliases[info.ParentAlias] {
+			root = info
+			continue
+		}
+		children[info.ParentAlias] = append(children[info.ParentAlias], info)
+	}
+
+	tree := make(map[string]interface{})
+
+	if root == nil {
+		return tree
+	}
 
-			rod, ok := roots[no.PKey]
+	for _, infos := range children {
+		sort.Sort(byBegin(infos))
+	}
 
-			if !ok {
+	tree[root.Name] = nestRows(stl.Data, root, children)
+	return tree
+}
+
+// nestRows merges the rows sharing the values of the table and its singular children into single records, nesting the children of each record
+func nestRows(rows [][]interface{}, info *TableInfo, children map[string][]*TableInfo) []TableSection {
+	var order []string
+	groups := make(map[string][][]interface{})
+
+	for _, row := range rows {
+		key := identity(row, info, children)
+
+		if _, ok := groups[key]; !ok {
+			order = append(order, key)
+		}
+
+		groups[key] = append(groups[key], row)
+	}
+
+	var records []TableSection
+
+	for _, key := range order {
+		grows := groups[key]
+		section := make(TableSection)
+
+		for ind, col := range info.Columns {
+			section[col] = grows[0][info.Begin+ind]
+		}
+
+		for _, child := range children[info.Alias] {
+			recs := nestRows(grows, child, children)
+
+			if child.Kind.Many() {
+				section[child.Name] = recs
 				continue
 			}
 
-			for n, rorec := range rod.Result {
-				rorec[no.Name()] = no.Result[n]
+			if len(recs) > 0 {
+				section[child.Name] = recs[0]
 			}
 		}
 
-		// res := root.Result
-		tree[root.Name()] = root.Result
-		stl = nil
-		roots = nil
-		root = nil
+		records = append(records, section)
+	}
 
-		r.Reply(tree)
-	})
+	return records
+}
+
+// identity returns the values of a table's columns and those of its singular children in a row as a comparable key
+func identity(row []interface{}, info *TableInfo, children map[string][]*TableInfo) string {
+	var key bytes.Buffer
+
+	for ind := range info.Columns {
+		fmt.Fprintf(&key, "%v|", row[info.Begin+ind])
+	}
+
+	for _, child := range children[info.Alias] {
+		if !child.Kind.Many() {
+			key.WriteString(identity(row, child, children))
+		}
+	}
+
+	return key.String()
 }
 
+// byBegin sorts TableInfos by their column position in the statement
+type byBegin []*TableInfo
+
+func (b byBegin) Len() int           { return len(b) }
+func (b byBegin) Less(i, j int) bool { return b[i].Begin < b[j].Begin }
+func (b byBegin) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
+
 // BuildPreQuero generates a sql parser without any attachement to the sql record query Reactor
 func BuildPreQuero(op, sp *parser.OPFactory, ds *parser.InspectionFactory) flux.Reactor {
 	co := adaptors.ChunkParser(ds)
@@ -403,6 +848,49 @@ func BuildQuero(db *sql.DB, op, sp *parser.OPFactory, ds *parser.InspectionFacto
 	return co
 }
 
+// BuildCachedQuero generates a full sql query parser whose statements with a 'cache' rule are served from and stored in the cache
+func BuildCachedQuero(db *sql.DB, c Cache, op, sp *parser.OPFactory, ds *parser.InspectionFactory) flux.Reactor {
+	co := BuildPreQuero(op, sp, ds)
+	co.Bind(CacheLookup(c), true)
+	co.Bind(DbExecutor(db), true)
+	co.Bind(CacheStore(c), true)
+	co.Bind(JSONBuilder(), true)
+	return co
+}
+
+// BuildRelationPreQuero generates a sql parser that resolves child records through the RelationResolver eg a *Relations registry or *Catalog
+func BuildRelationPreQuero(rs RelationResolver, op, sp *parser.OPFactory, ds *parser.InspectionFactory) flux.Reactor {
+	co := adaptors.ChunkParser(ds)
+	co.Bind(RelationTableBuilder(op, sp, rs), true)
+	co.Bind(TableParser(), true)
+	return co
+}
+
+// BuildRelationQuero generates a full sql query parser that resolves child records through the RelationResolver for instance use
+func BuildRelationQuero(db *sql.DB, rs RelationResolver, op, sp *parser.OPFactory, ds *parser.InspectionFactory) flux.Reactor {
+	co := BuildRelationPreQuero(rs, op, sp, ds)
+	co.Bind(DbExecutor(db), true)
+	co.Bind(JSONBuilder(), true)
+	return co
+}
+
+// BuildSchemaPreQuero generates a sql parser that infers relations from and validates every query against the catalog before producing its statement
+func BuildSchemaPreQuero(cat *Catalog, op, sp *parser.OPFactory, ds *parser.InspectionFactory) flux.Reactor {
+	co := adaptors.ChunkParser(ds)
+	co.Bind(RelationTableBuilder(op, sp, cat), true)
+	co.Bind(SchemaValidator(cat), true)
+	co.Bind(TableParser(), true)
+	return co
+}
+
+// BuildSchemaQuero generates a full sql query parser validated against the catalog for instance use
+func BuildSchemaQuero(db *sql.DB, cat *Catalog, op, sp *parser.OPFactory, ds *parser.InspectionFactory) flux.Reactor {
+	co := BuildSchemaPreQuero(cat, op, sp, ds)
+	co.Bind(DbExecutor(db), true)
+	co.Bind(JSONBuilder(), true)
+	return co
+}
+
 // BasicQueroEngine produces an engine with the default query handlers
 func BasicQueroEngine() flux.Reactor {
 	return BuildPreQuero(TemplatesQueries, RelQueries, parser.DefaultInspectionFactory)
@@ -413,6 +901,31 @@ func Quero(db *sql.DB) flux.Reactor {
 	return BuildQuero(db, TemplatesQueries, RelQueries, parser.DefaultInspectionFactory)
 }
 
+// CachedQuero returns a complete sql query handler using the default query formatters whose results are cached by their 'cache' rule
+func CachedQuero(db *sql.DB, c Cache) flux.Reactor {
+	return BuildCachedQuero(db, c, TemplatesQueries, RelQueries, parser.DefaultInspectionFactory)
+}
+
+// RelationQuero returns a complete sql query handler using the default query formatters whose child records are resolved through the RelationResolver
+func RelationQuero(db *sql.DB, rs RelationResolver) flux.Reactor {
+	return BuildRelationQuero(db, rs, TemplatesQueries, RelQueries, parser.DefaultInspectionFactory)
+}
+
+// SchemaQuero returns a complete sql query handler using the default query formatters whose queries are validated against the catalog
+func SchemaQuero(db *sql.DB, cat *Catalog) flux.Reactor {
+	return BuildSchemaQuero(db, cat, TemplatesQueries, RelQueries, parser.DefaultInspectionFactory)
+}
+
+// PolicyQuero returns a complete sql query handler using the default query formatters whose queries are authorized against the policy for the principal
+func PolicyQuero(db *sql.DB, po *adaptors.Policy, pr *adaptors.Principal) flux.Reactor {
+	co := adaptors.PolicyParser(parser.DefaultInspectionFactory, po, pr)
+	co.Bind(TableBuilder(TemplatesQueries, RelQueries), true)
+	co.Bind(TableParser(), true)
+	co.Bind(DbExecutor(db), true)
+	co.Bind(JSONBuilder(), true)
+	return co
+}
+
 // QueroJSON attaches a json reactor that marshalls all response out as a json string
 func QueroJSON(db *sql.DB) flux.Reactor {
 	qo := Quero(db)
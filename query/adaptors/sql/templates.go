@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/influx6/data/query/adaptors"
 	"github.com/influx6/data/query/parser"
 )
 
@@ -29,7 +30,7 @@ func AddSQLQueryHandlers(op *parser.OPFactory) {
 			return nil, ErrNoValue
 		}
 
-		val := c.Get("value")
+		val := bindValue(c, c.Get("value"))
 		return []string{fmt.Sprintf("{{table}}.%s = %s", name, val)}, nil
 	})
 
@@ -79,14 +80,19 @@ func AddSQLQueryHandlers(op *parser.OPFactory) {
 			return nil, ErrNoValue
 		}
 
+		items, ok := adaptors.InValues(c)
+
+		if !ok {
+			return nil, parser.ErrInvalidCollector
+		}
+
 		var inwords []string
-		ranges := c.Get("range").([]string)
 
-		for _, ins := range ranges {
-			inwords = append(inwords, fmt.Sprintf("{{table}}.%s = %s", name, ins))
+		for _, ins := range items {
+			inwords = append(inwords, fmt.Sprintf("{{table}}.%s = %v", name, bindValue(c, ins)))
 		}
 
-		return []string{strings.Join(inwords, "\nOR ")}, nil
+		return []string{fmt.Sprintf("(%s)", strings.Join(inwords, "\nOR "))}, nil
 	})
 
 	op.Add("is", func(name string, c parser.Collector) ([]string, error) {
@@ -95,7 +101,12 @@ func AddSQLQueryHandlers(op *parser.OPFactory) {
 			return nil, ErrNoValue
 		}
 
-		val := c.Get("value")
+		//'= NULL' never matches, so null values are checked with IS NULL
+		if isNull(c.Get("value")) {
+			return []string{fmt.Sprintf("{{table}}.%s IS NULL", name)}, nil
+		}
+
+		val := bindValue(c, c.Get("value"))
 
 		return []string{fmt.Sprintf("{{table}}.%s = %s", name, val)}, nil
 	})
@@ -106,10 +117,17 @@ func AddSQLQueryHandlers(op *parser.OPFactory) {
 			return nil, ErrNoValue
 		}
 
-		val := c.Get("value")
+		if isNull(c.Get("value")) {
+			return []string{fmt.Sprintf("{{table}}.%s IS NOT NULL", name)}, nil
+		}
+
+		val := bindValue(c, c.Get("value"))
 		return []string{fmt.Sprintf("{{table}}.%s != %s", name, val)}, nil
 	})
 
+	op.Add("isnull", nullHandler(true))
+	op.Add("notnull", nullHandler(false))
+
 	op.Add("range", func(name string, c parser.Collector) ([]string, error) {
 
 		if !c.Has("max") && !c.Has("min") {
@@ -128,6 +146,48 @@ func AddSQLQueryHandlers(op *parser.OPFactory) {
 	})
 }
 
+// bindValue returns the '?' placeholder for a parser.Param value and records the value within the collector's args,any other value is returned as is
+func bindValue(c parser.Collector, val interface{}) interface{} {
+	pm, ok := val.(parser.Param)
+
+	if !ok {
+		return val
+	}
+
+	args, _ := c.Get(parser.ArgsKey).([]interface{})
+	c.Set(parser.ArgsKey, append(args, pm.Value))
+
+	return "?"
+}
+
+// isNull returns true if the condition value is nil or the literal null
+func isNull(val interface{}) bool {
+	switch mo := adaptors.Unwrap(val).(type) {
+	case nil:
+		return true
+	case string:
+		return strings.EqualFold(strings.TrimSpace(mo), "null")
+	}
+	return false
+}
+
+// nullHandler returns a handler checking the column with IS NULL when the collector's boolean equals null and IS NOT NULL otherwise
+func nullHandler(null bool) parser.ParseFx {
+	return func(name string, c parser.Collector) ([]string, error) {
+		val, ok := c.Get("value").(bool)
+
+		if !ok {
+			return nil, ErrNoValue
+		}
+
+		if val == null {
+			return []string{fmt.Sprintf("{{table}}.%s IS NULL", name)}, nil
+		}
+
+		return []string{fmt.Sprintf("{{table}}.%s IS NOT NULL", name)}, nil
+	}
+}
+
 // AddSQLRelHandlers provides handlers for sql special keys tags
 func AddSQLRelHandlers(op *parser.OPFactory) {
 	op.Add("with", func(name string, c parser.Collector) ([]string, error) {
@@ -143,5 +203,8 @@ func AddSQLRelHandlers(op *parser.OPFactory) {
 
 func init() {
 	AddSQLQueryHandlers(TemplatesQueries)
+	AddPatternHandlers(TemplatesQueries, MySQL)
+	AddDateHandlers(TemplatesQueries, MySQL)
+	AddFunctionHandlers(TemplatesQueries, MySQL)
 	AddSQLRelHandlers(RelQueries)
 }
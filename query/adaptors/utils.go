@@ -1,6 +1,7 @@
 package adaptors
 
 import (
+	"reflect"
 	"regexp"
 
 	"github.com/influx6/data/query/parser"
@@ -132,6 +133,22 @@ func UnbuildInterfaceList(mu [][]interface{}) [][]interface{} {
 
 var onlySpaces = regexp.MustCompile(`^\s+$`)
 
+//InValues returns the items of an 'in' condition, either the words of its query list or the values bound to it eg the keys of federated parent records
+func InValues(c parser.Collector) ([]interface{}, bool) {
+	switch mo := c.Get("range").(type) {
+	case []string:
+		var items []interface{}
+		for _, item := range CleanHouse(mo) {
+			items = append(items, item)
+		}
+		return items, true
+	case []interface{}:
+		return mo, true
+	}
+
+	return nil, false
+}
+
 //CleanHouse cleans all spaces out of list of strings
 func CleanHouse(ls []string) []string {
 	var clean []string
@@ -143,3 +160,37 @@ func CleanHouse(ls []string) []string {
 
 	return clean
 }
+
+// Plain returns a copy of a result value with byte slices turned into strings and every map or list type made a plain map[string]interface{} or []interface{}, ready for encoding
+func Plain(val interface{}) interface{} {
+	if bs, ok := val.([]byte); ok {
+		return string(bs)
+	}
+
+	rv := reflect.ValueOf(val)
+
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return val
+		}
+
+		mo := make(map[string]interface{})
+
+		for _, key := range rv.MapKeys() {
+			mo[key.String()] = Plain(rv.MapIndex(key).Interface())
+		}
+
+		return mo
+	case reflect.Slice:
+		list := make([]interface{}, rv.Len())
+
+		for index := range list {
+			list[index] = Plain(rv.Index(index).Interface())
+		}
+
+		return list
+	}
+
+	return val
+}
@@ -0,0 +1,75 @@
+package adaptors
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/influx6/data/query/parser"
+	"github.com/influx6/ds"
+)
+
+//UnknownVariableMessage provides error for condition values naming variables that were not supplied
+const UnknownVariableMessage = "Variable '$%s' is not defined"
+
+// VariableName returns the name of a condition value of the form $name
+func VariableName(val interface{}) (string, bool) {
+	str, ok := val.(string)
+
+	if !ok {
+		return "", false
+	}
+
+	str = strings.TrimSpace(str)
+
+	if len(str) < 2 || str[0] != '$' {
+		return "", false
+	}
+
+	return str[1:], true
+}
+
+// BindVariables replaces each condition value of the form $name eg users(name: $name) with the parser.Param of the named variable,
+// so adaptors bind the value as an argument instead of placing it within the query
+func BindVariables(gs ds.Graphs, vars map[string]interface{}) error {
+	mo, err := DFGraph(gs)
+
+	if err != nil {
+		return err
+	}
+
+	for mo.Next() == nil {
+		node := mo.Node().(*parser.ParseNode)
+
+		var err error
+
+		bind := func(field string, c parser.Collector, stop func()) {
+			name, ok := VariableName(c.Get("value"))
+
+			if !ok {
+				return
+			}
+
+			val, ok := vars[name]
+
+			if !ok {
+				err = node.Report(field, fmt.Sprintf(UnknownVariableMessage, name))
+				stop()
+				return
+			}
+
+			c.Set("value", parser.Param{Value: val})
+		}
+
+		node.Rules.EachCondition(bind)
+
+		if err == nil {
+			node.Records.EachCondition(bind)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,269 @@
+package adaptors
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/influx6/ds"
+	"github.com/influx6/flux"
+)
+
+// DefaultPrimaryKey is the field identifying the records of a watched query when the engine has no PrimaryKey
+const DefaultPrimaryKey = "id"
+
+// ErrInvalidInterval is returned when a query is watched with an interval that is not above zero
+var ErrInvalidInterval = errors.New("Watch interval must be above zero")
+
+// ChangeKind defines how a record changed between two executions of a watched query
+type ChangeKind int
+
+const (
+	//Added represents a record missing from the previous results
+	Added ChangeKind = iota + 1
+	//Changed represents a record whose fields or child records differ from the previous results
+	Changed
+	//Removed represents a record missing from the new results
+	Removed
+)
+
+// String returns the name of the change kind
+func (c ChangeKind) String() string {
+	switch c {
+	case Added:
+		return "added"
+	case Changed:
+		return "changed"
+	case Removed:
+		return "removed"
+	}
+	return "unknown"
+}
+
+// MarshalJSON encodes the change kind as its name
+func (c ChangeKind) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.String())
+}
+
+// Change defines a record added, changed or removed between two executions, Previous is the record before a change or removal
+type Change struct {
+	Kind     ChangeKind             `json:"kind"`
+	Key      string                 `json:"key"`
+	Record   map[string]interface{} `json:"record,omitempty"`
+	Previous map[string]interface{} `json:"previous,omitempty"`
+}
+
+// Diff defines the changes to the records of a root record between two executions of a watched query, ordered by the keys of the records.
+// The first Diff of a root record adds all of its records, a Diff with an Err reports a failed execution and keeps the previous results
+type Diff struct {
+	Record  string    `json:"record"`
+	Changes []Change  `json:"changes,omitempty"`
+	Err     error     `json:"-"`
+	At      time.Time `json:"at"`
+}
+
+// watched defines a root record of a watched query with its compiled plan and last records keyed by their primary key
+type watched struct {
+	name    string
+	graph   ds.Graphs
+	adaptor Adaptor
+	plan    Plan
+	last    map[string]map[string]interface{}
+	primed  bool
+}
+
+// Watch executes the query every interval until the context is done, sending a Diff for each root record whose records changed.
+// Each root record is compiled once and its plan re-executed, records are matched across executions by the engine's PrimaryKey
+func (e *Engine) Watch(ctx context.Context, query string, interval time.Duration) (<-chan Diff, error) {
+	if interval <= 0 {
+		return nil, ErrInvalidInterval
+	}
+
+	graphs, err := Parse(e.inspect, query)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var roots []*watched
+
+	for _, gs := range graphs {
+		fd, err := e.split(gs)
+
+		if err != nil {
+			return nil, err
+		}
+
+		wd := &watched{name: fd.tree.root.Name(), graph: gs}
+
+		//federated queries copy their graphs on every execution, so only single adaptor queries keep a compiled plan
+		if len(fd.segments) == 1 {
+			wd.adaptor = fd.segments[0].adaptor
+
+			if wd.plan, err = wd.adaptor.Compile(gs); err != nil {
+				return nil, err
+			}
+		}
+
+		roots = append(roots, wd)
+	}
+
+	diffs := make(chan Diff)
+
+	go func() {
+		defer close(diffs)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			for _, wd := range roots {
+				diff, ok := e.poll(ctx, wd)
+
+				if !ok {
+					continue
+				}
+
+				select {
+				case diffs <- diff:
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return diffs, nil
+}
+
+// poll executes the watched record and returns the Diff against its last records, it is false when nothing changed
+func (e *Engine) poll(ctx context.Context, wd *watched) (Diff, bool) {
+	diff := Diff{Record: wd.name, At: time.Now()}
+
+	var res Result
+	var err error
+
+	if wd.plan != nil {
+		res, err = wd.adaptor.Execute(ctx, wd.plan)
+	} else {
+		res, err = e.Execute(ctx, wd.graph)
+	}
+
+	if err != nil {
+		if ctx.Err() != nil {
+			return diff, false
+		}
+
+		diff.Err = err
+		return diff, true
+	}
+
+	key := e.PrimaryKey
+
+	if key == "" {
+		key = DefaultPrimaryKey
+	}
+
+	current := make(map[string]map[string]interface{})
+
+	for _, rec := range asRecords(res[wd.name]) {
+		plain, _ := Plain(rec).(map[string]interface{})
+		current[recordKey(plain, key)] = plain
+	}
+
+	diff.Changes = Changes(wd.last, current)
+	wd.last = current
+
+	if !wd.primed {
+		wd.primed = true
+		return diff, true
+	}
+
+	return diff, len(diff.Changes) > 0
+}
+
+// recordKey returns the primary key of the record, records without one are keyed by their json form
+func recordKey(rec map[string]interface{}, key string) string {
+	if val, ok := rec[key]; ok && val != nil {
+		return KeyOf(val)
+	}
+
+	data, _ := json.Marshal(rec)
+	return string(data)
+}
+
+// Changes returns the changes between two sets of records keyed by their primary key, ordered by key
+func Changes(previous, current map[string]map[string]interface{}) []Change {
+	var changes []Change
+
+	for key, rec := range current {
+		old, ok := previous[key]
+
+		switch {
+		case !ok:
+			changes = append(changes, Change{Kind: Added, Key: key, Record: rec})
+		case !reflect.DeepEqual(old, rec):
+			changes = append(changes, Change{Kind: Changed, Key: key, Record: rec, Previous: old})
+		}
+	}
+
+	for key, old := range previous {
+		if _, ok := current[key]; !ok {
+			changes = append(changes, Change{Kind: Removed, Key: key, Previous: old})
+		}
+	}
+
+	sort.Sort(byKey(changes))
+	return changes
+}
+
+// byKey sorts changes by the key of their records
+type byKey []Change
+
+func (b byKey) Len() int           { return len(b) }
+func (b byKey) Less(i, j int) bool { return b[i].Key < b[j].Key }
+func (b byKey) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
+
+// WatchAdaptor returns a reactor that watches each query string it receives through the engine until the context is done, replying with every Diff
+func WatchAdaptor(ctx context.Context, e *Engine, interval time.Duration) flux.Reactor {
+	return flux.Reactive(func(r flux.Reactor, err error, d interface{}) {
+		if err != nil {
+			r.ReplyError(err)
+			return
+		}
+
+		query, ok := d.(string)
+
+		if !ok {
+			r.ReplyError(ErrInputTytpe)
+			return
+		}
+
+		diffs, err := e.Watch(ctx, query, interval)
+
+		if err != nil {
+			r.ReplyError(err)
+			return
+		}
+
+		go func() {
+			for diff := range diffs {
+				if diff.Err != nil {
+					r.ReplyError(diff.Err)
+					continue
+				}
+
+				r.Reply(diff)
+			}
+		}()
+	})
+}
@@ -0,0 +1,157 @@
+package parser
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//InvalidDateMessage provides error for date condition values that are neither ISO-8601 dates nor relative durations
+const InvalidDateMessage = "Invalid date %s, expected an ISO-8601 date eg 2016-01-02T15:04:05Z, now or a duration eg 7d"
+
+// DateLayouts are the ISO-8601 layouts accepted by date conditions, dates without a zone are in UTC
+var DateLayouts = []string{
+	time.RFC3339Nano,
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02",
+}
+
+// DateUnits are the units of relative durations with the length of each
+var DateUnits = map[string]time.Duration{
+	"w": 7 * 24 * time.Hour,
+	"d": 24 * time.Hour,
+	"h": time.Hour,
+	"m": time.Minute,
+	"s": time.Second,
+}
+
+var offsetPart = regexp.MustCompile(`^(\d+)([wdhms])`)
+
+// DateExpr defines the value of a date condition, either an absolute Time or an Offset from the time the condition is evaluated at.
+// Offsets are written as durations eg 7d, 12h or 1w2d and are in the past unless signed with a '+', so 'after: 7d' is after seven days ago
+type DateExpr struct {
+	Time     time.Time
+	Offset   time.Duration
+	Relative bool
+	text     string
+}
+
+// ParseDate returns the DateExpr of an ISO-8601 date, the word now or a relative duration
+func ParseDate(val string) (DateExpr, error) {
+	text := Unquote(val)
+
+	if strings.EqualFold(text, "now") {
+		return DateExpr{Relative: true, text: text}, nil
+	}
+
+	for _, layout := range DateLayouts {
+		if tm, err := time.Parse(layout, text); err == nil {
+			return DateExpr{Time: tm, text: text}, nil
+		}
+	}
+
+	offset, err := parseOffset(text)
+
+	if err != nil {
+		return DateExpr{}, err
+	}
+
+	return DateExpr{Offset: offset, Relative: true, text: text}, nil
+}
+
+// parseOffset returns the signed duration of a relative date, negative unless it starts with a '+'
+func parseOffset(text string) (time.Duration, error) {
+	sign := time.Duration(-1)
+	rest := text
+
+	switch {
+	case strings.HasPrefix(rest, "+"):
+		sign = 1
+		rest = rest[1:]
+	case strings.HasPrefix(rest, "-"):
+		rest = rest[1:]
+	}
+
+	if rest == "" {
+		return 0, fmt.Errorf(InvalidDateMessage, text)
+	}
+
+	var offset time.Duration
+
+	for rest != "" {
+		part := offsetPart.FindStringSubmatch(rest)
+
+		if part == nil {
+			return 0, fmt.Errorf(InvalidDateMessage, text)
+		}
+
+		num, err := strconv.Atoi(part[1])
+
+		if err != nil {
+			return 0, fmt.Errorf(InvalidDateMessage, text)
+		}
+
+		offset += time.Duration(num) * DateUnits[part[2]]
+		rest = rest[len(part[0]):]
+	}
+
+	return sign * offset, nil
+}
+
+// DateValue returns the DateExpr of a date condition value, which may also be a bound Param holding a time.Time or a date string
+func DateValue(val interface{}) (DateExpr, error) {
+	if pm, ok := val.(Param); ok {
+		val = pm.Value
+	}
+
+	switch mo := val.(type) {
+	case DateExpr:
+		return mo, nil
+	case time.Time:
+		return DateExpr{Time: mo}, nil
+	case *time.Time:
+		if mo != nil {
+			return DateExpr{Time: *mo}, nil
+		}
+	case string:
+		return ParseDate(mo)
+	}
+
+	return DateExpr{}, fmt.Errorf(InvalidDateMessage, fmt.Sprintf("%v", val))
+}
+
+// Resolve returns the time of the expression when evaluated at now
+func (d DateExpr) Resolve(now time.Time) time.Time {
+	if d.Relative {
+		return now.Add(d.Offset)
+	}
+	return d.Time
+}
+
+// String returns the expression as written
+func (d DateExpr) String() string {
+	if d.text != "" {
+		return d.text
+	}
+
+	if d.Relative {
+		return d.Offset.String()
+	}
+
+	return d.Time.Format(time.RFC3339Nano)
+}
+
+// Within returns the earliest and latest times of the period between now and the relative expression
+func (d DateExpr) Within(now time.Time) (time.Time, time.Time) {
+	at := d.Resolve(now)
+
+	if at.Before(now) {
+		return at, now
+	}
+
+	return now, at
+}
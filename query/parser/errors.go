@@ -23,6 +23,14 @@ type (
 	SocketNotMadeError struct {
 		to, from string
 	}
+
+	//PositionError represents an error tied to a line and position within a query
+	PositionError struct {
+		Message string
+		Cause   string
+		Line    int
+		Pos     int
+	}
 )
 
 //NewSocketNotMade returns a new sock error
@@ -37,3 +45,12 @@ func NewSocketNotMade(to, from string) SocketNotMadeError {
 func (s SocketNotMadeError) Error() string {
 	return fmt.Sprintf("BindError between %+s and %+s", s.to, s.from)
 }
+
+//Error returns a string representation of the error
+func (p *PositionError) Error() string {
+	return fmt.Sprintf(`
+A disturbance in the Force in Line: %d, Pos: %d:
+  -  Cause: '%s'
+  -  Why: %s
+`, p.Line, p.Pos, p.Cause, p.Message)
+}
@@ -0,0 +1,534 @@
+package parser
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+//UnknownFunctionMessage provides error for computed fields calling functions that are not registered
+const UnknownFunctionMessage = "Function '%s' is not registered"
+
+//FunctionArityMessage provides error for functions called with too few or too many arguments
+const FunctionArityMessage = "Function '%s' takes %s arguments but got %d"
+
+//InvalidExpressionMessage provides error for computed fields whose expression can not be parsed
+const InvalidExpressionMessage = "Invalid expression %q: %s"
+
+//InvalidComputedMessage provides error for computed fields whose name is not an identifier
+const InvalidComputedMessage = "Computed field '%s' needs a name of letters, digits and underscores"
+
+//DuplicateComputedMessage provides error for computed fields named after another field of their record
+const DuplicateComputedMessage = "Computed field '%s' is already a field of its record"
+
+// identifier matches the names of computed fields and the columns and functions of their expressions
+var identifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Expr defines a node of the expression of a computed field
+type Expr interface {
+	String() string
+}
+
+// ColumnExpr defines a column of the record
+type ColumnExpr struct {
+	Name string
+}
+
+// String returns the column name
+func (c *ColumnExpr) String() string {
+	return c.Name
+}
+
+// LiteralExpr defines a number, string, boolean or null value, numbers are float64 and null is nil
+type LiteralExpr struct {
+	Value interface{}
+}
+
+// String returns the literal as written in a query
+func (l *LiteralExpr) String() string {
+	switch mo := l.Value.(type) {
+	case nil:
+		return "null"
+	case float64:
+		return strconv.FormatFloat(mo, 'f', -1, 64)
+	case string:
+		return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(mo) + `"`
+	}
+	return fmt.Sprintf("%v", l.Value)
+}
+
+// CallExpr defines a call of a registered function
+type CallExpr struct {
+	Func string
+	Args []Expr
+}
+
+// String returns the call as written in a query
+func (c *CallExpr) String() string {
+	var args []string
+
+	for _, arg := range c.Args {
+		args = append(args, arg.String())
+	}
+
+	return fmt.Sprintf("%s(%s)", c.Func, strings.Join(args, ", "))
+}
+
+// BinaryExpr defines an arithmetic operation, one of + - * or /
+type BinaryExpr struct {
+	Op          string
+	Left, Right Expr
+}
+
+// String returns the operation as written in a query, adding the parentheses its precedence needs
+func (b *BinaryExpr) String() string {
+	left, right := b.Left.String(), b.Right.String()
+
+	if lb, ok := b.Left.(*BinaryExpr); ok && precedence(lb.Op) < precedence(b.Op) {
+		left = "(" + left + ")"
+	}
+
+	if rb, ok := b.Right.(*BinaryExpr); ok && precedence(rb.Op) <= precedence(b.Op) {
+		right = "(" + right + ")"
+	}
+
+	return fmt.Sprintf("%s %s %s", left, b.Op, right)
+}
+
+// precedence returns the binding strength of an operator
+func precedence(op string) int {
+	if op == "*" || op == "/" {
+		return 2
+	}
+	return 1
+}
+
+// ComputedField defines a field whose value is computed from an expression over its record's columns eg 'ageInMonths: age * 12'
+type ComputedField struct {
+	Name string
+	Expr Expr
+}
+
+// WalkExpr calls the function for the expression and each expression within it
+func WalkExpr(ex Expr, fx func(Expr)) {
+	fx(ex)
+
+	switch mo := ex.(type) {
+	case *CallExpr:
+		for _, arg := range mo.Args {
+			WalkExpr(arg, fx)
+		}
+	case *BinaryExpr:
+		WalkExpr(mo.Left, fx)
+		WalkExpr(mo.Right, fx)
+	}
+}
+
+// ExprColumns returns the sorted distinct columns the expression reads
+func ExprColumns(ex Expr) []string {
+	seen := make(map[string]bool)
+	var cols []string
+
+	WalkExpr(ex, func(sub Expr) {
+		if col, ok := sub.(*ColumnExpr); ok && !seen[col.Name] {
+			seen[col.Name] = true
+			cols = append(cols, col.Name)
+		}
+	})
+
+	sort.Strings(cols)
+	return cols
+}
+
+// ExprFunctions returns the sorted distinct functions the expression calls
+func ExprFunctions(ex Expr) []string {
+	seen := make(map[string]bool)
+	var fns []string
+
+	WalkExpr(ex, func(sub Expr) {
+		if call, ok := sub.(*CallExpr); ok && !seen[call.Func] {
+			seen[call.Func] = true
+			fns = append(fns, call.Func)
+		}
+	})
+
+	sort.Strings(fns)
+	return fns
+}
+
+// Function defines a scalar function computed fields may call with between MinArgs and MaxArgs arguments, where a MaxArgs below zero
+// allows any number of them and Check, when not nil, validates the arguments further
+type Function struct {
+	Name    string
+	MinArgs int
+	MaxArgs int
+	Check   func(args []Expr) error
+}
+
+// arity returns the number of arguments the function takes in words
+func (f *Function) arity() string {
+	switch {
+	case f.MaxArgs < 0:
+		return fmt.Sprintf("at least %d", f.MinArgs)
+	case f.MinArgs == f.MaxArgs:
+		return fmt.Sprintf("%d", f.MinArgs)
+	}
+	return fmt.Sprintf("%d to %d", f.MinArgs, f.MaxArgs)
+}
+
+// FunctionRegistry provides the set of scalar functions computed fields may call, adaptors implement each of them
+type FunctionRegistry struct {
+	funcs map[string]*Function
+	rw    sync.RWMutex
+}
+
+// NewFunctionRegistry returns a new FunctionRegistry instance
+func NewFunctionRegistry() *FunctionRegistry {
+	return &FunctionRegistry{funcs: make(map[string]*Function)}
+}
+
+// Register adds the function, replacing any function of the same name
+func (f *FunctionRegistry) Register(fn Function) {
+	f.rw.Lock()
+	defer f.rw.Unlock()
+	f.funcs[strings.ToLower(fn.Name)] = &fn
+}
+
+// Has returns true if the function is registered
+func (f *FunctionRegistry) Has(name string) bool {
+	_, err := f.Find(name)
+	return err == nil
+}
+
+// Find returns the function of the name
+func (f *FunctionRegistry) Find(name string) (*Function, error) {
+	f.rw.RLock()
+	fn, ok := f.funcs[strings.ToLower(name)]
+	f.rw.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf(UnknownFunctionMessage, name)
+	}
+
+	return fn, nil
+}
+
+// Names returns the sorted names of the registered functions
+func (f *FunctionRegistry) Names() []string {
+	f.rw.RLock()
+	defer f.rw.RUnlock()
+
+	var names []string
+
+	for name := range f.funcs {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// DateTruncUnits are the units date_trunc truncates to
+var DateTruncUnits = []string{"year", "month", "day", "hour", "minute", "second"}
+
+//DefaultFunctions provides a singleton of the default scalar functions
+var DefaultFunctions = NewFunctionRegistry()
+
+// AddDefaultFunctions adds the lower, upper, concat, coalesce, round and date_trunc functions to the registry
+func AddDefaultFunctions(f *FunctionRegistry) {
+	f.Register(Function{Name: "lower", MinArgs: 1, MaxArgs: 1})
+	f.Register(Function{Name: "upper", MinArgs: 1, MaxArgs: 1})
+	f.Register(Function{Name: "concat", MinArgs: 1, MaxArgs: -1})
+	f.Register(Function{Name: "coalesce", MinArgs: 1, MaxArgs: -1})
+	f.Register(Function{Name: "round", MinArgs: 1, MaxArgs: 2})
+
+	//date_trunc(unit, column) takes its unit as a string eg date_trunc("month", created)
+	f.Register(Function{Name: "date_trunc", MinArgs: 2, MaxArgs: 2, Check: func(args []Expr) error {
+		lit, ok := args[0].(*LiteralExpr)
+
+		var unit string
+
+		if ok {
+			unit, ok = lit.Value.(string)
+		}
+
+		if !ok {
+			return fmt.Errorf("Function 'date_trunc' needs its unit as a string eg \"month\"")
+		}
+
+		for _, name := range DateTruncUnits {
+			if strings.ToLower(unit) == name {
+				lit.Value = name
+				return nil
+			}
+		}
+
+		return fmt.Errorf("Function 'date_trunc' needs one of the units %s but got %q", strings.Join(DateTruncUnits, ", "), unit)
+	}})
+}
+
+// ParseExpr parses the expression of a computed field, rejecting calls of functions the registry does not hold or with the wrong arguments.
+// Expressions are made of columns, numbers, quoted strings, null, true, false, function calls and the + - * / operators with parentheses
+func ParseExpr(src string, fr *FunctionRegistry) (Expr, error) {
+	if fr == nil {
+		fr = DefaultFunctions
+	}
+
+	toks, err := lexExpr(src)
+
+	if err != nil {
+		return nil, fmt.Errorf(InvalidExpressionMessage, src, err)
+	}
+
+	ep := &exprParser{toks: toks, funcs: fr}
+	ex, err := ep.sum()
+
+	if err == nil && ep.pos < len(toks) {
+		err = fmt.Errorf("unexpected %q", toks[ep.pos].text)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf(InvalidExpressionMessage, strings.TrimSpace(src), err)
+	}
+
+	return ex, nil
+}
+
+// exprToken defines a single token of an expression
+type exprToken struct {
+	kind byte
+	text string
+	val  interface{}
+}
+
+const (
+	identToken   = 'i'
+	literalToken = 'l'
+	symbolToken  = 's'
+)
+
+// lexExpr splits the expression into identifiers, literals and the symbols + - * / ( and ,
+func lexExpr(src string) ([]exprToken, error) {
+	var toks []exprToken
+	runes := []rune(src)
+
+	for i := 0; i < len(runes); {
+		ch := runes[i]
+
+		switch {
+		case isWhiteSpace(ch):
+			i++
+		case strings.ContainsRune("+-*/(),", ch):
+			toks = append(toks, exprToken{kind: symbolToken, text: string(ch)})
+			i++
+		case ch == '"' || ch == '\'':
+			var buf []rune
+			j := i + 1
+
+			for ; j < len(runes) && runes[j] != ch; j++ {
+				if runes[j] == '\\' && j+1 < len(runes) {
+					j++
+				}
+				buf = append(buf, runes[j])
+			}
+
+			if j >= len(runes) {
+				return nil, fmt.Errorf("unterminated string")
+			}
+
+			toks = append(toks, exprToken{kind: literalToken, text: string(runes[i : j+1]), val: string(buf)})
+			i = j + 1
+		case ch >= '0' && ch <= '9' || ch == '.':
+			j := i
+
+			for j < len(runes) && (runes[j] >= '0' && runes[j] <= '9' || runes[j] == '.') {
+				j++
+			}
+
+			num, err := strconv.ParseFloat(string(runes[i:j]), 64)
+
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %q", string(runes[i:j]))
+			}
+
+			toks = append(toks, exprToken{kind: literalToken, text: string(runes[i:j]), val: num})
+			i = j
+		case ch == '_' || isLetter(ch):
+			j := i
+
+			for j < len(runes) && (runes[j] == '_' || isLetter(runes[j]) || runes[j] >= '0' && runes[j] <= '9') {
+				j++
+			}
+
+			word := string(runes[i:j])
+
+			switch strings.ToLower(word) {
+			case "null":
+				toks = append(toks, exprToken{kind: literalToken, text: word})
+			case "true", "false":
+				toks = append(toks, exprToken{kind: literalToken, text: word, val: strings.ToLower(word) == "true"})
+			default:
+				toks = append(toks, exprToken{kind: identToken, text: word})
+			}
+
+			i = j
+		default:
+			return nil, fmt.Errorf("unexpected %q", string(ch))
+		}
+	}
+
+	return toks, nil
+}
+
+// exprParser parses the tokens of an expression by precedence
+type exprParser struct {
+	toks  []exprToken
+	pos   int
+	funcs *FunctionRegistry
+}
+
+// peek returns the text of the symbol at the current position, or an empty string
+func (e *exprParser) peek() string {
+	if e.pos < len(e.toks) && e.toks[e.pos].kind == symbolToken {
+		return e.toks[e.pos].text
+	}
+	return ""
+}
+
+// sum parses operands joined by + and -
+func (e *exprParser) sum() (Expr, error) {
+	return e.binary(e.product, "+", "-")
+}
+
+// product parses operands joined by * and /
+func (e *exprParser) product() (Expr, error) {
+	return e.binary(e.operand, "*", "/")
+}
+
+// binary parses the operands of next joined by any of the operators from left to right
+func (e *exprParser) binary(next func() (Expr, error), ops ...string) (Expr, error) {
+	left, err := next()
+
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		op := e.peek()
+
+		if op == "" || (op != ops[0] && op != ops[1]) {
+			return left, nil
+		}
+
+		e.pos++
+
+		right, err := next()
+
+		if err != nil {
+			return nil, err
+		}
+
+		left = &BinaryExpr{Op: op, Left: left, Right: right}
+	}
+}
+
+// operand parses a literal, a negative number, a column, a function call or a parenthesized expression
+func (e *exprParser) operand() (Expr, error) {
+	if e.pos >= len(e.toks) {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+
+	tok := e.toks[e.pos]
+	e.pos++
+
+	switch tok.kind {
+	case literalToken:
+		return &LiteralExpr{Value: tok.val}, nil
+	case identToken:
+		if e.peek() != "(" {
+			return &ColumnExpr{Name: tok.text}, nil
+		}
+
+		e.pos++
+		return e.call(strings.ToLower(tok.text))
+	}
+
+	switch tok.text {
+	case "(":
+		ex, err := e.sum()
+
+		if err != nil {
+			return nil, err
+		}
+
+		if e.peek() != ")" {
+			return nil, fmt.Errorf("missing )")
+		}
+
+		e.pos++
+		return ex, nil
+	case "-":
+		if e.pos < len(e.toks) {
+			if num, ok := e.toks[e.pos].val.(float64); ok {
+				e.pos++
+				return &LiteralExpr{Value: -num}, nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("unexpected %q", tok.text)
+}
+
+// call parses the arguments of a call to the registered function after its opening parenthesis
+func (e *exprParser) call(name string) (Expr, error) {
+	fn, err := e.funcs.Find(name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	call := &CallExpr{Func: name}
+
+	if e.peek() == ")" {
+		e.pos++
+	} else {
+		for {
+			arg, err := e.sum()
+
+			if err != nil {
+				return nil, err
+			}
+
+			call.Args = append(call.Args, arg)
+
+			if sym := e.peek(); sym == "," {
+				e.pos++
+				continue
+			} else if sym == ")" {
+				e.pos++
+				break
+			}
+
+			return nil, fmt.Errorf("missing ) after the arguments of %s", name)
+		}
+	}
+
+	if len(call.Args) < fn.MinArgs || (fn.MaxArgs >= 0 && len(call.Args) > fn.MaxArgs) {
+		return nil, fmt.Errorf(FunctionArityMessage, name, fn.arity(), len(call.Args))
+	}
+
+	if fn.Check != nil {
+		if err := fn.Check(call.Args); err != nil {
+			return nil, err
+		}
+	}
+
+	return call, nil
+}
+
+func init() {
+	AddDefaultFunctions(DefaultFunctions)
+}
@@ -2,6 +2,7 @@ package parser
 
 import (
 	"fmt"
+	"regexp"
 	"strconv"
 	"strings"
 )
@@ -160,6 +161,162 @@ func AddDefaultInspections(inspect *InspectionFactory) {
 
 		return cond, nil
 	})
+
+	//pattern conditions keep their value as written, adaptors remove its quotes when matching
+	for _, tag := range []string{"like", "ilike", "startswith", "endswith", "contains", "search"} {
+		inspect.Register(tag, patternInspector(tag))
+	}
+
+	inspect.Register("match", func(data string) (Collector, error) {
+
+		cond := NewCondition("match")
+		cond.Set("value", strings.TrimSpace(data))
+
+		if _, err := regexp.Compile(Unquote(data)); err != nil {
+			return nil, fmt.Errorf("Invalid regular expression %s: %s", data, err)
+		}
+
+		return cond, nil
+	})
+
+	//null checks take a boolean, where 'isnull: false' is the same as 'notnull: true'
+	for _, tag := range []string{"isnull", "notnull"} {
+		inspect.Register(tag, boolInspector(tag))
+	}
+
+	//existence rules filter a record by whether any rows of a child record exist, see ExistenceRule
+	for _, tag := range []string{"exists", "notexists"} {
+		inspect.Register(tag, boolInspector(tag))
+	}
+
+	//date conditions hold a DateExpr or a $variable that adaptors read with DateValue
+	for _, tag := range []string{"before", "after"} {
+		inspect.Register(tag, dateInspector(tag, false))
+	}
+
+	inspect.Register("within", dateInspector("within", true))
+
+	inspect.Register("between", func(data string) (Collector, error) {
+		props := strings.Split(Unquote(data), "..")
+
+		if len(props) != 2 {
+			return nil, fmt.Errorf("Invalid string %s does not match 'from..to' rule ", data)
+		}
+
+		from, err := ParseDate(strings.TrimSpace(props[0]))
+
+		if err != nil {
+			return nil, err
+		}
+
+		to, err := ParseDate(strings.TrimSpace(props[1]))
+
+		if err != nil {
+			return nil, err
+		}
+
+		cond := NewCondition("between")
+		cond.Set("min", from)
+		cond.Set("max", to)
+
+		return cond, nil
+	})
+}
+
+// dateInspector returns an inspector setting the DateExpr of the data as the value of a condition of the tag, relative conditions only take durations
+func dateInspector(tag string, relative bool) ValidFx {
+	return func(data string) (Collector, error) {
+		val := strings.TrimSpace(data)
+		cond := NewCondition(tag)
+
+		//variables are bound after parsing
+		if strings.HasPrefix(val, "$") {
+			cond.Set("value", val)
+			return cond, nil
+		}
+
+		date, err := ParseDate(val)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if relative && !date.Relative {
+			return nil, fmt.Errorf("Condition %s needs a duration eg 7d but got %s", tag, val)
+		}
+
+		cond.Set("value", date)
+
+		return cond, nil
+	}
+}
+
+// boolInspector returns an inspector setting the boolean as the value of a condition of the tag
+func boolInspector(tag string) ValidFx {
+	return func(data string) (Collector, error) {
+		val, err := strconv.ParseBool(Unquote(data))
+
+		if err != nil {
+			return nil, fmt.Errorf("Condition %s needs true or false but got %s", tag, strings.TrimSpace(data))
+		}
+
+		cond := NewCondition(tag)
+		cond.Set("value", val)
+
+		return cond, nil
+	}
+}
+
+// ExistenceRule returns true if the query section of a record holds an 'exists' or 'notexists' rule eg '(exists: true, with: [user_id id])',
+// such records filter their parent and may be written without a field block
+func ExistenceRule(query string) bool {
+	parts, err := stripQuery(query)
+
+	if err != nil {
+		return false
+	}
+
+	for _, part := range parts {
+		rsv := strings.Split(part, ":")
+
+		if len(rsv) < 2 {
+			continue
+		}
+
+		switch strings.ToLower(strings.TrimSpace(rsv[0])) {
+		case "exists", "notexists":
+			return true
+		}
+	}
+
+	return false
+}
+
+// patternInspector returns an inspector setting the pattern as the value of a condition of the tag
+func patternInspector(tag string) ValidFx {
+	return func(data string) (Collector, error) {
+		val := strings.TrimSpace(data)
+
+		if Unquote(val) == "" {
+			return nil, fmt.Errorf("Condition %s needs a pattern", tag)
+		}
+
+		cond := NewCondition(tag)
+		cond.Set("value", val)
+
+		return cond, nil
+	}
+}
+
+// Unquote removes the quotes around a condition value eg 'al%' or "al%"
+func Unquote(val string) string {
+	val = strings.TrimSpace(val)
+
+	if len(val) >= 2 && (val[0] == '\'' || val[0] == '"') && val[len(val)-1] == val[0] {
+		return val[1 : len(val)-1]
+	}
+
+	return val
 }
 
 func init() {
@@ -1,6 +1,9 @@
 package parser
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 // ErrNotFound a general error for when a state or item is not found
 var ErrNotFound = errors.New("Item not Found")
@@ -139,3 +142,16 @@ func (c *Collectors) EachCondition(fx EachConditionHandler) {
 		}
 	})
 }
+
+//ArgsKey is the collector key where adaptors record the values bound to a condition
+const ArgsKey = "args"
+
+//Param represents a condition value bound to a query as an argument rather than written into its text
+type Param struct {
+	Value interface{}
+}
+
+//String returns the string representation of the bound value
+func (p Param) String() string {
+	return fmt.Sprintf("%v", p.Value)
+}
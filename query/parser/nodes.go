@@ -109,6 +109,7 @@ type ValidFx func(data string) (Collector, error)
 //InspectionFactory provides a factory of dealing with special query parameters in the parser
 type InspectionFactory struct {
 	factory map[string]*Inspector
+	funcs   *FunctionRegistry
 	rw      sync.RWMutex
 }
 
@@ -148,6 +149,25 @@ func (c *InspectionFactory) Register(tag string, cx ValidFx) {
 	c.factory[tag] = NewInspector(tag, cx)
 }
 
+//UseFunctions sets the registry of functions computed fields may call
+func (c *InspectionFactory) UseFunctions(fr *FunctionRegistry) {
+	c.rw.Lock()
+	defer c.rw.Unlock()
+	c.funcs = fr
+}
+
+//Functions returns the registry of functions computed fields may call, DefaultFunctions unless another was set
+func (c *InspectionFactory) Functions() *FunctionRegistry {
+	c.rw.RLock()
+	defer c.rw.RUnlock()
+
+	if c.funcs == nil {
+		return DefaultFunctions
+	}
+
+	return c.funcs
+}
+
 //Deregister lets you add a new condition maker
 func (c *InspectionFactory) Deregister(tag string) {
 	if !c.Has(tag) {
@@ -28,6 +28,7 @@ const (
 type ParseNode struct {
 	ds.Nodes
 	name           string
+	Source         string
 	Key            string
 	Parent         string
 	PKey           string
@@ -35,13 +36,24 @@ type ParseNode struct {
 	Attr           *ds.StringSet
 	Rules, Records *Collectors
 	Result         []map[string]interface{}
+	Computed       []ComputedField
+	Line, Pos      int
+	Marks          map[string]*Token
 }
 
-//NewParseNode returns a new ParseNode instance
+//NewParseNode returns a new ParseNode instance, a name in the form 'record@source' routes the record to the named source/adaptor
 func NewParseNode(tp NodeType, val, pa, pk string, gs ds.Graphs) *ParseNode {
 	alias := strings.ToLower(utils.RandomAlias())
+
+	var source string
+
+	if at := strings.Index(val, "@"); at != -1 {
+		val, source = val[:at], val[at+1:]
+	}
+
 	return &ParseNode{
 		name:    val,
+		Source:  source,
 		Key:     alias,
 		NType:   tp,
 		Parent:  pa,
@@ -50,7 +62,46 @@ func NewParseNode(tp NodeType, val, pa, pk string, gs ds.Graphs) *ParseNode {
 		Attr:    ds.NewStringSet(),
 		Rules:   NewCollectors(),
 		Records: NewCollectors(),
+		Marks:   make(map[string]*Token),
+	}
+}
+
+//Mark records the token where the node or one of its fields/rules was declared
+func (p *ParseNode) Mark(tag string, tok *Token) {
+	if tok == nil {
+		return
+	}
+
+	if tag == "" {
+		p.Line, p.Pos = tok.Line, tok.Pos
+		return
+	}
+
+	p.Marks[tag] = tok
+}
+
+//Report returns a positioned error for a field or rule of the node, using the node's own position when the tag was never marked
+func (p *ParseNode) Report(tag, msg string) error {
+	if tok, ok := p.Marks[tag]; ok {
+		return report(msg, tag, tok.Line, tok.Pos)
+	}
+
+	if tag == "" {
+		tag = p.Name()
+	}
+
+	return report(msg, tag, p.Line, p.Pos)
+}
+
+//ComputedField returns the computed field of the name or nil
+func (p *ParseNode) ComputedField(name string) *ComputedField {
+	for i := range p.Computed {
+		if p.Computed[i].Name == name {
+			return &p.Computed[i]
+		}
 	}
+
+	return nil
 }
 
 //Name returns the tag/name of this node
@@ -67,6 +118,10 @@ func (p *ParseNode) String() string {
 		fmt.Sprintf(", Rules: %+s", p.Rules),
 	}
 
+	if len(p.Computed) > 0 {
+		smp = append(smp, fmt.Sprintf(", Computed: %+s", p.Computed))
+	}
+
 	return strings.Join(smp, "")
 }
 
@@ -97,6 +152,7 @@ func (p *Parser) Scan(rl io.Reader) (ds.Graphs, error) {
 	gos := ds.NewGraph()
 
 	psn := NewParseNode(MODELROOT, tok.Data, "", "", gos)
+	psn.Mark("", tok)
 	// gos.Add(tok.Data)
 	gos.AddNode(psn)
 
@@ -135,7 +191,8 @@ func scanIdentWithQuery(data string, target *ParseNode, inspect *InspectionFacto
 			continue
 		}
 
-		tag, value := strings.TrimSpace(rsv[0]), strings.Join(rsv[1:], "")
+		//values may hold colons of their own eg 'after: 2016-01-02T15:04:05Z'
+		tag, value := strings.TrimSpace(rsv[0]), strings.TrimSpace(strings.Join(rsv[1:], ":"))
 
 		tag = strings.ToLower(tag)
 		// if !inspect.Has(tag) {
@@ -184,11 +241,11 @@ func scanAttrWithQuery(attr, query string, target *ParseNode, inspect *Inspectio
 	for _, v := range parts {
 		rsv := strings.Split(v, ":")
 
-		if len(rsv) != 2 {
+		if len(rsv) < 2 {
 			return errors.New(BadQuerySection)
 		}
 
-		tag, value := strings.TrimSpace(rsv[0]), strings.Join(rsv[1:], "")
+		tag, value := strings.TrimSpace(rsv[0]), strings.TrimSpace(strings.Join(rsv[1:], ":"))
 		// tag, value := strings.TrimSpace(rsv[0]), rsv[1]
 
 		tag = strings.ToLower(tag)
@@ -230,7 +287,13 @@ func scanSection(target *ParseNode, graph ds.Graphs, scan *Scanner, p *Parser) e
 
 	if tok.EqualsType(Query) {
 		// log.Printf("Handler query for:", target.Name(), tok)
-		scanIdentWithQuery(tok.Data, target, p.inspect)
+		if err := scanIdentWithQuery(tok.Data, target, p.inspect); err != nil {
+			return report(err.Error(), tok.Data, tok.Line, tok.Pos)
+		}
+
+		target.Rules.Each(func(_ []Collector, tag string, _ func()) {
+			target.Mark(tag, tok)
+		})
 		nxt := scanOutWhiteSpace(scan)
 
 		if !nxt.EqualsType(GroupStart) {
@@ -262,6 +325,15 @@ func scanSection(target *ParseNode, graph ds.Graphs, scan *Scanner, p *Parser) e
 			tag := curtok.Data
 			// log.Printf("in-indent-level", curtok.Data)
 
+			//a computed field eg 'fullName: concat(first, " ", last)'
+			if at := strings.Index(tag, ":"); at != -1 {
+				if err := scanComputed(tag[:at], tag[at+1:], curtok, target, scan, p); err != nil {
+					return err
+				}
+
+				continue
+			}
+
 			nx := scanOutWhiteSpace(scan)
 			// log.Printf("in-indent-gs-data", nx.Data)
 
@@ -274,6 +346,7 @@ func scanSection(target *ParseNode, graph ds.Graphs, scan *Scanner, p *Parser) e
 					scan.unreadLast()
 
 					psn := NewParseNode(MODELSUBROOT, curtok.Data, target.Name(), target.Key, graph)
+					psn.Mark("", curtok)
 					// graph.Add(tag)
 					graph.AddNode(psn)
 
@@ -291,11 +364,68 @@ func scanSection(target *ParseNode, graph ds.Graphs, scan *Scanner, p *Parser) e
 					continue
 				}
 
-				scanAttrWithQuery(tag, nx.Data, target, p.inspect)
+				//a sub record filtering its parent without a field block eg 'photos(exists: true, with: [user_id id])'
+				if ExistenceRule(nx.Data) {
+					psn := NewParseNode(MODELSUBROOT, curtok.Data, target.Name(), target.Key, graph)
+					psn.Mark("", curtok)
+					graph.AddNode(psn)
+					graph.BindNodes(target, psn, 0)
+
+					if err := scanIdentWithQuery(nx.Data, psn, p.inspect); err != nil {
+						return report(err.Error(), nx.Data, nx.Line, nx.Pos)
+					}
+
+					psn.Rules.Each(func(_ []Collector, rule string, _ func()) {
+						psn.Mark(rule, nx)
+					})
+
+					if nxx.EqualsType(GroupEnd) {
+						break
+					}
+
+					continue
+				}
+
+				if err := scanAttrWithQuery(tag, nx.Data, target, p.inspect); err != nil {
+					return report(err.Error(), nx.Data, nx.Line, nx.Pos)
+				}
+
+				target.Mark(tag, curtok)
+
+				if nxx.EqualsType(GroupEnd) {
+					break
+				}
+
+				continue
+			}
+
+			//a sub record without a query section eg 'photos{ url }'
+			if nx.EqualsType(GroupStart) {
+				scan.unreadLast()
+
+				psn := NewParseNode(MODELSUBROOT, curtok.Data, target.Name(), target.Key, graph)
+				psn.Mark("", curtok)
+				graph.AddNode(psn)
+				graph.BindNodes(target, psn, 0)
+
+				if err := scanSection(psn, graph, scan, p); err != nil {
+					return err
+				}
+
 				continue
 			}
 
+			if target.ComputedField(tag) != nil {
+				return report(fmt.Sprintf(DuplicateComputedMessage, tag), tag, curtok.Line, curtok.Pos)
+			}
+
 			target.Records.Set(tag, nil)
+			target.Mark(tag, curtok)
+
+			//the last field of a section may be followed directly by its closing brace
+			if nx.EqualsType(GroupEnd) {
+				break
+			}
 		}
 
 	}
@@ -303,6 +433,30 @@ func scanSection(target *ParseNode, graph ds.Graphs, scan *Scanner, p *Parser) e
 	return nil
 }
 
+//scanComputed scans out the expression of a computed field after its name, where head holds the part of it read with the name
+func scanComputed(name, head string, tok *Token, target *ParseNode, scan *Scanner, p *Parser) error {
+	expr := head + scan.scanExpression().Data
+
+	if !identifier.MatchString(name) {
+		return report(fmt.Sprintf(InvalidComputedMessage, name), tok.Data, tok.Line, tok.Pos)
+	}
+
+	if target.Records.Has(name) || target.ComputedField(name) != nil {
+		return report(fmt.Sprintf(DuplicateComputedMessage, name), tok.Data, tok.Line, tok.Pos)
+	}
+
+	ex, err := ParseExpr(expr, p.inspect.Functions())
+
+	if err != nil {
+		return report(err.Error(), strings.TrimSpace(expr), tok.Line, tok.Pos)
+	}
+
+	target.Computed = append(target.Computed, ComputedField{Name: name, Expr: ex})
+	target.Mark(name, tok)
+
+	return nil
+}
+
 //ScanChunks takes a scanner and scans out each section of the supported query format
 func ScanChunks(scan *Scanner, chunks func(string)) error {
 	if chunks == nil {
@@ -373,19 +527,14 @@ func scanChunk(scan *Scanner) (string, error) {
 			isopen = true
 		}
 
+		chunk = append(chunk, tok.Data)
+
 		if tok.EqualsType(GroupEnd) {
 			open--
-			if open <= 0 {
+			if open <= 0 && isopen {
 				break
 			}
-
-			if isopen {
-				chunk = append(chunk, tok.Data)
-				isopen = false
-			}
 		}
-
-		chunk = append(chunk, tok.Data)
 	}
 
 	return strings.TrimSuffix(strings.TrimSpace(strings.Join(chunk, "")), ","), nil
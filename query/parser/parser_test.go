@@ -2,7 +2,9 @@ package parser
 
 import (
 	"os"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/influx6/ds"
 	"github.com/influx6/flux"
@@ -129,3 +131,179 @@ func TestModelFileParser(t *testing.T) {
 
 	flux.LogPassed(t, "Successfully passed model query file properly")
 }
+
+func TestSubRecordWithoutQuery(t *testing.T) {
+	ps := NewParser(DefaultInspectionFactory)
+
+	g, err := ps.Scan(strings.NewReader(`users(){ name, photos{ url }, age(gt: 20)}`))
+
+	if err != nil {
+		flux.FatalFailed(t, "Parser.Error occured: %+s", err)
+	}
+
+	users := g.Get("users").(*ParseNode)
+
+	if !users.Records.Has("age") || users.Records.Has("url") {
+		flux.FatalFailed(t, "Expected 'age' on users and 'url' on photos: %+s", users.Records.Keys())
+	}
+
+	photos := g.Get("photos").(*ParseNode)
+
+	if photos.PKey != users.Key || !photos.Records.Has("url") {
+		flux.FatalFailed(t, "Expected 'photos' to be a child of 'users' with 'url': %+s", photos)
+	}
+
+	flux.LogPassed(t, "Parsed sub record without query section properly")
+}
+
+func TestRecordSource(t *testing.T) {
+	ps := NewParser(DefaultInspectionFactory)
+
+	g, err := ps.Scan(strings.NewReader(`users@sql(){ name, photos@json(with: [user_id id]){ url } }`))
+
+	if err != nil {
+		flux.FatalFailed(t, "Parser.Error occured: %+s", err)
+	}
+
+	users, ok := g.Get("users").(*ParseNode)
+
+	if !ok || users.Source != "sql" {
+		flux.FatalFailed(t, "Expected record 'users' with source 'sql': %+s", users)
+	}
+
+	photos, ok := g.Get("photos").(*ParseNode)
+
+	if !ok || photos.Source != "json" || photos.Parent != "users" {
+		flux.FatalFailed(t, "Expected record 'photos' of 'users' with source 'json': %+s", photos)
+	}
+
+	flux.LogPassed(t, "Parsed record sources properly")
+}
+
+func TestPatternConditions(t *testing.T) {
+	ps := NewParser(DefaultInspectionFactory)
+
+	if _, err := ps.Scan(strings.NewReader(`users(){ name(match: '^[a-z]+$'), bio(search: 'go lang') }`)); err != nil {
+		flux.FatalFailed(t, "Parser.Error occured: %+s", err)
+	}
+
+	if _, err := ps.Scan(strings.NewReader(`users(){ name(match: '^[a-z') }`)); err == nil {
+		flux.FatalFailed(t, "Expected invalid match expression to be rejected")
+	}
+
+	flux.LogPassed(t, "Validated pattern conditions while parsing")
+}
+
+func TestExistenceRecord(t *testing.T) {
+	ps := NewParser(DefaultInspectionFactory)
+
+	g, err := ps.Scan(strings.NewReader(`users(){ name, photos(exists: true, with: [user_id id]), age }`))
+
+	if err != nil {
+		flux.FatalFailed(t, "Parser.Error occured: %+s", err)
+	}
+
+	users := g.Get("users").(*ParseNode)
+	photos, ok := g.Get("photos").(*ParseNode)
+
+	if !ok || photos.PKey != users.Key || !photos.Rules.Has("exists") || len(photos.Records.Keys()) != 0 {
+		flux.FatalFailed(t, "Expected 'photos' to be a child record of 'users' without fields: %+s", photos)
+	}
+
+	if users.Records.Has("photos") || !users.Records.Has("age") {
+		flux.FatalFailed(t, "Expected 'photos' not to be a field of 'users': %+v", users.Records.Keys())
+	}
+
+	if _, err := ps.Scan(strings.NewReader(`users(){ photos(exists: maybe, with: [user_id id]) }`)); err == nil {
+		flux.FatalFailed(t, "Expected non boolean existence rule to be rejected")
+	}
+
+	flux.LogPassed(t, "Parsed existence records without a field block")
+}
+
+func TestDateConditions(t *testing.T) {
+	ps := NewParser(DefaultInspectionFactory)
+
+	g, err := ps.Scan(strings.NewReader(`users(created: 2016-01-02T15:04:05Z){ name, joined(after: 2016-01-02T15:04:05+01:00), seen(within: 1w2d) }`))
+
+	if err != nil {
+		flux.FatalFailed(t, "Parser.Error occured: %+s", err)
+	}
+
+	users := g.Get("users").(*ParseNode)
+
+	if rule, _ := users.Rules.Get("created"); len(rule) != 1 || rule[0].Get("value") != "2016-01-02T15:04:05Z" {
+		flux.FatalFailed(t, "Expected rule value to keep its colons: %+v", rule)
+	}
+
+	after, _ := users.Records.Get("joined")
+
+	if date, ok := after[0].Get("value").(DateExpr); !ok || !date.Time.Equal(time.Date(2016, 1, 2, 14, 4, 5, 0, time.UTC)) {
+		flux.FatalFailed(t, "Expected an absolute date: %+v", after)
+	}
+
+	within, _ := users.Records.Get("seen")
+
+	if date, ok := within[0].Get("value").(DateExpr); !ok || !date.Relative || date.Offset != -9*24*time.Hour {
+		flux.FatalFailed(t, "Expected nine days in the past: %+v", within)
+	}
+
+	for _, query := range []string{`users(){ seen(within: 2016-01-02) }`, `users(){ seen(after: 7y) }`, `users(){ seen(between: 7d) }`} {
+		if _, err := ps.Scan(strings.NewReader(query)); err == nil {
+			flux.FatalFailed(t, "Expected invalid date condition to be rejected: %s", query)
+		}
+	}
+
+	flux.LogPassed(t, "Parsed absolute and relative date conditions")
+}
+
+func TestComputedFields(t *testing.T) {
+	ps := NewParser(DefaultInspectionFactory)
+
+	g, err := ps.Scan(strings.NewReader(`users(){ name, fullName: concat(first, " ", last), ageInMonths: age * (12 - 0), month: date_trunc("MONTH", created) }`))
+
+	if err != nil {
+		flux.FatalFailed(t, "Parser.Error occured: %+s", err)
+	}
+
+	users := g.Get("users").(*ParseNode)
+
+	if users.Records.Has("fullName") || len(users.Records.Keys()) != 1 || len(users.Computed) != 3 {
+		flux.FatalFailed(t, "Expected one column and three computed fields: %+v", users)
+	}
+
+	for name, expr := range map[string]string{
+		"fullName":    `concat(first, " ", last)`,
+		"ageInMonths": `age * (12 - 0)`,
+		"month":       `date_trunc("month", created)`,
+	} {
+		if cf := users.ComputedField(name); cf == nil || cf.Expr.String() != expr {
+			flux.FatalFailed(t, "Expected %s to compute %s: %+v", name, expr, cf)
+		}
+	}
+
+	text, err := Canonical(g)
+
+	if err != nil {
+		flux.FatalFailed(t, "Failed to print query: %+s", err)
+	}
+
+	if !strings.Contains(text, `ageInMonths: age * (12 - 0), fullName: concat(first, " ", last)`) {
+		flux.FatalFailed(t, "Expected computed fields in printed query: %s", text)
+	}
+
+	for _, query := range []string{
+		`users(){ hash: md5(name) }`,
+		`users(){ name, name: lower(name) }`,
+		`users(){ name: lower(name), name }`,
+		`users(){ lowered: lower(first, last) }`,
+		`users(){ day: date_trunc("fortnight", created) }`,
+		`users(){ total: age * }`,
+	} {
+		if _, err := ps.Scan(strings.NewReader(query)); err == nil {
+			flux.FatalFailed(t, "Expected invalid computed field to be rejected: %s", query)
+		}
+	}
+
+	flux.LogPassed(t, "Parsed computed fields with registered functions")
+}
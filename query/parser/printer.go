@@ -0,0 +1,267 @@
+package parser
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/influx6/ds"
+)
+
+//DefaultIndent is the indentation used by Format for each nested level of a query
+const DefaultIndent = "  "
+
+//ErrNoRoot is returned when printing a graph without a root record
+var ErrNoRoot = errors.New("Graph has no root record")
+
+//Printer renders parsed query graphs back into query text. Attributes, rules, fields, computed fields, conditions and child records are sorted, so equal queries
+//print the same regardless of how they were written
+type Printer struct {
+	//Indent is the indentation of each nested level, when empty the query is printed on a single line
+	Indent string
+}
+
+//Format returns the query text of the graph indented with DefaultIndent, see Printer
+func Format(gs ds.Graphs) (string, error) {
+	return (&Printer{Indent: DefaultIndent}).Print(gs)
+}
+
+//Canonical returns the single line query text of the graph, suitable as a key for caching and logging queries
+func Canonical(gs ds.Graphs) (string, error) {
+	return (&Printer{}).Print(gs)
+}
+
+//FormatQuery parses each root record of the query and returns them formatted by the printer, separated by blank lines
+func FormatQuery(p *Printer, inspect *InspectionFactory, query string) (string, error) {
+	var chunks []string
+
+	if err := ScanChunks(NewScanner(bytes.NewBufferString(query)), func(chunk string) {
+		chunks = append(chunks, chunk)
+	}); err != nil {
+		return "", err
+	}
+
+	ps := NewParser(inspect)
+
+	var out []string
+
+	for _, chunk := range chunks {
+		gs, err := ps.Scan(bytes.NewBufferString(chunk))
+
+		if err != nil {
+			return "", err
+		}
+
+		text, err := p.Print(gs)
+
+		if err != nil {
+			return "", err
+		}
+
+		out = append(out, text)
+	}
+
+	if p.Indent == "" {
+		return strings.Join(out, "\n"), nil
+	}
+
+	return strings.Join(out, "\n\n"), nil
+}
+
+//Print returns the query text of the graph
+func (p *Printer) Print(gs ds.Graphs) (string, error) {
+	root, children, err := nodeTree(gs)
+
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	p.node(&buf, root, children, 0)
+	return buf.String(), nil
+}
+
+//node writes the record with its fields and child records at the giving depth
+func (p *Printer) node(buf *bytes.Buffer, node *ParseNode, children map[string][]*ParseNode, depth int) {
+	buf.WriteString(node.Name())
+
+	if node.Source != "" {
+		buf.WriteString("@" + node.Source)
+	}
+
+	buf.WriteString("(" + strings.Join(rules(node), ", ") + "){")
+
+	fields := node.Records.Keys()
+	sort.Strings(fields)
+
+	subs := append([]*ParseNode(nil), children[node.Key]...)
+	sort.SliceStable(subs, func(i, j int) bool {
+		return subs[i].Name()+"@"+subs[i].Source < subs[j].Name()+"@"+subs[j].Source
+	})
+
+	computed := append([]ComputedField(nil), node.Computed...)
+	sort.SliceStable(computed, func(i, j int) bool {
+		return computed[i].Name < computed[j].Name
+	})
+
+	if len(fields) == 0 && len(computed) == 0 && len(subs) == 0 {
+		buf.WriteString("}")
+		return
+	}
+
+	count := 0
+
+	next := func() {
+		if count > 0 {
+			buf.WriteString(",")
+		}
+
+		count++
+
+		if p.Indent == "" {
+			buf.WriteString(" ")
+			return
+		}
+
+		buf.WriteString("\n" + strings.Repeat(p.Indent, depth+1))
+	}
+
+	for _, field := range fields {
+		next()
+		buf.WriteString(field)
+
+		if conds := conditions(node.Records, field); len(conds) > 0 {
+			buf.WriteString("(" + strings.Join(conds, ", ") + ")")
+		}
+	}
+
+	for _, cf := range computed {
+		next()
+		buf.WriteString(cf.Name + ": " + cf.Expr.String())
+	}
+
+	for _, sub := range subs {
+		next()
+		p.node(buf, sub, children, depth+1)
+	}
+
+	if p.Indent == "" {
+		buf.WriteString(" }")
+		return
+	}
+
+	buf.WriteString("\n" + strings.Repeat(p.Indent, depth) + "}")
+}
+
+//rules returns the sorted attributes and 'tag: value' rules of the node
+func rules(node *ParseNode) []string {
+	attrs := append([]string(nil), node.Attr.All()...)
+	sort.Strings(attrs)
+
+	tags := node.Rules.Keys()
+	sort.Strings(tags)
+
+	for _, tag := range tags {
+		cols, _ := node.Rules.Get(tag)
+
+		for _, col := range cols {
+			attrs = append(attrs, fmt.Sprintf("%s: %s", tag, FormatValue(col)))
+		}
+	}
+
+	return attrs
+}
+
+//conditions returns the sorted 'type: value' conditions of a field
+func conditions(co *Collectors, field string) []string {
+	cols, _ := co.Get(field)
+
+	var conds []string
+
+	for _, col := range cols {
+		tag, _ := col.Get("type").(string)
+		conds = append(conds, fmt.Sprintf("%s: %s", tag, FormatValue(col)))
+	}
+
+	sort.Strings(conds)
+	return conds
+}
+
+//FormatValue returns the query text of a condition's value, lists as '[a b]', ranges as 'min..max' and anything else as is
+func FormatValue(c Collector) string {
+	if items, ok := c.Get("range").([]string); ok {
+		return "[" + strings.Join(items, " ") + "]"
+	}
+
+	if items, ok := c.Get("range").([]interface{}); ok {
+		var words []string
+		for _, item := range items {
+			words = append(words, fmt.Sprintf("%v", formatParam(item)))
+		}
+		return "[" + strings.Join(words, " ") + "]"
+	}
+
+	if items, ok := c.Get("value").([]string); ok {
+		return "[" + strings.Join(items, " ") + "]"
+	}
+
+	if c.Has("min") && c.Has("max") && !c.Has("value") {
+		return fmt.Sprintf("%v..%v", formatParam(c.Get("min")), formatParam(c.Get("max")))
+	}
+
+	return fmt.Sprintf("%v", formatParam(c.Get("value")))
+}
+
+//formatParam returns the value of a bound Param
+func formatParam(val interface{}) interface{} {
+	if pm, ok := val.(Param); ok {
+		return pm.Value
+	}
+	return val
+}
+
+//nodeTree returns the root record of the graph with the child records of each record keyed by its parent's Key
+func nodeTree(gs ds.Graphs) (*ParseNode, map[string][]*ParseNode, error) {
+	found, err := ds.NewLinearGraphSearch(gs).FindOne(func(n ds.Nodes) bool {
+		ps, ok := n.(*ParseNode)
+		return ok && ps.NType == MODELROOT
+	})
+
+	if err != nil {
+		return nil, nil, ErrNoRoot
+	}
+
+	mo, err := ds.DepthFirstPreOrderIterator(nil, nil)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	mo.Use(found)
+
+	var root *ParseNode
+	children := make(map[string][]*ParseNode)
+
+	for mo.Next() == nil {
+		node, ok := mo.Node().(*ParseNode)
+
+		if !ok {
+			continue
+		}
+
+		if root == nil {
+			root = node
+			continue
+		}
+
+		children[node.PKey] = append(children[node.PKey], node)
+	}
+
+	if root == nil {
+		return nil, nil, ErrNoRoot
+	}
+
+	return root, children, nil
+}
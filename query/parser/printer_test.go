@@ -0,0 +1,70 @@
+package parser
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/influx6/flux"
+)
+
+func TestPrinter(t *testing.T) {
+	ps := NewParser(DefaultInspectionFactory)
+
+	gs, err := ps.Scan(bytes.NewBufferString(`users@sql(id: 1){
+		photos(with: [user_id id]){ url },
+		name,
+		age(lt: 40,gt: 20),
+		kind(in: [admin staff]),
+		score(range: 1..5)
+	}`))
+
+	if err != nil {
+		flux.FatalFailed(t, "Failed to parse query: %+s", err)
+	}
+
+	formatted, err := Format(gs)
+
+	if err != nil {
+		flux.FatalFailed(t, "Failed to format graph: %+s", err)
+	}
+
+	expected := `users@sql(id: 1){
+  age(gt: 20, lt: 40),
+  kind(in: [admin staff]),
+  name,
+  score(range: 1..5),
+  photos(with: [user_id id]){
+    url
+  }
+}`
+
+	if formatted != expected {
+		flux.FatalFailed(t, "Expected formatted query:\n%s\nbut got:\n%s", expected, formatted)
+	}
+
+	flux.LogPassed(t, "Formatted graph into indented query text")
+
+	canonical, err := Canonical(gs)
+
+	if err != nil {
+		flux.FatalFailed(t, "Failed to print canonical graph: %+s", err)
+	}
+
+	regs, err := ps.Scan(bytes.NewBufferString(formatted))
+
+	if err != nil {
+		flux.FatalFailed(t, "Failed to parse formatted query: %+s", err)
+	}
+
+	again, err := Canonical(regs)
+
+	if err != nil {
+		flux.FatalFailed(t, "Failed to print canonical graph: %+s", err)
+	}
+
+	if canonical != again || canonical != "users@sql(id: 1){ age(gt: 20, lt: 40), kind(in: [admin staff]), name, score(range: 1..5), photos(with: [user_id id]){ url } }" {
+		flux.FatalFailed(t, "Expected stable canonical form but got %q and %q", canonical, again)
+	}
+
+	flux.LogPassed(t, "Printed the same canonical form for a query and its formatted text")
+}
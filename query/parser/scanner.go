@@ -232,7 +232,10 @@ func (s *Scanner) scanIdent() *Token {
 	for {
 
 		if ch := s.readOnly(); ch == eof {
-			// break
+			//an identifier ending the input is still a complete identifier
+			if buff.Len() > 0 {
+				break
+			}
 			return NewToken(string(eof), EOF, s.pos, s.line)
 			// return nil
 		} else if isSpecial(ch) || isWhiteSpace(ch) || isComma(ch) {
@@ -250,9 +253,10 @@ func (s *Scanner) scanIdent() *Token {
 	return NewToken(buff.String(), Indent, s.pos, s.line)
 }
 
-//scanQuery scans out the query (id:),(lt:,gt:)
+//scanQuery scans out the query (id:),(lt:,gt:), nested parentheses eg upper(concat(a, b)) end with their own closing one
 func (s *Scanner) scanQuery() *Token {
 	var buff bytes.Buffer
+	depth := 0
 
 	for {
 
@@ -262,8 +266,14 @@ func (s *Scanner) scanQuery() *Token {
 			// return nil
 		} else if isQueryEnd(ch) {
 			buff.WriteRune(ch)
-			break
+			depth--
+			if depth <= 0 {
+				break
+			}
 		} else {
+			if isQueryStart(ch) {
+				depth++
+			}
 			buff.WriteRune(ch)
 		}
 
@@ -272,6 +282,48 @@ func (s *Scanner) scanQuery() *Token {
 	return NewToken(buff.String(), Query, s.pos, s.line)
 }
 
+//scanExpression scans out the expression of a computed field eg concat(first, " ", last), stopping before the comma or closing brace
+//that ends it outside of parentheses and quotes
+func (s *Scanner) scanExpression() *Token {
+	var buff bytes.Buffer
+	var quote rune
+	depth := 0
+
+	for {
+		ch := s.readOnly()
+
+		if ch == eof {
+			break
+		}
+
+		if quote == 0 && depth == 0 && (isComma(ch) || isGroupEnd(ch)) {
+			s.unread()
+			break
+		}
+
+		switch {
+		case quote != 0 && ch == '\\':
+			buff.WriteRune(ch)
+			ch = s.readOnly()
+		case quote != 0 && ch == quote:
+			quote = 0
+		case quote != 0:
+		case ch == '"' || ch == '\'':
+			quote = ch
+		case isQueryStart(ch):
+			depth++
+		case isQueryEnd(ch):
+			depth--
+		case isLineBreak(ch):
+			s.line++
+		}
+
+		buff.WriteRune(ch)
+	}
+
+	return s.recordRead(NewToken(buff.String(), Query, s.pos, s.line))
+}
+
 //BackwardsIf takes a value and walks Backward till 0 unless the stop function is called
 func BackwardsIf(to int, fx func(int, func())) {
 	state := true
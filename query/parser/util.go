@@ -159,9 +159,10 @@ func isSpecial(c rune) bool {
 }
 
 func report(msg, val string, line, pos int) error {
-	return (fmt.Errorf(`
-A disturbance in the Force in Line: %d, Pos: %d:
-  -  Cause: '%s'
-  -  Why: %s
-`, line, pos, val, msg))
+	return &PositionError{
+		Message: msg,
+		Cause:   val,
+		Line:    line,
+		Pos:     pos,
+	}
 }
@@ -0,0 +1,379 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/influx6/data/query/adaptors"
+	"github.com/influx6/data/query/parser"
+	"github.com/influx6/ds"
+)
+
+// DefaultMaxBodySize is the largest request body read by a Handler without a MaxBodySize
+const DefaultMaxBodySize = 1 << 20
+
+//OperationNotFoundMessage provides error for an operationName that names none of the query's root records
+const OperationNotFoundMessage = "Operation '%s' not found in query"
+
+// ErrNoQuery is returned for requests without a query
+var ErrNoQuery = errors.New("Request has no query")
+
+// ErrMethod is returned for requests with a method other than GET, POST or OPTIONS
+var ErrMethod = errors.New("Method not allowed, use GET or POST")
+
+// Request defines the json body of a POST query request, GET requests carry the same fields in the 'q', 'id', 'variables' and 'operationName' parameters.
+// A request names a persisted query by its ID or hash instead of sending its text
+type Request struct {
+	Query         string                 `json:"query"`
+	ID            string                 `json:"id,omitempty"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+	OperationName string                 `json:"operationName,omitempty"`
+}
+
+// Error defines a structured error of a Response, positioned at the part of the query it refers to when known
+type Error struct {
+	Message string `json:"message"`
+	Kind    string `json:"kind"`
+	Cause   string `json:"cause,omitempty"`
+	Line    int    `json:"line,omitempty"`
+	Column  int    `json:"column,omitempty"`
+	status  int
+}
+
+// Response defines the json body of every reply, data holds the records of each root record executed
+type Response struct {
+	Data   map[string]interface{} `json:"data"`
+	Errors []*Error               `json:"errors,omitempty"`
+}
+
+// AuthFx authenticates a request and returns the principal its queries are authorized as, an error rejects the request as unauthorized
+type AuthFx func(r *http.Request) (*adaptors.Principal, error)
+
+// CORS defines the cross-origin requests a Handler allows
+type CORS struct {
+	//Origins lists the allowed origins, "*" allows any origin
+	Origins []string
+	//Headers lists the request headers allowed besides Content-Type
+	Headers []string
+	//Credentials allows cookies and authorization headers on cross-origin requests
+	Credentials bool
+	//MaxAge is the number of seconds a preflight response may be cached
+	MaxAge int
+}
+
+// allows returns true if the origin is allowed
+func (c *CORS) allows(origin string) bool {
+	for _, allowed := range c.Origins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// Handler provides an http.Handler executing queries through an engine, replying with a json Response
+type Handler struct {
+	//Auth authenticates each request when set
+	Auth AuthFx
+	//Policy authorizes each query for the principal of the request when set
+	Policy *adaptors.Policy
+	//Limits bounds the size of each query when set
+	Limits *adaptors.Limits
+	//CORS allows cross-origin requests when set
+	CORS *CORS
+	//Queries provides the persisted queries requests may run by id or hash
+	Queries adaptors.QueryStore
+	//PersistedOnly rejects any query text that is not one of the persisted Queries
+	PersistedOnly bool
+	//MaxBodySize bounds the size of a POST body, DefaultMaxBodySize is used when zero
+	MaxBodySize int64
+	engine      *adaptors.Engine
+	inspect     *parser.InspectionFactory
+}
+
+// NewHandler returns a new Handler over the engine using the inspections, parser.DefaultInspectionFactory is used when the inspections are nil
+func NewHandler(en *adaptors.Engine, inspect *parser.InspectionFactory) *Handler {
+	if inspect == nil {
+		inspect = parser.DefaultInspectionFactory
+	}
+
+	return &Handler{engine: en, inspect: inspect}
+}
+
+// ServeHTTP handles a GET or POST query request
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.CORS != nil && h.cors(w, r) {
+		return
+	}
+
+	var pr *adaptors.Principal
+	var err error
+
+	if h.Auth != nil {
+		if pr, err = h.Auth(r); err != nil {
+			h.reply(w, nil, []*Error{{Message: err.Error(), Kind: "authentication", status: http.StatusUnauthorized}})
+			return
+		}
+	}
+
+	req, err := h.request(w, r)
+
+	if err != nil {
+		h.reply(w, nil, []*Error{{Message: err.Error(), Kind: "request", status: requestStatus(err)}})
+		return
+	}
+
+	data, errs := h.execute(r.Context(), req, pr)
+	h.reply(w, data, errs)
+}
+
+// cors writes the cross-origin headers of the request, returning true if it was a preflight request that has been answered
+func (h *Handler) cors(w http.ResponseWriter, r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+
+	if origin == "" {
+		return false
+	}
+
+	w.Header().Add("Vary", "Origin")
+
+	if !h.CORS.allows(origin) {
+		if r.Method != http.MethodOptions {
+			return false
+		}
+
+		w.WriteHeader(http.StatusForbidden)
+		return true
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", origin)
+
+	if h.CORS.Credentials {
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+	}
+
+	if r.Method != http.MethodOptions {
+		return false
+	}
+
+	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", strings.Join(append([]string{"Content-Type"}, h.CORS.Headers...), ", "))
+
+	if h.CORS.MaxAge > 0 {
+		w.Header().Set("Access-Control-Max-Age", strconv.Itoa(h.CORS.MaxAge))
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return true
+}
+
+// request reads the query Request of a GET or POST request
+func (h *Handler) request(w http.ResponseWriter, r *http.Request) (*Request, error) {
+	var req Request
+
+	switch r.Method {
+	case http.MethodGet:
+		params := r.URL.Query()
+		req.Query = params.Get("q")
+		req.ID = params.Get("id")
+		req.OperationName = params.Get("operationName")
+
+		if vars := params.Get("variables"); vars != "" {
+			if err := json.Unmarshal([]byte(vars), &req.Variables); err != nil {
+				return nil, fmt.Errorf("Invalid variables: %s", err)
+			}
+		}
+	case http.MethodPost:
+		size := h.MaxBodySize
+
+		if size <= 0 {
+			size = DefaultMaxBodySize
+		}
+
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, size)).Decode(&req); err != nil && err != io.EOF {
+			return nil, fmt.Errorf("Invalid request body: %s", err)
+		}
+	default:
+		return nil, ErrMethod
+	}
+
+	if strings.TrimSpace(req.Query) == "" && req.ID == "" {
+		return nil, ErrNoQuery
+	}
+
+	return &req, nil
+}
+
+// execute parses the query and runs each of its root records, or only the one named by the operationName, collecting their records and errors in
+// the order of the query while running the engine's Parallelism of them at once
+func (h *Handler) execute(ctx context.Context, req *Request, pr *adaptors.Principal) (map[string]interface{}, []*Error) {
+	graphs, qerr := h.graphs(req)
+
+	if qerr != nil {
+		return nil, []*Error{qerr}
+	}
+
+	if req.OperationName != "" {
+		if graphs = operation(graphs, req.OperationName); len(graphs) == 0 {
+			return nil, []*Error{{Message: fmt.Sprintf(OperationNotFoundMessage, req.OperationName), Kind: "query", status: http.StatusBadRequest}}
+		}
+	}
+
+	results := make([]adaptors.Result, len(graphs))
+	failures := make([]error, len(graphs))
+
+	//a failed root record leaves the others to complete, so errors are kept rather than returned to cancel them
+	adaptors.Parallel(ctx, h.engine.Parallelism, len(graphs), func(ctx context.Context, index int) error {
+		results[index], failures[index] = h.run(ctx, graphs[index], req.Variables, pr)
+		return nil
+	})
+
+	data := make(map[string]interface{})
+
+	var errs []*Error
+
+	for index, res := range results {
+		if failures[index] != nil {
+			errs = append(errs, toError(failures[index]))
+			continue
+		}
+
+		for key, val := range res {
+			data[key] = adaptors.Plain(val)
+		}
+	}
+
+	return data, errs
+}
+
+// graphs returns the graphs of the persisted query named by the request or those of its query text, which must be persisted when only persisted queries are allowed
+func (h *Handler) graphs(req *Request) ([]ds.Graphs, *Error) {
+	if req.ID != "" {
+		if h.Queries == nil {
+			return nil, &Error{Message: fmt.Sprintf(adaptors.PersistedNotFoundMessage, req.ID), Kind: "persisted", status: http.StatusNotFound}
+		}
+
+		pq, err := h.Queries.Get(req.ID)
+
+		if err != nil {
+			return nil, &Error{Message: err.Error(), Kind: "persisted", status: http.StatusNotFound}
+		}
+
+		graphs, err := pq.Graphs()
+
+		if err != nil {
+			return nil, toError(err)
+		}
+
+		return graphs, nil
+	}
+
+	graphs, err := adaptors.Parse(h.inspect, req.Query)
+
+	if err != nil {
+		return nil, toError(err)
+	}
+
+	if !h.PersistedOnly {
+		return graphs, nil
+	}
+
+	if h.Queries != nil {
+		if _, err := adaptors.Persisted(h.Queries, graphs); err == nil {
+			return graphs, nil
+		}
+	}
+
+	return nil, &Error{Message: adaptors.ErrNotPersisted.Error(), Kind: "persisted", status: http.StatusForbidden}
+}
+
+// run checks the graph against the limits and policy and executes it with its variables bound
+func (h *Handler) run(ctx context.Context, gs ds.Graphs, vars map[string]interface{}, pr *adaptors.Principal) (adaptors.Result, error) {
+	if err := adaptors.BindVariables(gs, vars); err != nil {
+		return nil, err
+	}
+
+	if h.Limits != nil {
+		if err := h.Limits.Check(gs); err != nil {
+			return nil, err
+		}
+	}
+
+	if h.Policy != nil {
+		if err := h.Policy.Authorize(pr, gs); err != nil {
+			return nil, err
+		}
+	}
+
+	return h.engine.Execute(ctx, gs)
+}
+
+// operation returns the graphs whose root record is named by the operation
+func operation(graphs []ds.Graphs, name string) []ds.Graphs {
+	var found []ds.Graphs
+
+	for _, gs := range graphs {
+		root, err := adaptors.GetRoot(gs)
+
+		if err != nil {
+			continue
+		}
+
+		if node, ok := root.(*parser.ParseNode); ok && node.Name() == name {
+			found = append(found, gs)
+		}
+	}
+
+	return found
+}
+
+// reply writes the response, its status is that of the first error when no records were returned
+func (h *Handler) reply(w http.ResponseWriter, data map[string]interface{}, errs []*Error) {
+	status := http.StatusOK
+
+	if len(errs) > 0 && len(data) == 0 {
+		status = errs[0].status
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+
+	json.NewEncoder(w).Encode(&Response{Data: data, Errors: errs})
+}
+
+// requestStatus returns the http status of an invalid request
+func requestStatus(err error) int {
+	if err == ErrMethod {
+		return http.StatusMethodNotAllowed
+	}
+	return http.StatusBadRequest
+}
+
+// toError returns the structured Error of a query error
+func toError(err error) *Error {
+	switch mo := err.(type) {
+	case *parser.PositionError:
+		return &Error{Message: mo.Message, Kind: "query", Cause: mo.Cause, Line: mo.Line, Column: mo.Pos, status: http.StatusBadRequest}
+	case *adaptors.LimitError:
+		return &Error{Message: mo.Error(), Kind: "limit", Cause: mo.Node, Line: mo.Line, Column: mo.Pos, status: http.StatusBadRequest}
+	case *adaptors.AuthorizationError:
+		return &Error{Message: mo.Error(), Kind: "authorization", Cause: mo.Table, status: http.StatusForbidden}
+	}
+
+	switch err {
+	case adaptors.ErrNoPrincipal:
+		return &Error{Message: err.Error(), Kind: "authorization", status: http.StatusForbidden}
+	case context.DeadlineExceeded:
+		return &Error{Message: err.Error(), Kind: "timeout", status: http.StatusGatewayTimeout}
+	case parser.ErrBadQuery:
+		return &Error{Message: err.Error(), Kind: "query", status: http.StatusBadRequest}
+	}
+
+	return &Error{Message: err.Error(), Kind: "execution", status: http.StatusInternalServerError}
+}
@@ -0,0 +1,167 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/influx6/data/query/adaptors"
+	dqjson "github.com/influx6/data/query/adaptors/json"
+	"github.com/influx6/flux"
+)
+
+func handler() *Handler {
+	doc := dqjson.NewDocument(map[string]interface{}{
+		"users": []interface{}{
+			map[string]interface{}{"id": 1, "name": "alex", "age": 21},
+			map[string]interface{}{"id": 2, "name": "josh", "age": 32},
+		},
+	})
+
+	reg := adaptors.NewRegistry()
+	reg.Register("json", adaptors.NewSourceAdaptor(doc, nil))
+
+	return NewHandler(adaptors.NewEngine(reg, nil), nil)
+}
+
+func serve(h http.Handler, req *http.Request) (*httptest.ResponseRecorder, *Response) {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	var res Response
+	json.Unmarshal(rec.Body.Bytes(), &res)
+	return rec, &res
+}
+
+func TestPostQuery(t *testing.T) {
+	body := `{"query": "users(name: $name){ name, age }", "variables": {"name": "josh"}}`
+
+	rec, res := serve(handler(), httptest.NewRequest("POST", "/query", strings.NewReader(body)))
+
+	if rec.Code != http.StatusOK || len(res.Errors) != 0 {
+		flux.FatalFailed(t, "Expected query to succeed but got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	users, ok := res.Data["users"].([]interface{})
+
+	if !ok || len(users) != 1 || users[0].(map[string]interface{})["name"] != "josh" {
+		flux.FatalFailed(t, "Expected user 'josh' bound from variables: %s", rec.Body.String())
+	}
+
+	flux.LogPassed(t, "Executed POST query with variables")
+}
+
+func TestGetQuery(t *testing.T) {
+	q := url.Values{"q": {"users(){ name } admins(){ name }"}, "operationName": {"users"}}
+
+	rec, res := serve(handler(), httptest.NewRequest("GET", "/query?"+q.Encode(), nil))
+
+	if rec.Code != http.StatusOK || len(res.Data["users"].([]interface{})) != 2 || res.Data["admins"] != nil {
+		flux.FatalFailed(t, "Expected only the 'users' operation to run: %s", rec.Body.String())
+	}
+
+	flux.LogPassed(t, "Executed GET query selected by operationName")
+}
+
+func TestQueryErrors(t *testing.T) {
+	rec, res := serve(handler(), httptest.NewRequest("POST", "/query", strings.NewReader(`{"query": "users(name: $missing){ name }"}`)))
+
+	if rec.Code != http.StatusBadRequest || len(res.Errors) != 1 || res.Errors[0].Kind != "query" || res.Errors[0].Line != 1 {
+		flux.FatalFailed(t, "Expected positioned query error for an undefined variable: %s", rec.Body.String())
+	}
+
+	if rec, _ := serve(handler(), httptest.NewRequest("PUT", "/query", nil)); rec.Code != http.StatusMethodNotAllowed {
+		flux.FatalFailed(t, "Expected PUT to be rejected but got %d", rec.Code)
+	}
+
+	if rec, _ := serve(handler(), httptest.NewRequest("POST", "/query", strings.NewReader(`{}`))); rec.Code != http.StatusBadRequest {
+		flux.FatalFailed(t, "Expected empty query to be rejected but got %d", rec.Code)
+	}
+
+	flux.LogPassed(t, "Replied with structured errors for bad requests and queries")
+}
+
+func TestAuthAndPolicy(t *testing.T) {
+	h := handler()
+	h.Auth = func(r *http.Request) (*adaptors.Principal, error) {
+		if r.Header.Get("Authorization") == "" {
+			return nil, errors.New("missing token")
+		}
+		return adaptors.NewPrincipal("1"), nil
+	}
+
+	h.Policy = adaptors.NewPolicy(true)
+	h.Policy.DenyColumns("users", "age")
+
+	if rec, res := serve(h, httptest.NewRequest("GET", "/query?q=users(){name}", nil)); rec.Code != http.StatusUnauthorized || res.Errors[0].Kind != "authentication" {
+		flux.FatalFailed(t, "Expected unauthenticated request to be rejected: %s", rec.Body.String())
+	}
+
+	req := httptest.NewRequest("GET", "/query?q=users(){age}", nil)
+	req.Header.Set("Authorization", "token")
+
+	if rec, res := serve(h, req); rec.Code != http.StatusForbidden || res.Errors[0].Kind != "authorization" {
+		flux.FatalFailed(t, "Expected denied column to be forbidden: %s", rec.Body.String())
+	}
+
+	flux.LogPassed(t, "Authenticated requests and authorized their queries")
+}
+
+func TestCORS(t *testing.T) {
+	h := handler()
+	h.CORS = &CORS{Origins: []string{"https://app.example.com"}, MaxAge: 600}
+
+	req := httptest.NewRequest("OPTIONS", "/query", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+
+	rec, _ := serve(h, req)
+
+	if rec.Code != http.StatusNoContent || rec.Header().Get("Access-Control-Allow-Origin") != "https://app.example.com" || rec.Header().Get("Access-Control-Max-Age") != "600" {
+		flux.FatalFailed(t, "Expected preflight to be allowed: %d %v", rec.Code, rec.Header())
+	}
+
+	req = httptest.NewRequest("OPTIONS", "/query", nil)
+	req.Header.Set("Origin", "https://evil.example.com")
+
+	if rec, _ := serve(h, req); rec.Code != http.StatusForbidden {
+		flux.FatalFailed(t, "Expected preflight from another origin to be forbidden but got %d", rec.Code)
+	}
+
+	flux.LogPassed(t, "Answered cross-origin preflight requests")
+}
+
+func TestPersistedQueries(t *testing.T) {
+	store := adaptors.NewMemoryQueryStore(nil)
+
+	if _, err := store.Add("users", `users(){ name }`); err != nil {
+		flux.FatalFailed(t, "Failed to persist query: %+s", err)
+	}
+
+	h := handler()
+	h.Queries = store
+	h.PersistedOnly = true
+
+	rec, res := serve(h, httptest.NewRequest("POST", "/query", strings.NewReader(`{"id": "users"}`)))
+
+	if rec.Code != http.StatusOK || len(res.Data["users"].([]interface{})) != 2 {
+		flux.FatalFailed(t, "Expected persisted query to run by id: %s", rec.Body.String())
+	}
+
+	if rec, _ := serve(h, httptest.NewRequest("GET", "/query?q="+url.QueryEscape("users(){\n  name\n}"), nil)); rec.Code != http.StatusOK {
+		flux.FatalFailed(t, "Expected text of a persisted query to be allowed: %s", rec.Body.String())
+	}
+
+	if rec, res := serve(h, httptest.NewRequest("GET", "/query?q="+url.QueryEscape("users(){ age }"), nil)); rec.Code != http.StatusForbidden || res.Errors[0].Kind != "persisted" {
+		flux.FatalFailed(t, "Expected query that is not persisted to be rejected: %s", rec.Body.String())
+	}
+
+	if rec, _ := serve(h, httptest.NewRequest("GET", "/query?id=missing", nil)); rec.Code != http.StatusNotFound {
+		flux.FatalFailed(t, "Expected unknown id to be not found but got %d", rec.Code)
+	}
+
+	flux.LogPassed(t, "Ran persisted queries by id and rejected other queries")
+}